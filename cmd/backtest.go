@@ -3,9 +3,13 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/ztrade/base/common"
+	"github.com/ztrade/ztrade/pkg/core"
 	"github.com/ztrade/ztrade/pkg/ctl"
+	"github.com/ztrade/ztrade/pkg/process/dbstore"
 
 	log "github.com/sirupsen/logrus"
 
@@ -28,8 +32,16 @@ var (
 	fee          float64
 	lever        float64
 	simpleReport bool
+	spotMode     bool
+	marginMode   bool
 
-	rptDB string
+	rptDB        string
+	markInterval time.Duration
+	benchmark    string
+	runName      string
+	noSave       bool
+	seed         int64
+	manifestFile string
 )
 
 // backtestCmd represents the backtest command
@@ -51,7 +63,15 @@ func init() {
 	backtestCmd.PersistentFlags().Float64VarP(&fee, "fee", "", 0.0001, "fee")
 	backtestCmd.PersistentFlags().Float64VarP(&lever, "lever", "", 1, "lever")
 	backtestCmd.PersistentFlags().BoolVarP(&simpleReport, "console", "", false, "print report to console")
+	backtestCmd.PersistentFlags().BoolVarP(&spotMode, "spot", "", false, "backtest with spot balance, default is true if the kind of exchange is spot")
+	backtestCmd.PersistentFlags().BoolVarP(&marginMode, "margin", "", false, "allow short in spot mode")
 	backtestCmd.PersistentFlags().StringVarP(&rptDB, "reportDB", "d", "", "save all actions to sqlite db")
+	backtestCmd.PersistentFlags().StringVar(&benchmark, "benchmark", "", "symbol of the same exchange to compare with, default is holding the backtest symbol")
+	backtestCmd.PersistentFlags().DurationVar(&markInterval, "mark", time.Hour*24, "period of the mark-to-market equity used by risk metrics, eg: 1h")
+	backtestCmd.PersistentFlags().StringVar(&runName, "name", "", "name of the run saved in registry")
+	backtestCmd.PersistentFlags().BoolVar(&noSave, "nosave", false, "don't save the run to registry")
+	backtestCmd.PersistentFlags().Int64Var(&seed, "seed", 0, "seed of order ids, random if 0, the seed used is saved in the manifest")
+	backtestCmd.PersistentFlags().StringVar(&manifestFile, "manifest", "", "save the run manifest(data checksum, script hash, params and seed) to json file")
 	initTimerange(backtestCmd)
 }
 
@@ -77,11 +97,25 @@ func runBacktest(cmd *cobra.Command, args []string) {
 		log.Fatal("init backtest failed:", err.Error())
 	}
 	r.SetTimeRange(startTime, endTime)
+	r.SetMarkInterval(markInterval)
+	if benchmark != "" {
+		candles, err := db.CandleHistory(exchangeName, benchmark, "1m")(startTime, endTime)
+		if err != nil {
+			log.Fatal("load benchmark failed:", err.Error())
+		}
+		r.SetBenchmark(benchmark, candles)
+	}
 	back.SetScript(scriptFile)
 	back.SetReporter(r)
 	back.SetBalanceInit(balanceInit, fee)
 	back.SetLoadDBOnce(loadOnce)
 	back.SetLever(lever)
+	back.SetSeed(seed)
+	spot := spotMode || cfg.GetString(fmt.Sprintf("exchanges.%s.kind", exchangeName)) == "spot"
+	if spot {
+		back.SetSpot(true, marginMode)
+		r.SetSpot(marginMode)
+	}
 
 	err = back.Run()
 
@@ -89,6 +123,14 @@ func runBacktest(cmd *cobra.Command, args []string) {
 		fmt.Println("run backtest error", err.Error())
 		log.Fatal("run backtest error", err.Error())
 	}
+	manifest := back.Manifest()
+	log.Infof("backtest seed: %d, candles: %d, data checksum: %s", manifest.Seed, manifest.Candles, manifest.DataChecksum)
+	if manifestFile != "" {
+		err = saveManifest(manifestFile, manifest)
+		if err != nil {
+			log.Errorf("save manifest failed: %s", err.Error())
+		}
+	}
 	if simpleReport {
 		result, err := r.GetResult()
 		if err != nil {
@@ -102,12 +144,20 @@ func runBacktest(cmd *cobra.Command, args []string) {
 			return
 		}
 		fmt.Println(string(buf))
+		saveRun(db, r, manifest)
 		return
 	}
+	candles, err := db.CandleHistory(exchangeName, symbol, "1m")(startTime, endTime)
+	if err != nil {
+		log.Warnf("load candles of chart failed: %s", err.Error())
+	} else {
+		r.SetCandles(candles)
+	}
 	err = r.GenRPT(rptFile)
 	if err != nil {
 		return
 	}
+	saveRun(db, r, manifest)
 	if rptDB != "" {
 		err = r.ExportToDB(rptDB)
 		if err != nil {
@@ -118,3 +168,47 @@ func runBacktest(cmd *cobra.Command, args []string) {
 	err = common.OpenURL(rptFile)
 	return
 }
+
+// saveManifest write the manifest of backtest to json file
+func saveManifest(fPath string, manifest core.RunManifest) (err error) {
+	buf, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(fPath, buf, 0644)
+	return
+}
+
+// saveRun save the params and result of backtest to run registry
+func saveRun(db *dbstore.DBStore, r *report.Report, manifest core.RunManifest) {
+	if noSave {
+		return
+	}
+	run := core.BacktestRun{
+		Name:         runName,
+		Script:       manifest.Script,
+		ScriptHash:   manifest.ScriptHash,
+		Param:        manifest.Param,
+		Exchange:     manifest.Exchange,
+		Symbol:       manifest.Symbol,
+		Balance:      manifest.Balance,
+		Fee:          manifest.Fee,
+		Lever:        manifest.Lever,
+		Spot:         manifest.Spot,
+		StartTime:    manifest.StartTime,
+		EndTime:      manifest.EndTime,
+		Seed:         manifest.Seed,
+		DataChecksum: manifest.DataChecksum,
+	}
+	err := r.FillRun(&run)
+	if err != nil {
+		log.Errorf("encode backtest result failed: %s", err.Error())
+		return
+	}
+	err = db.SaveBacktestRun(&run)
+	if err != nil {
+		log.Errorf("save backtest run failed: %s", err.Error())
+		return
+	}
+	log.Infof("backtest run saved: %d", run.ID)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/ztrade/ztrade/pkg/netbus"
+)
+
+// brokerCmd run the embedded event broker
+var brokerCmd = &cobra.Command{
+	Use:   "broker",
+	Short: "run event broker",
+	Long:  `run event broker, so the trade processes can exchange events with bus.broker in config`,
+	Run:   runBroker,
+}
+
+var brokerAddr string
+
+func init() {
+	rootCmd.AddCommand(brokerCmd)
+	brokerCmd.PersistentFlags().StringVar(&brokerAddr, "addr", ":7070", "broker listen address")
+}
+
+func runBroker(cmd *cobra.Command, args []string) {
+	broker := netbus.NewBroker(brokerAddr)
+	err := broker.Start()
+	if err != nil {
+		log.Fatal("start broker failed:", err.Error())
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	<-stop
+	broker.Close()
+}
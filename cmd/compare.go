@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/ztrade/base/common"
+	"github.com/ztrade/ztrade/pkg/report"
+)
+
+var (
+	compareFile    string
+	compareConsole bool
+	compareList    int
+	compareOpen    bool
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [run id...]",
+	Short: "compare backtest runs",
+	Long:  `compare the backtest runs saved in registry, list the latest runs if no id`,
+	Run:   runCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.PersistentFlags().StringVarP(&compareFile, "report", "o", "compare.html", "output compare html file path")
+	compareCmd.PersistentFlags().BoolVarP(&compareConsole, "console", "", false, "print comparison to console")
+	compareCmd.PersistentFlags().IntVarP(&compareList, "limit", "n", 20, "number of runs to list")
+	compareCmd.PersistentFlags().BoolVar(&compareOpen, "open", false, "open the compare report in browser")
+}
+
+func runCompare(cmd *cobra.Command, args []string) {
+	cfg := viper.GetViper()
+	db, err := initDB(cfg)
+	if err != nil {
+		log.Fatal("init db failed:", err.Error())
+	}
+	if len(args) == 0 {
+		runs, err := db.ListBacktestRuns(compareList)
+		if err != nil {
+			log.Fatal("list backtest runs failed:", err.Error())
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header([]string{"ID", "Name", "Script", "Symbol", "Start", "End", "Param", "Created"})
+		for _, v := range runs {
+			table.Append([]string{strconv.FormatInt(v.ID, 10), v.Name, v.Script, v.Symbol, v.StartTime.String(), v.EndTime.String(), v.Param, v.CreateTime.String()})
+		}
+		table.Render()
+		return
+	}
+	ids := make([]int64, len(args))
+	for i, v := range args {
+		ids[i], err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid run id %s", v)
+		}
+	}
+	saved, err := db.GetBacktestRuns(ids...)
+	if err != nil {
+		log.Fatal("get backtest runs failed:", err.Error())
+	}
+	if len(saved) != len(ids) {
+		log.Warnf("%d runs not found", len(ids)-len(saved))
+	}
+	var runs []report.CompareRun
+	for _, v := range saved {
+		run, err := report.NewCompareRun(v)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		runs = append(runs, run)
+	}
+	if compareConsole {
+		header := []string{"Metric"}
+		for _, v := range runs {
+			header = append(header, v.Name)
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header(header)
+		for _, v := range report.CompareTable(runs) {
+			table.Append(append([]string{v.Name}, v.Values...))
+		}
+		table.Render()
+		return
+	}
+	f, err := os.OpenFile(compareFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		log.Fatal("create compare report failed:", err.Error())
+	}
+	err = report.GenCompareHTML(f, runs)
+	f.Close()
+	if err != nil {
+		fmt.Println("gen compare report failed:", err.Error())
+		return
+	}
+	if !compareOpen {
+		return
+	}
+	err = common.OpenURL(compareFile)
+	if err != nil {
+		log.Fatal("open compare report failed:", err.Error())
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/ztrade/exchange"
 	"github.com/ztrade/ztrade/pkg/ctl"
+	"github.com/ztrade/ztrade/pkg/metrics"
 	"github.com/ztrade/ztrade/pkg/process/dbstore"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -27,6 +28,8 @@ var (
 	logFile  string
 	debug    bool
 	runPprof bool
+	// metrics serve address
+	metricsAddr string
 
 	logF *os.File
 )
@@ -73,6 +76,7 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "P", false, "run debug mode")
 	rootCmd.PersistentFlags().BoolVarP(&runPprof, "pprof", "p", false, "run with pprof mode at :8088")
 	rootCmd.PersistentFlags().StringVarP(&logFile, "log", "l", "ztrade.log", "log file")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics", "", "serve prometheus metrics at the address, eg: :9090, default is metrics.addr in config")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -90,6 +94,12 @@ func init() {
 		if debug {
 			log.SetLevel(log.DebugLevel)
 		}
+		if metricsAddr == "" {
+			metricsAddr = viper.GetString("metrics.addr")
+		}
+		if metricsAddr != "" {
+			metrics.Serve(metricsAddr)
+		}
 		if !runPprof {
 			return
 		}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/ztrade/ztrade/pkg/ctl"
+)
+
+var symbolCmd = &cobra.Command{
+	Use:   "symbol",
+	Short: "manage symbol infos",
+	Long:  `manage symbol infos: tick size, lot size, min qty, min notional and multiplier`,
+	Run:   runSymbolList,
+}
+
+var symbolSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "sync symbol infos from exchange",
+	Long:  `sync symbol infos from exchange`,
+	Run:   runSymbolSync,
+}
+
+var symbolImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "import symbol infos from json file",
+	Long:  `import symbol infos from json file, the file is an array of SymbolInfo`,
+	Args:  cobra.ExactArgs(1),
+	Run:   runSymbolImport,
+}
+
+func init() {
+	rootCmd.AddCommand(symbolCmd)
+	symbolCmd.AddCommand(symbolSyncCmd)
+	symbolCmd.AddCommand(symbolImportCmd)
+	symbolCmd.PersistentFlags().StringVar(&exchangeName, "exchange", "binance", "exchage name")
+}
+
+func runSymbolList(cmd *cobra.Command, args []string) {
+	db, err := initDB(viper.GetViper())
+	if err != nil {
+		log.Fatal("init db failed:", err.Error())
+	}
+	infos, err := db.GetSymbolInfos(exchangeName)
+	if err != nil {
+		log.Fatal("get symbol infos failed:", err.Error())
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header([]string{"Exchange", "Symbol", "TickSize", "LotSize", "MinQty", "MinNotional", "Multiplier"})
+	for _, v := range infos {
+		table.Append([]string{v.Exchange, v.Symbol,
+			fmt.Sprint(v.TickSize), fmt.Sprint(v.LotSize), fmt.Sprint(v.MinQty),
+			fmt.Sprint(v.MinNotional), fmt.Sprint(v.GetMultiplier())})
+	}
+	table.Render()
+}
+
+func runSymbolSync(cmd *cobra.Command, args []string) {
+	cfg := viper.GetViper()
+	db, err := initDB(cfg)
+	if err != nil {
+		log.Fatal("init db failed:", err.Error())
+	}
+	n, err := ctl.SyncSymbols(cfg, db, exchangeName)
+	if err != nil {
+		log.Fatal("sync symbols failed:", err.Error())
+	}
+	fmt.Printf("sync %d symbols of %s\n", n, exchangeName)
+}
+
+func runSymbolImport(cmd *cobra.Command, args []string) {
+	db, err := initDB(viper.GetViper())
+	if err != nil {
+		log.Fatal("init db failed:", err.Error())
+	}
+	n, err := ctl.ImportSymbols(db, exchangeName, args[0])
+	if err != nil {
+		log.Fatal("import symbols failed:", err.Error())
+	}
+	fmt.Printf("import %d symbols of %s\n", n, exchangeName)
+}
@@ -3,18 +3,23 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/ztrade/base/common"
 	"github.com/ztrade/ztrade/pkg/ctl"
+	"github.com/ztrade/ztrade/pkg/metrics"
+	"github.com/ztrade/ztrade/pkg/process/algo"
 	"github.com/ztrade/ztrade/pkg/report"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // tradeCmd represents the trade command
@@ -26,7 +31,14 @@ var tradeCmd = &cobra.Command{
 }
 
 var (
-	recentDay int
+	recentDay     int
+	venues        []string
+	watchInterval time.Duration
+	stateInterval time.Duration
+	liveInterval  time.Duration
+	summaryAt     string
+	openReport    bool
+	roles         []string
 )
 
 func init() {
@@ -38,13 +50,16 @@ func init() {
 	tradeCmd.PersistentFlags().StringVar(&exchangeName, "exchange", "bitmex", "exchage name, only support bitmex current now")
 	tradeCmd.PersistentFlags().IntVarP(&recentDay, "recent", "r", 1, "load recent (n) day datas,default 1")
 	tradeCmd.PersistentFlags().StringVar(&param, "param", "", "param json string")
+	tradeCmd.PersistentFlags().StringSliceVar(&venues, "venue", nil, "extra exchange venues, format: name:symbol")
+	tradeCmd.PersistentFlags().DurationVar(&stateInterval, "state", time.Minute, "save the script state to db every interval, 0 means only save when stop")
+	tradeCmd.PersistentFlags().DurationVar(&liveInterval, "live", time.Minute*10, "regenerate the report every interval while trading, 0 means only generate when stop")
+	tradeCmd.PersistentFlags().StringVar(&summaryAt, "summary", "00:00", "send the daily pnl summary to notify at the time of UTC, empty means disabled")
+	tradeCmd.PersistentFlags().BoolVar(&openReport, "open", false, "open the report in browser when stop")
+	tradeCmd.PersistentFlags().StringSliceVar(&roles, "role", ctl.Roles, "roles run by this process: market,strategy,execution,report, the roles in other processes are connected by bus.broker")
+	tradeCmd.PersistentFlags().DurationVar(&watchInterval, "watch", 0, "check the script file every interval and hot reload it when modified, eg: 5s, SIGHUP reload it too")
 }
 
 func runTrade(cmd *cobra.Command, args []string) {
-	if scriptFile == "" {
-		log.Fatal("strategy file can't be empty")
-		return
-	}
 	var gracefulStop = make(chan os.Signal)
 	signal.Notify(gracefulStop, syscall.SIGTERM)
 	signal.Notify(gracefulStop, syscall.SIGINT)
@@ -53,12 +68,52 @@ func runTrade(cmd *cobra.Command, args []string) {
 		log.Fatal("trade error:", err.Error())
 		return
 	}
+	err = real.SetRoles(roles)
+	if err != nil {
+		log.Fatal("role error:", err.Error())
+	}
+	runScript := real.HasRole(ctl.RoleStrategy)
+	if runScript && scriptFile == "" {
+		log.Fatal("strategy file can't be empty")
+		return
+	}
+	for _, v := range venues {
+		infos := strings.SplitN(v, ":", 2)
+		if len(infos) != 2 {
+			log.Fatal("venue format error, must be name:symbol, got:", v)
+		}
+		err = real.AddExchange(infos[0], infos[1])
+		if err != nil {
+			log.Fatal("add venue error:", err.Error())
+		}
+	}
 	if recentDay != 0 {
 		real.SetLoadRecent(time.Duration(recentDay) * time.Hour * 24)
 	}
-	r := report.NewReportSimple()
-	tStart := time.Now()
+	db, err := initDB(viper.GetViper())
+	if err != nil {
+		log.Warnf("init db failed: %s, vwap works like twap", err.Error())
+	} else {
+		real.SetStateStore(db.StateStore(exchangeName, symbol), stateInterval)
+		real.SetHistory(db.CandleHistory(exchangeName, symbol, "1m"))
+		profile, err := algo.LoadVolumeProfile(db, exchangeName, symbol, time.Now(), 7)
+		if err != nil {
+			log.Warnf("load volume profile failed: %s, vwap works like twap", err.Error())
+		} else {
+			real.SetVolumeProfile(profile)
+		}
+	}
+	r := report.NewLiveReport()
 	real.SetReporter(r)
+	if summaryAt != "" {
+		at, err := time.Parse("15:04", summaryAt)
+		if err != nil {
+			log.Fatal("summary time format error, must be 15:04, got:", summaryAt)
+		}
+		real.SetDailySummary(time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute)
+	}
+	metrics.Handle("/pnl", r)
+	metrics.Handle("/pnl/report", http.HandlerFunc(r.ServeReport))
 	paramData := make(map[string]interface{})
 	if param != "" {
 		err = json.Unmarshal([]byte(param), &paramData)
@@ -66,10 +121,24 @@ func runTrade(cmd *cobra.Command, args []string) {
 			log.Fatal("param error:", err.Error())
 		}
 	}
-	err = real.AddScript(filepath.Base(scriptFile), scriptFile, param)
-	if err != nil {
-		fmt.Println("AddScript failed:", err.Error())
-		return
+	if runScript {
+		scriptName := filepath.Base(scriptFile)
+		err = real.AddScript(scriptName, scriptFile, param)
+		if err != nil {
+			fmt.Println("AddScript failed:", err.Error())
+			return
+		}
+		real.SetWatchInterval(watchInterval)
+		reloadSig := make(chan os.Signal, 1)
+		signal.Notify(reloadSig, syscall.SIGHUP)
+		go func() {
+			for range reloadSig {
+				err := real.ReloadScript(scriptName)
+				if err != nil {
+					log.Error(err.Error())
+				}
+			}
+		}()
 	}
 	// real.SetScript(scriptFile)
 	go func() {
@@ -81,13 +150,23 @@ func runTrade(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatal("trade error:", err.Error())
 	}
+	stopLive := make(chan struct{})
+	if liveInterval > 0 && real.HasRole(ctl.RoleReport) {
+		go runLiveReport(r, stopLive)
+	}
 	real.Wait()
+	close(stopLive)
+	if !real.HasRole(ctl.RoleReport) {
+		return
+	}
 	fmt.Println("begin to geneate report to ", rptFile)
-	r.SetTimeRange(tStart, time.Now())
 	err = r.GenRPT(rptFile)
 	if err != nil {
 		return
 	}
+	if !openReport {
+		return
+	}
 	fmt.Println("open report ", rptFile)
 	err = common.OpenURL(rptFile)
 	if err != nil {
@@ -95,3 +174,20 @@ func runTrade(cmd *cobra.Command, args []string) {
 	}
 	return
 }
+
+// runLiveReport regenerate the report every liveInterval
+func runLiveReport(r *report.LiveReport, stop chan struct{}) {
+	ticker := time.NewTicker(liveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		err := r.GenRPT(rptFile)
+		if err != nil {
+			log.Errorf("generate live report failed: %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"time"
+
+	. "github.com/ztrade/trademodel"
+)
+
+// Algo names
+const (
+	AlgoTWAP    = "twap"
+	AlgoVWAP    = "vwap"
+	AlgoIceberg = "iceberg"
+	AlgoPeg     = "peg"
+)
+
+// Algo order status
+const (
+	AlgoStatusRunning  = "running"
+	AlgoStatusFinished = "finished"
+	AlgoStatusCanceled = "canceled"
+	AlgoStatusFailed   = "failed"
+)
+
+// AlgoOrder parent order which is split into child orders by an execution algo
+type AlgoOrder struct {
+	TradeAction
+	Algo     string
+	Duration time.Duration // twap/vwap: total execution time
+	Slices   int           // twap/vwap: number of child orders
+	Visible  float64       // iceberg: visible amount of each child order
+	Offset   float64       // peg: distance from best bid/ask, positive is more passive
+}
+
+// AlgoProgress progress of a parent algo order
+type AlgoProgress struct {
+	ID       string
+	Algo     string
+	Action   TradeType
+	Amount   float64
+	Filled   float64
+	AvgPrice float64
+	Children int
+	Status   string
+	Msg      string
+	Time     time.Time
+}
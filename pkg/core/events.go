@@ -1,13 +1,13 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/tidwall/gjson"
-	. "github.com/ztrade/trademodel"
 )
 
 // Events
@@ -22,35 +22,36 @@ const (
 	EventDepth       = "depth"
 	// all trades in the markets
 	EventTradeMarket = "trade_market"
+	// own order canceled
+	EventOrderCanceled = "order_canceled"
 
 	EventBalance     = "balance"
 	EventBalanceInit = "balance_init"
+	// balance set by script
+	EventScriptBalance = "init_balance"
 
 	EventWatch       = "watch"
 	EventWatchCandle = "watch_candle"
 
 	EventNotify = "notify"
 
+	EventSymbolInfo = "symbol_info"
+
+	EventAlgoOrder    = "algo_order"
+	EventAlgoProgress = "algo_progress"
+
+	// values drawn in report
+	EventPlot = "plot"
+
 	EventError = "error"
 )
 
+// BalanceAsset extra of EventBalance which is not the account balance, eg: base currency of spot
+const BalanceAsset = "asset"
+
 var (
-	EventTypes = map[string]reflect.Type{
-		EventCandle: reflect.TypeOf(Candle{}),
-		EventOrder:  reflect.TypeOf(TradeAction{}),
-		// EventOrderCancelAll     = "order_cancel_all"
-		EventTrade:    reflect.TypeOf(Trade{}),
-		EventPosition: reflect.TypeOf(Position{}),
-		// EventCurPosition        = "cur_position" // position of current script
-		// EventRiskLimit          = "risk_limit"
-		EventDepth:       reflect.TypeOf(Depth{}),
-		EventTradeMarket: reflect.TypeOf(Trade{}),
-		EventBalance:     reflect.TypeOf(Balance{}),
-		EventBalanceInit: reflect.TypeOf(BalanceInfo{}),
-		EventWatch:       reflect.TypeOf(WatchParam{}),
-		EventNotify:      reflect.TypeOf(NotifyEvent{}),
-		EventWatchCandle: reflect.TypeOf(CandleParam{}),
-	}
+	// EventTypes data type of events, filled by RegisterEvent
+	EventTypes = map[string]reflect.Type{}
 
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
 )
@@ -89,20 +90,98 @@ type EventData struct {
 	Extra interface{} `json:"extra"`
 }
 
+// MarshalJSON error data is encoded as message
+func (d EventData) MarshalJSON() ([]byte, error) {
+	type eventData EventData
+	if err, ok := d.Data.(error); ok {
+		d.Data = err.Error()
+	}
+	return json.Marshal(eventData(d))
+}
+
 // UnmarshalJSON EventData can't be used as Embed
 func (d *EventData) UnmarshalJSON(buf []byte) (err error) {
 	ret := gjson.ParseBytes(buf)
 	d.Type = ret.Get("type").String()
-	typ, ok := EventTypes[d.Type]
-	if ok {
-		d.Data = reflect.New(typ).Interface()
+	schema, ok := GetEventSchema(d.Type)
+	data := ret.Get("data")
+	if d.Type == EventWatch && data.IsObject() {
+		// the data of WatchParam is decoded by the watch type, not the event schema
+		d.Data, err = decodeWatch(data)
+	} else {
+		d.Data, err = decodeData(schema, ok, data)
+	}
+	if err != nil {
+		return
+	}
+	extra := ret.Get("extra")
+	if !extra.Exists() || extra.Type == gjson.Null {
+		d.Extra = nil
+		return
+	}
+	if ok && schema.Extra != nil {
+		v := reflect.New(schema.Extra)
+		err = json.Unmarshal([]byte(extra.Raw), v.Interface())
+		d.Extra = v.Elem().Interface()
+	} else {
+		err = json.Unmarshal([]byte(extra.Raw), &d.Extra)
+	}
+	return
+}
+
+// decodeData decode the data by the schema, the data of unknown event is decoded as interface{}
+func decodeData(schema EventSchema, ok bool, data gjson.Result) (ret interface{}, err error) {
+	if ok && data.IsObject() || ok && schema.IsError() {
+		ret = schema.NewData()
 	} else {
-		d.Data = map[string]interface{}{}
+		ret = new(interface{})
 	}
-	err = json.Unmarshal([]byte(ret.Get("data").Raw), d.Data)
+	if data.Exists() {
+		err = json.Unmarshal([]byte(data.Raw), ret)
+		if err != nil {
+			return
+		}
+	}
+	ret = derefData(ret, schema.IsError())
 	return
 }
 
+// decodeWatch decode WatchParam, the data is decoded as the type registered by RegisterWatch or map
+func decodeWatch(ret gjson.Result) (wp *WatchParam, err error) {
+	wp = &WatchParam{Type: ret.Get("type").String()}
+	data := ret.Get("data")
+	if data.Exists() && data.Type != gjson.Null {
+		if t, ok := getWatchType(wp.Type); ok {
+			v := reflect.New(t)
+			err = json.Unmarshal([]byte(data.Raw), v.Interface())
+			wp.Data = v.Interface()
+		} else {
+			err = json.Unmarshal([]byte(data.Raw), &wp.Data)
+		}
+		if err != nil {
+			return
+		}
+	}
+	extra := ret.Get("extra")
+	if extra.Exists() && extra.Type != gjson.Null {
+		err = json.Unmarshal([]byte(extra.Raw), &wp.Extra)
+	}
+	return
+}
+
+// derefData convert the decoded data to the value sent by processers
+func derefData(data interface{}, isError bool) interface{} {
+	switch v := data.(type) {
+	case *interface{}:
+		return *v
+	case *string:
+		if isError {
+			return errors.New(*v)
+		}
+	}
+	return data
+}
+
 // WatchParam add watch event param
 type WatchParam = EventData
 
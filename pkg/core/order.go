@@ -0,0 +1,25 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+
+	. "github.com/ztrade/trademodel"
+)
+
+// orderIDPattern id of the orders sent by scripts: {script}-{8 random chars}, child orders of algo add -{n}
+var orderIDPattern = regexp.MustCompile(`^(.+)-[a-z0-9]{8}(-[0-9]+)?$`)
+
+// ScriptOfOrder return the script which sent the order, "" if the order is not sent by script
+func ScriptOfOrder(id string) string {
+	ret := orderIDPattern.FindStringSubmatch(id)
+	if ret == nil {
+		return ""
+	}
+	return ret[1]
+}
+
+// TradeFailed check if the trade is sent for the failed order, it's not filled
+func TradeFailed(tr *Trade) bool {
+	return tr.Amount == 0 || strings.HasPrefix(tr.Remark, "failed:")
+}
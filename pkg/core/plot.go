@@ -0,0 +1,14 @@
+package core
+
+import (
+	"time"
+)
+
+// PlotData one value of indicator drawn in report
+type PlotData struct {
+	Script string
+	// Name label of the line, eg: boll.top
+	Name  string
+	Time  time.Time
+	Value float64
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"math"
+
+	. "github.com/ztrade/trademodel"
+)
+
+// PnL profit and loss of one script or the whole account
+type PnL struct {
+	Script     string
+	Position   float64
+	AvgPrice   float64
+	Realized   float64
+	Unrealized float64
+	Fee        float64
+	Total      float64 // realized + unrealized - fee
+	Trades     int
+}
+
+// AddTrade update the position and realized pnl with trade, fee is the rate of trade value
+func (p *PnL) AddTrade(tr Trade, fee float64) {
+	amount := tr.Amount
+	if !tr.Action.IsLong() {
+		amount = -amount
+	}
+	if p.Position*amount < 0 {
+		closed := math.Min(math.Abs(p.Position), math.Abs(amount))
+		if p.Position > 0 {
+			p.Realized += closed * (tr.Price - p.AvgPrice)
+		} else {
+			p.Realized += closed * (p.AvgPrice - tr.Price)
+		}
+	}
+	p.Position, p.AvgPrice = UpdateHold(p.Position, p.AvgPrice, tr)
+	p.Fee += tr.Price * tr.Amount * fee
+	p.Trades++
+}
+
+// Mark return the pnl with the open position valued at price, 0 means no price
+func (p PnL) Mark(price float64) PnL {
+	if price != 0 {
+		p.Unrealized = p.Position * (price - p.AvgPrice)
+	}
+	p.Total = p.Realized + p.Unrealized - p.Fee
+	return p
+}
+
+// UpdateHold update the signed position and average open price with trade
+func UpdateHold(hold, avgPrice float64, tr Trade) (float64, float64) {
+	amount := tr.Amount
+	if !tr.Action.IsLong() {
+		amount = -amount
+	}
+	newHold := hold + amount
+	switch {
+	case math.Abs(newHold) < 1e-12:
+		return 0, 0
+	case hold == 0 || hold*amount > 0:
+		// open or add position
+		return newHold, (hold*avgPrice + amount*tr.Price) / newHold
+	case hold*newHold < 0:
+		// reversed
+		return newHold, tr.Price
+	default:
+		return newHold, avgPrice
+	}
+}
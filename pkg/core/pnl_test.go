@@ -0,0 +1,56 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/ztrade/trademodel"
+)
+
+func TestUpdateHold(t *testing.T) {
+	hold, avg := UpdateHold(0, 0, Trade{Action: OpenLong, Price: 100, Amount: 1})
+	hold, avg = UpdateHold(hold, avg, Trade{Action: OpenLong, Price: 200, Amount: 1})
+	if hold != 2 || avg != 150 {
+		t.Fatalf("add position error: %f %f", hold, avg)
+	}
+	hold, avg = UpdateHold(hold, avg, Trade{Action: CloseLong, Price: 300, Amount: 1})
+	if hold != 1 || avg != 150 {
+		t.Fatalf("reduce position error: %f %f", hold, avg)
+	}
+	hold, avg = UpdateHold(hold, avg, Trade{Action: OpenShort, Price: 120, Amount: 3})
+	if hold != -2 || avg != 120 {
+		t.Fatalf("reverse position error: %f %f", hold, avg)
+	}
+	hold, avg = UpdateHold(hold, avg, Trade{Action: CloseShort, Price: 100, Amount: 2})
+	if hold != 0 || avg != 0 {
+		t.Fatalf("close position error: %f %f", hold, avg)
+	}
+}
+
+func TestScriptOfOrder(t *testing.T) {
+	cases := map[string]string{
+		"demo.go-abc12345":    "demo.go",
+		"my-script-abc12345":  "my-script",
+		"demo-abc12345-3":     "demo",
+		"demo-abc12345-3-abc": "",
+		"12":                  "",
+	}
+	for id, script := range cases {
+		if ret := ScriptOfOrder(id); ret != script {
+			t.Errorf("ScriptOfOrder(%s) = %s, want %s", id, ret, script)
+		}
+	}
+}
+
+func TestPnL(t *testing.T) {
+	var p PnL
+	p.AddTrade(Trade{Action: OpenShort, Price: 100, Amount: 2}, 0.001)
+	p.AddTrade(Trade{Action: CloseShort, Price: 90, Amount: 1}, 0.001)
+	m := p.Mark(95)
+	if m.Position != -1 || m.Realized != 10 || m.Unrealized != 5 || m.Trades != 2 {
+		t.Fatalf("pnl error: %+v", m)
+	}
+	if math.Abs(m.Total-(15-0.29)) > 1e-9 {
+		t.Fatalf("total error: %f", m.Total)
+	}
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"time"
+)
+
+// BacktestRun one backtest saved in the run registry
+type BacktestRun struct {
+	ID           int64     `xorm:"pk autoincr 'id'"`
+	Name         string    `xorm:"'name'"`
+	Script       string    `xorm:"'script'"`
+	ScriptHash   string    `xorm:"index 'script_hash'"`
+	Param        string    `xorm:"text 'param'"`
+	Exchange     string    `xorm:"'exchange'"`
+	Symbol       string    `xorm:"'symbol'"`
+	StartTime    time.Time `xorm:"'start_time'"`
+	EndTime      time.Time `xorm:"'end_time'"`
+	Balance      float64   `xorm:"'balance'"`
+	Fee          float64   `xorm:"'fee'"`
+	Lever        float64   `xorm:"'lever'"`
+	Spot         bool      `xorm:"'spot'"`
+	Seed         int64     `xorm:"'seed'"`          // seed of order ids
+	DataChecksum string    `xorm:"'data_checksum'"` // sha256 of candles
+	Result       []byte    `xorm:"blob 'result'"`   // json of report result
+	Equity       []byte    `xorm:"blob 'equity'"`   // json of equity series
+	CreateTime   time.Time `xorm:"created 'create_time'"`
+}
+
+// RunManifest the inputs of one backtest, the backtest with the same manifest produces the same report
+type RunManifest struct {
+	Exchange   string
+	Symbol     string
+	BinSize    string
+	StartTime  time.Time
+	EndTime    time.Time
+	Script     string
+	ScriptHash string // sha256 of script file
+	Param      string
+	Balance    float64
+	Fee        float64
+	Lever      float64
+	Spot       bool
+	Margin     bool
+	Seed       int64
+	// Candles number of candles
+	Candles      int64
+	DataChecksum string // sha256 of candles
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	. "github.com/ztrade/trademodel"
+)
+
+// EventSchema declare the payload of event type
+type EventSchema struct {
+	Type string
+	// Version increase when the payload changed incompatible,
+	// the decoder accept the payload of the same or older version
+	Version int
+	// Data type of event data, the data is sent as pointer of Data
+	Data reflect.Type
+	// Extra type of event extra, nil means no extra
+	Extra reflect.Type
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+	eventSchemas = map[string]EventSchema{}
+	watchTypes   = map[string]reflect.Type{}
+	schemaMutex  sync.RWMutex
+)
+
+func init() {
+	RegisterEvent(EventCandle, 1, Candle{}, "")
+	RegisterEvent(EventOrder, 1, TradeAction{}, nil)
+	RegisterEvent(EventTrade, 1, Trade{}, nil)
+	RegisterEvent(EventPosition, 1, Position{}, nil)
+	RegisterEvent(EventCurPosition, 1, Position{}, nil)
+	RegisterEvent(EventRiskLimit, 1, RiskLimit{}, nil)
+	RegisterEvent(EventDepth, 1, Depth{}, nil)
+	RegisterEvent(EventTradeMarket, 1, Trade{}, nil)
+	RegisterEvent(EventOrderCanceled, 1, TradeAction{}, nil)
+	RegisterEvent(EventBalance, 1, Balance{}, "")
+	RegisterEvent(EventBalanceInit, 1, BalanceInfo{}, nil)
+	RegisterEvent(EventScriptBalance, 1, BalanceInfo{}, nil)
+	RegisterEvent(EventWatch, 1, WatchParam{}, nil)
+	RegisterEvent(EventWatchCandle, 1, CandleParam{}, nil)
+	RegisterEvent(EventNotify, 1, NotifyEvent{}, nil)
+	RegisterEvent(EventSymbolInfo, 1, SymbolInfo{}, nil)
+	RegisterEvent(EventAlgoOrder, 1, AlgoOrder{}, nil)
+	RegisterEvent(EventAlgoProgress, 1, AlgoProgress{}, nil)
+	RegisterEvent(EventPlot, 1, PlotData{}, nil)
+	registerSchema(EventSchema{Type: EventError, Version: 1, Data: errorType})
+	RegisterWatch(EventWatchCandle, CandleParam{})
+
+	// data of WatchParam may be map
+	gob.Register(map[string]interface{}{})
+}
+
+// RegisterEvent declare the data and extra type of event, must be called in init
+// data and extra are the zero value of the type, nil extra means no extra
+func RegisterEvent(typ string, version int, data, extra interface{}) {
+	schema := EventSchema{Type: typ, Version: version, Data: reflect.TypeOf(data)}
+	if extra != nil {
+		schema.Extra = reflect.TypeOf(extra)
+	}
+	if schema.Data.Kind() == reflect.Ptr {
+		panic(fmt.Sprintf("register event %s: data should not be pointer", typ))
+	}
+	registerSchema(schema)
+	gob.Register(reflect.New(schema.Data).Interface())
+}
+
+func registerSchema(schema EventSchema) {
+	schemaMutex.Lock()
+	defer schemaMutex.Unlock()
+	if _, ok := eventSchemas[schema.Type]; ok {
+		panic(fmt.Sprintf("event %s already registered", schema.Type))
+	}
+	eventSchemas[schema.Type] = schema
+	EventTypes[schema.Type] = schema.Data
+}
+
+// RegisterWatch declare the data type of WatchParam with the type, must be called in init
+// the data of registered watch is decoded as pointer of the type, others are decoded as map
+func RegisterWatch(typ string, data interface{}) {
+	t := reflect.TypeOf(data)
+	if t.Kind() == reflect.Ptr {
+		panic(fmt.Sprintf("register watch %s: data should not be pointer", typ))
+	}
+	schemaMutex.Lock()
+	defer schemaMutex.Unlock()
+	if _, ok := watchTypes[typ]; ok {
+		panic(fmt.Sprintf("watch %s already registered", typ))
+	}
+	watchTypes[typ] = t
+	gob.Register(reflect.New(t).Interface())
+}
+
+// getWatchType return the data type of watch
+func getWatchType(typ string) (t reflect.Type, ok bool) {
+	schemaMutex.RLock()
+	t, ok = watchTypes[typ]
+	schemaMutex.RUnlock()
+	return
+}
+
+// GetEventSchema return the schema of event type
+func GetEventSchema(typ string) (schema EventSchema, ok bool) {
+	schemaMutex.RLock()
+	schema, ok = eventSchemas[typ]
+	schemaMutex.RUnlock()
+	return
+}
+
+// EventSchemas return all registered schemas sorted by type
+func EventSchemas() (schemas []EventSchema) {
+	schemaMutex.RLock()
+	for _, v := range eventSchemas {
+		schemas = append(schemas, v)
+	}
+	schemaMutex.RUnlock()
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Type < schemas[j].Type
+	})
+	return
+}
+
+// IsError return true if the data of schema is error
+func (s EventSchema) IsError() bool {
+	return s.Data == errorType
+}
+
+// NewData create a new pointer of data
+func (s EventSchema) NewData() interface{} {
+	if s.IsError() {
+		return new(string)
+	}
+	return reflect.New(s.Data).Interface()
+}
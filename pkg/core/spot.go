@@ -0,0 +1,152 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	. "github.com/ztrade/trademodel"
+)
+
+const spotEpsilon = 1e-9
+
+var (
+	// ErrShortNotAllowed short in spot without margin
+	ErrShortNotAllowed = errors.New("short not allowed in spot without margin")
+	// ErrSpotNoBalance quote balance not enough to buy
+	ErrSpotNoBalance = errors.New("spot no balance")
+	// ErrUnknownQuote quote currency of spot symbol is unknown
+	ErrUnknownQuote = errors.New("unknown quote currency")
+
+	spotQuotes = []string{"USDT", "USDC", "BUSD", "FDUSD", "TUSD", "DAI", "USD", "EUR", "BTC", "ETH", "BNB"}
+)
+
+// SplitSpotSymbol split spot symbol into base and quote currency, eg: BTCUSDT => BTC, USDT
+// the symbol must have a separator(_-/) or end with a known quote currency
+func SplitSpotSymbol(symbol string) (base, quote string, err error) {
+	str := strings.ToUpper(symbol)
+	for _, sep := range []string{"_", "-", "/"} {
+		if n := strings.Index(str, sep); n > 0 && n < len(str)-1 {
+			return str[:n], str[n+1:], nil
+		}
+	}
+	for _, v := range spotQuotes {
+		if strings.HasSuffix(str, v) && len(str) > len(v) {
+			return str[:len(str)-len(v)], v, nil
+		}
+	}
+	err = fmt.Errorf("%w: %s, use a separator like BASE-QUOTE", ErrUnknownQuote, symbol)
+	return
+}
+
+// SpotBalance balance of spot account, with base and quote currency
+// buy cost quote and receive base, sell cost base and receive quote,
+// the fee is charged in the received currency
+type SpotBalance struct {
+	Base          float64
+	Quote         float64
+	BaseCurrency  string
+	QuoteCurrency string
+
+	fee    float64
+	margin bool
+	// account value when position open
+	openValue float64
+}
+
+// NewSpotBalance create spot balance
+func NewSpotBalance(baseCurrency, quoteCurrency string) *SpotBalance {
+	b := new(SpotBalance)
+	b.BaseCurrency = baseCurrency
+	b.QuoteCurrency = quoteCurrency
+	return b
+}
+
+// Set set quote balance
+func (b *SpotBalance) Set(balance float64) {
+	b.Quote = balance
+}
+
+func (b *SpotBalance) SetFee(fee float64) {
+	b.fee = fee
+}
+
+// SetLever spot has no lever
+func (b *SpotBalance) SetLever(lever float64) {
+}
+
+// SetMargin allow short with borrowed base currency
+func (b *SpotBalance) SetMargin(margin bool) {
+	b.margin = margin
+}
+
+// Get return quote balance
+func (b *SpotBalance) Get() float64 {
+	return b.Quote
+}
+
+// Pos return base balance
+func (b *SpotBalance) Pos() float64 {
+	return b.Base
+}
+
+// SellAmount return the amount can be sold
+// fee of buy is charged in base, so the hold may be a little less than amount, it's clipped to the hold
+func (b *SpotBalance) SellAmount(amount float64) (float64, error) {
+	if b.margin || amount <= b.Base+spotEpsilon {
+		return amount, nil
+	}
+	if b.Base <= 0 || amount-b.Base > amount*b.fee+spotEpsilon {
+		return 0, ErrShortNotAllowed
+	}
+	return b.Base, nil
+}
+
+// Value return account value in quote currency
+func (b *SpotBalance) Value(price float64) float64 {
+	return b.Quote + b.Base*price
+}
+
+// AddTrade add trade, return profit when position closed, fee is in quote currency
+func (b *SpotBalance) AddTrade(tr Trade) (profit, profitRate, fee float64, err error) {
+	if tr.Amount <= 0 || tr.Price <= 0 {
+		err = fmt.Errorf("spot invalid trade: %f@%f", tr.Amount, tr.Price)
+		return
+	}
+	if math.Abs(b.Base) <= spotEpsilon {
+		b.openValue = b.Value(tr.Price)
+	}
+	amount := tr.Amount
+	// IsLong means buy
+	if tr.Action.IsLong() {
+		cost := tr.Price * amount
+		if cost > b.Quote+spotEpsilon {
+			err = ErrSpotNoBalance
+			return
+		}
+		b.Quote -= cost
+		b.Base += amount * (1 - b.fee)
+		fee = amount * b.fee * tr.Price
+	} else {
+		amount, err = b.SellAmount(amount)
+		if err != nil {
+			return
+		}
+		b.Base -= amount
+		b.Quote += tr.Price * amount * (1 - b.fee)
+		fee = tr.Price * amount * b.fee
+	}
+	// settle the dust caused by fee when close
+	if !tr.Action.IsOpen() && math.Abs(b.Base) <= tr.Amount*b.fee+spotEpsilon {
+		b.Quote += b.Base * tr.Price
+		b.Base = 0
+	}
+	if b.Base == 0 {
+		profit = b.Value(tr.Price) - b.openValue
+		if b.openValue != 0 {
+			profitRate = profit / b.openValue
+		}
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/ztrade/trademodel"
+)
+
+func TestSplitSpotSymbol(t *testing.T) {
+	cases := []struct {
+		symbol, base, quote string
+	}{
+		{"BTCUSDT", "BTC", "USDT"},
+		{"ethbtc", "ETH", "BTC"},
+		{"BTC-USD", "BTC", "USD"},
+		{"btc_fdusd", "BTC", "FDUSD"},
+		{"SOL/EUR", "SOL", "EUR"},
+	}
+	for _, v := range cases {
+		base, quote, err := SplitSpotSymbol(v.symbol)
+		if err != nil || base != v.base || quote != v.quote {
+			t.Errorf("split %s error: %s %s %v", v.symbol, base, quote, err)
+		}
+	}
+	for _, v := range []string{"XYZABC", "USDT", "BTC-"} {
+		_, _, err := SplitSpotSymbol(v)
+		if !errors.Is(err, ErrUnknownQuote) {
+			t.Errorf("split %s should fail: %v", v, err)
+		}
+	}
+}
+
+func TestSpotBalance(t *testing.T) {
+	b := NewSpotBalance("BTC", "USDT")
+	b.Set(1000)
+	b.SetFee(0.001)
+	_, _, _, err := b.AddTrade(Trade{Action: OpenShort, Price: 100, Amount: 1})
+	if !errors.Is(err, ErrShortNotAllowed) {
+		t.Fatalf("short without margin should fail: %v", err)
+	}
+	_, _, _, err = b.AddTrade(Trade{Action: OpenLong, Price: 100, Amount: 20})
+	if !errors.Is(err, ErrSpotNoBalance) {
+		t.Fatalf("buy without balance should fail: %v", err)
+	}
+	_, _, fee, err := b.AddTrade(Trade{Action: OpenLong, Price: 100, Amount: 2})
+	if err != nil || b.Base != 1.998 || b.Quote != 800 || fee != 0.2 {
+		t.Fatalf("buy error: %v, base: %f, quote: %f, fee: %f", err, b.Base, b.Quote, fee)
+	}
+	amount, err := b.SellAmount(2)
+	if err != nil || amount != 1.998 {
+		t.Fatalf("sell amount error: %f %v", amount, err)
+	}
+	_, err = b.SellAmount(3)
+	if !errors.Is(err, ErrShortNotAllowed) {
+		t.Fatalf("sell more than hold should fail: %v", err)
+	}
+	profit, _, _, err := b.AddTrade(Trade{Action: CloseLong, Price: 110, Amount: 2})
+	if err != nil || b.Base != 0 {
+		t.Fatalf("sell error: %v, base: %f", err, b.Base)
+	}
+	if profit <= 0 || b.Quote != 800+1.998*110*0.999 {
+		t.Fatalf("profit error: %f, quote: %f", profit, b.Quote)
+	}
+}
@@ -0,0 +1,15 @@
+package core
+
+import (
+	"time"
+)
+
+// ScriptState snapshot of script state, saved to restore the script when restart
+type ScriptState struct {
+	ID         int64     `xorm:"pk autoincr 'id'"`
+	Name       string    `xorm:"notnull unique(nes) 'name'"`
+	Exchange   string    `xorm:"notnull unique(nes) 'exchange'"`
+	Symbol     string    `xorm:"notnull unique(nes) 'symbol'"`
+	Data       []byte    `xorm:"blob 'data'"`
+	UpdateTime time.Time `xorm:"'update_time'"`
+}
@@ -1,6 +1,13 @@
 package core
 
-import "strings"
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	. "github.com/ztrade/trademodel"
+)
 
 // SymbolInfo symbol infos
 type SymbolInfo struct {
@@ -9,8 +16,110 @@ type SymbolInfo struct {
 	Symbol      string `xorm:"notnull unique(esr) 'symbol'"`
 	Resolutions string `xorm:"notnull unique(esr) 'resolutions'"`
 	Pricescale  int    `xorm:"notnull 'pricescale'"`
+	// min price change
+	TickSize float64 `xorm:"'tick_size'"`
+	// min amount change
+	LotSize     float64 `xorm:"'lot_size'"`
+	MinQty      float64 `xorm:"'min_qty'"`
+	MinNotional float64 `xorm:"'min_notional'"`
+	// contract multiplier, 0 means 1
+	Multiplier float64 `xorm:"'multiplier'"`
+}
+
+// NewSymbolInfo create SymbolInfo from the symbol of exchange
+func NewSymbolInfo(exchange string, s Symbol) *SymbolInfo {
+	si := &SymbolInfo{
+		Exchange:    exchange,
+		Symbol:      s.Symbol,
+		Resolutions: s.Resolutions,
+		Pricescale:  int(math.Pow10(s.Precision)),
+		TickSize:    s.PriceStep,
+		LotSize:     s.AmountStep,
+	}
+	if si.TickSize == 0 && s.Precision > 0 {
+		si.TickSize = math.Pow10(-s.Precision)
+	}
+	if si.LotSize == 0 && s.AmountPrecision > 0 {
+		si.LotSize = math.Pow10(-s.AmountPrecision)
+	}
+	return si
+}
+
+// SymbolLimit limits of symbol, which are not in the symbol of exchange
+type SymbolLimit struct {
+	MinQty      float64
+	MinNotional float64
+	// contract multiplier, 0 means 1
+	Multiplier float64
+}
+
+// SetLimit set the limits of symbol, zero values are ignored
+func (si *SymbolInfo) SetLimit(limit SymbolLimit) {
+	if limit.MinQty > 0 {
+		si.MinQty = limit.MinQty
+	}
+	if limit.MinNotional > 0 {
+		si.MinNotional = limit.MinNotional
+	}
+	if limit.Multiplier > 0 {
+		si.Multiplier = limit.Multiplier
+	}
 }
 
 func (si *SymbolInfo) GetResolutions() []string {
 	return strings.Split(si.Resolutions, ",")
 }
+
+// GetMultiplier return contract multiplier, 1 if not set
+func (si *SymbolInfo) GetMultiplier() float64 {
+	if si.Multiplier == 0 {
+		return 1
+	}
+	return si.Multiplier
+}
+
+// RoundPrice round price to the nearest tick
+func (si *SymbolInfo) RoundPrice(price float64) float64 {
+	if si.TickSize <= 0 {
+		return price
+	}
+	step := decimal.NewFromFloat(si.TickSize)
+	ret, _ := decimal.NewFromFloat(price).Div(step).Round(0).Mul(step).Float64()
+	return ret
+}
+
+// RoundAmount round amount down to the lot size
+func (si *SymbolInfo) RoundAmount(amount float64) float64 {
+	if si.LotSize <= 0 {
+		return amount
+	}
+	step := decimal.NewFromFloat(si.LotSize)
+	ret, _ := decimal.NewFromFloat(amount).Div(step).Floor().Mul(step).Float64()
+	return ret
+}
+
+// Check check if amount and notional of order is large enough
+func (si *SymbolInfo) Check(price, amount float64) (err error) {
+	if amount <= 0 {
+		return fmt.Errorf("%s invalid amount: %f", si.Symbol, amount)
+	}
+	if si.MinQty > 0 && amount < si.MinQty {
+		return fmt.Errorf("%s amount %f less than min qty %f", si.Symbol, amount, si.MinQty)
+	}
+	notional := price * amount * si.GetMultiplier()
+	if si.MinNotional > 0 && price > 0 && notional < si.MinNotional {
+		return fmt.Errorf("%s notional %f less than min notional %f", si.Symbol, notional, si.MinNotional)
+	}
+	return
+}
+
+// FixOrder round price and amount of order and check it, cancel orders are ignored
+func (si *SymbolInfo) FixOrder(act *TradeAction) (err error) {
+	if act.Action == CancelOne || act.Action == CancelAll {
+		return
+	}
+	act.Price = si.RoundPrice(act.Price)
+	act.Amount = si.RoundAmount(act.Amount)
+	err = si.Check(act.Price, act.Amount)
+	return
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"testing"
+
+	. "github.com/ztrade/trademodel"
+)
+
+func TestNewSymbolInfo(t *testing.T) {
+	si := NewSymbolInfo("binance", Symbol{Symbol: "BTCUSDT", Precision: 2, AmountPrecision: 3})
+	if si.TickSize != 0.01 || si.LotSize != 0.001 || si.Pricescale != 100 {
+		t.Fatalf("symbol info error: %#v", si)
+	}
+	si = NewSymbolInfo("binance", Symbol{Symbol: "BTCUSDT", Precision: 2, PriceStep: 0.1, AmountStep: 0.5})
+	if si.TickSize != 0.1 || si.LotSize != 0.5 {
+		t.Fatalf("steps should be used: %#v", si)
+	}
+	si.SetLimit(SymbolLimit{MinQty: 0.001, MinNotional: 5})
+	if si.MinQty != 0.001 || si.MinNotional != 5 || si.GetMultiplier() != 1 {
+		t.Fatalf("limit error: %#v", si)
+	}
+	si.SetLimit(SymbolLimit{Multiplier: 10})
+	if si.MinQty != 0.001 || si.MinNotional != 5 || si.GetMultiplier() != 10 {
+		t.Fatalf("zero limits should be ignored: %#v", si)
+	}
+}
+
+func TestRoundPrice(t *testing.T) {
+	cases := []struct {
+		tick, price, ret float64
+	}{
+		{0, 100.123, 100.123},
+		{0.01, 100.123, 100.12},
+		{0.01, 100.125, 100.13},
+		{0.1, 0.3, 0.3},
+		{0.5, 100.74, 100.5},
+		{0.5, 100.75, 101},
+		{10, 12345, 12350},
+		{0.0001, 0.00015, 0.0002},
+	}
+	for _, v := range cases {
+		si := SymbolInfo{TickSize: v.tick}
+		if ret := si.RoundPrice(v.price); ret != v.ret {
+			t.Errorf("round price %f by %f error: %f, expect %f", v.price, v.tick, ret, v.ret)
+		}
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	cases := []struct {
+		lot, amount, ret float64
+	}{
+		{0, 1.2345, 1.2345},
+		{0.001, 1.2345, 1.234},
+		{0.001, 1.2349, 1.234},
+		{0.1, 0.3, 0.3},
+		{0.1, 0.7, 0.7},
+		{5, 12, 10},
+		{0.001, 0.0009, 0},
+	}
+	for _, v := range cases {
+		si := SymbolInfo{LotSize: v.lot}
+		if ret := si.RoundAmount(v.amount); ret != v.ret {
+			t.Errorf("round amount %f by %f error: %f, expect %f", v.amount, v.lot, ret, v.ret)
+		}
+	}
+}
+
+func TestSymbolCheck(t *testing.T) {
+	si := SymbolInfo{Symbol: "BTCUSDT", MinQty: 0.001, MinNotional: 5}
+	cases := []struct {
+		price, amount float64
+		ok            bool
+	}{
+		{100, 0, false},
+		{100, -1, false},
+		{100, 0.0009, false},
+		{100, 0.049, false},
+		{100, 0.05, true},
+		// market order, notional is not checked
+		{0, 0.001, true},
+	}
+	for _, v := range cases {
+		err := si.Check(v.price, v.amount)
+		if (err == nil) != v.ok {
+			t.Errorf("check %f %f error: %v", v.price, v.amount, err)
+		}
+	}
+	si.Multiplier = 10
+	if err := si.Check(100, 0.005); err != nil {
+		t.Errorf("notional should use multiplier: %s", err.Error())
+	}
+}
+
+func TestFixOrder(t *testing.T) {
+	si := SymbolInfo{Symbol: "BTCUSDT", TickSize: 0.1, LotSize: 0.001, MinQty: 0.001, MinNotional: 5}
+	cases := []struct {
+		act           TradeAction
+		price, amount float64
+		ok            bool
+	}{
+		{TradeAction{Action: OpenLong, Price: 100.04, Amount: 0.0509}, 100, 0.05, true},
+		{TradeAction{Action: OpenLong, Price: 100.06, Amount: 0.0009}, 100.1, 0, false},
+		{TradeAction{Action: CloseShort, Price: 100, Amount: 0.0499}, 100, 0.049, false},
+		{TradeAction{Action: CancelAll}, 0, 0, true},
+		{TradeAction{Action: CancelOne, Price: 100.04, Amount: 0.0001}, 100.04, 0.0001, true},
+	}
+	for i, v := range cases {
+		act := v.act
+		err := si.FixOrder(&act)
+		if (err == nil) != v.ok || act.Price != v.price || act.Amount != v.amount {
+			t.Errorf("fix order %d error: %v, price: %f, amount: %f", i, err, act.Price, act.Amount)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ztrade/base/common"
 	. "github.com/ztrade/ztrade/pkg/core"
 	"github.com/ztrade/ztrade/pkg/event"
+	"github.com/ztrade/ztrade/pkg/process/algo"
 	"github.com/ztrade/ztrade/pkg/process/dbstore"
 	"github.com/ztrade/ztrade/pkg/process/rpt"
 	"github.com/ztrade/ztrade/pkg/process/vex"
@@ -32,6 +33,10 @@ type Backtest struct {
 	loadDBOnce  int
 	fee         float64
 	lever       float64
+	spot        bool
+	margin      bool
+	seed        int64
+	manifest    RunManifest
 
 	closeAllWhenFinished bool
 }
@@ -67,6 +72,18 @@ func (b *Backtest) SetLever(lever float64) {
 	b.lever = lever
 }
 
+// SetSpot backtest with spot balance, short is only allowed when margin enabled
+func (b *Backtest) SetSpot(spot, margin bool) {
+	b.spot = spot
+	b.margin = margin
+}
+
+// SetSeed set the seed of order ids, a random seed is used if 0
+// the seed is saved in the manifest, the backtest with the same manifest produces the same report
+func (b *Backtest) SetSeed(seed int64) {
+	b.seed = seed
+}
+
 func (b *Backtest) SetScript(scriptFile string) {
 	b.scriptFile = scriptFile
 }
@@ -101,22 +118,51 @@ func (b *Backtest) Run() (err error) {
 	tbl.SetLoadDataMode(true)
 	tbl.SetCloseCh(closeCh)
 	ex := vex.NewVExchange(b.symbol)
+	if b.spot {
+		err = ex.SetSpot(b.margin)
+		if err != nil {
+			return
+		}
+	}
+	symbolInfo, err := b.db.GetSymbolInfo(b.exchange, b.symbol)
+	if err != nil {
+		log.Warnf("load symbol info failed: %s, orders are not rounded", err.Error())
+	} else if symbolInfo != nil {
+		ex.SetSymbolInfo(symbolInfo)
+	}
+	algoEx := algo.NewExecutor(b.symbol)
+	profile, err := algo.LoadVolumeProfile(b.db, b.exchange, b.symbol, b.start, 7)
+	if err != nil {
+		log.Warnf("load volume profile failed: %s, vwap works like twap", err.Error())
+	} else {
+		algoEx.SetVolumeProfile(profile)
+	}
 	engine, err := NewScript(b.scriptFile, b.paramData, b.symbol)
 	if err != nil {
 		return
 	}
+	engine.SetHistory(b.db.CandleHistory(b.exchange, b.symbol, bSize))
+	if b.seed == 0 {
+		b.seed = time.Now().UnixNano()
+	}
+	engine.SetSeed(b.seed)
+	checksum := newCandleChecksum()
 	r := rpt.NewRpt(b.rpt)
 	processers := event.NewSyncProcessers()
+	// orders, trades and timers use the time of candles
+	processers.SetClock(event.NewSimClock())
 	processers.Add(param)
 	processers.Add(tbl)
+	processers.Add(checksum)
 	processers.Add(ex)
+	processers.Add(algoEx)
 	processers.Add(engine)
 	processers.Add(r)
 
 	var stopOnce sync.Once
 	errorCh := make(chan bool)
 	processers.SetErrorCallback(func(err error) {
-		if errors.Is(err, common.ErrNoBalance) {
+		if errors.Is(err, common.ErrNoBalance) || errors.Is(err, ErrSpotNoBalance) {
 			stopOnce.Do(func() {
 				log.Errorf("got error: %s, just exit", err.Error())
 				processers.Stop()
@@ -152,9 +198,36 @@ func (b *Backtest) Run() (err error) {
 		ex.CloseAll()
 	}
 	processers.WaitClose(time.Second * 10)
+	b.manifest = RunManifest{
+		Exchange:     b.exchange,
+		Symbol:       b.symbol,
+		BinSize:      bSize,
+		StartTime:    b.start,
+		EndTime:      b.end,
+		Script:       b.scriptFile,
+		Param:        b.paramData,
+		Balance:      b.balanceInit,
+		Fee:          b.fee,
+		Lever:        b.lever,
+		Spot:         b.spot,
+		Margin:       b.margin,
+		Seed:         b.seed,
+		Candles:      checksum.count,
+		DataChecksum: checksum.Sum(),
+	}
+	b.manifest.ScriptHash, err = FileHash(b.scriptFile)
+	if err != nil {
+		log.Warnf("hash script %s failed: %s", b.scriptFile, err.Error())
+		err = nil
+	}
 	return
 }
 
+// Manifest return the inputs of backtest, must call after the end of backtest
+func (b *Backtest) Manifest() RunManifest {
+	return b.manifest
+}
+
 // Progress return the progress of current backtest
 func (b *Backtest) Progress() (progress int) {
 	return b.progress
@@ -0,0 +1,111 @@
+package ctl
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+)
+
+// typeString return the type of define.go in the format of reflect
+func typeString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		// the types of trademodel are dot imported
+		if ast.IsExported(t.Name) {
+			return "trademodel." + t.Name
+		}
+		return t.Name
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	case *ast.ArrayType:
+		return "[]" + typeString(t.Elt)
+	case *ast.Ellipsis:
+		return "..." + typeString(t.Elt)
+	}
+	return ""
+}
+
+func fieldTypes(fields *ast.FieldList) (types []string) {
+	if fields == nil {
+		return
+	}
+	for _, v := range fields.List {
+		n := max(len(v.Names), 1)
+		for i := 0; i < n; i++ {
+			types = append(types, typeString(v.Type))
+		}
+	}
+	return
+}
+
+func funcString(fn *ast.FuncType) string {
+	ret := "func(" + strings.Join(fieldTypes(fn.Params), ", ") + ")"
+	results := fieldTypes(fn.Results)
+	switch len(results) {
+	case 0:
+	case 1:
+		ret += " " + results[0]
+	default:
+		ret += " (" + strings.Join(results, ", ") + ")"
+	}
+	return ret
+}
+
+func reflectMethods(typ reflect.Type) (methods map[string]string) {
+	methods = make(map[string]string)
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		methods[m.Name] = m.Type.String()
+	}
+	return
+}
+
+// TestDefineInterfaces the interfaces in define.go of plugins must be the same as engine
+func TestDefineInterfaces(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "define.go", defineGo, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	engines := map[string]reflect.Type{
+		"AlgoEngine":      reflect.TypeOf((*engine.AlgoEngine)(nil)).Elem(),
+		"VenueEngine":     reflect.TypeOf((*engine.VenueEngine)(nil)).Elem(),
+		"SymbolEngine":    reflect.TypeOf((*engine.SymbolEngine)(nil)).Elem(),
+		"IndicatorEngine": reflect.TypeOf((*engine.IndicatorEngine)(nil)).Elem(),
+		"AccountEngine":   reflect.TypeOf((*engine.AccountEngine)(nil)).Elem(),
+		"SizingEngine":    reflect.TypeOf((*engine.SizingEngine)(nil)).Elem(),
+		"TimerEngine":     reflect.TypeOf((*engine.TimerEngine)(nil)).Elem(),
+	}
+	found := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		typ, ok := engines[spec.Name.Name]
+		if !ok {
+			return false
+		}
+		found[spec.Name.Name] = true
+		define := make(map[string]string)
+		for _, v := range spec.Type.(*ast.InterfaceType).Methods.List {
+			define[v.Names[0].Name] = funcString(v.Type.(*ast.FuncType))
+		}
+		expect := reflectMethods(typ)
+		if !reflect.DeepEqual(define, expect) {
+			t.Errorf("%s of define.go is different from engine:\n%v\n%v", spec.Name.Name, define, expect)
+		}
+		return false
+	})
+	for k := range engines {
+		if !found[k] {
+			t.Errorf("%s not defined in define.go", k)
+		}
+	}
+}
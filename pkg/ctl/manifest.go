@@ -0,0 +1,54 @@
+package ctl
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"os"
+
+	"github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	"github.com/ztrade/ztrade/pkg/event"
+)
+
+// FileHash return the sha256 of file
+func FileHash(fPath string) (ret string, err error) {
+	buf, err := os.ReadFile(fPath)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(buf)
+	ret = hex.EncodeToString(sum[:])
+	return
+}
+
+// candleChecksum processer which hash the candles of backtest
+type candleChecksum struct {
+	event.BaseProcesser
+	hash  hash.Hash
+	count int64
+}
+
+func newCandleChecksum() *candleChecksum {
+	c := new(candleChecksum)
+	c.Name = "checksum"
+	c.hash = sha256.New()
+	return c
+}
+
+func (c *candleChecksum) Init(bus *event.Bus) (err error) {
+	c.BaseProcesser.Init(bus)
+	return event.SubscribeData(c, EventCandle, c.onEventCandle)
+}
+
+func (c *candleChecksum) onEventCandle(e *event.Event, candle *trademodel.Candle) (err error) {
+	binary.Write(c.hash, binary.LittleEndian, candle.Start)
+	binary.Write(c.hash, binary.LittleEndian, []float64{candle.Open, candle.High, candle.Low, candle.Close, candle.Volume, candle.Turnover})
+	c.count++
+	return
+}
+
+func (c *candleChecksum) Sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ztrade/ztrade/pkg/event"
 	"github.com/ztrade/ztrade/pkg/process/goscript"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
 )
 
 type Scripter interface {
@@ -12,6 +13,8 @@ type Scripter interface {
 	AddScript(name, src, param string) (err error)
 	RemoveScript(name string) error
 	ScriptCount() int
+	SetHistory(fn engine.HistoryFn)
+	SetSeed(seed int64)
 }
 
 func NewScript(file, param, symbol string) (s Scripter, err error) {
@@ -0,0 +1,61 @@
+package ctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	zexchange "github.com/ztrade/exchange"
+	. "github.com/ztrade/ztrade/pkg/core"
+	"github.com/ztrade/ztrade/pkg/process/dbstore"
+	"github.com/ztrade/ztrade/pkg/process/exchange"
+)
+
+// SyncSymbols fetch symbol infos and limits from exchange and save to db
+func SyncSymbols(cfg *viper.Viper, db *dbstore.DBStore, exchangeName string) (n int, err error) {
+	exchangeType := cfg.GetString(fmt.Sprintf("exchanges.%s.type", exchangeName))
+	exCfg := zexchange.WrapViper(cfg)
+	ex, err := zexchange.NewExchange(exchangeType, exCfg, exchangeName)
+	if err != nil {
+		return
+	}
+	infos, err := exchange.GetSymbolInfos(exchangeType, ex, exCfg, exchangeName)
+	if err != nil {
+		return
+	}
+	for _, v := range infos {
+		err = db.SaveSymbolInfo(v)
+		if err != nil {
+			err = fmt.Errorf("save symbol %s failed: %w", v.Symbol, err)
+			return
+		}
+		n++
+	}
+	return
+}
+
+// ImportSymbols import symbol infos from json file, exchange is used if not set in file
+func ImportSymbols(db *dbstore.DBStore, exchangeName, file string) (n int, err error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	var infos []SymbolInfo
+	err = json.Unmarshal(buf, &infos)
+	if err != nil {
+		return
+	}
+	for i := range infos {
+		if infos[i].Exchange == "" {
+			infos[i].Exchange = exchangeName
+		}
+		err = db.SaveSymbolInfo(&infos[i])
+		if err != nil {
+			err = fmt.Errorf("save symbol %s failed: %w", infos[i].Symbol, err)
+			return
+		}
+		n++
+	}
+	return
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"time"
+
 	"github.com/ztrade/base/common"
 	"github.com/ztrade/base/engine"
+	"github.com/ztrade/indicator"
 	. "github.com/ztrade/trademodel"
 )
 
@@ -23,6 +26,71 @@ type ParamData = common.ParamData
 
 type Engine = engine.Engine
 
+// AlgoEngine execution algo api, get it by engine.(AlgoEngine)
+type AlgoEngine interface {
+	TWAP(typ TradeType, price, amount float64, duration time.Duration, slices int) string
+	VWAP(typ TradeType, price, amount float64, duration time.Duration, slices int) string
+	Iceberg(typ TradeType, price, amount, visible float64) string
+	Peg(typ TradeType, amount, offset float64) string
+}
+
+// VenueEngine multi exchange api, get it by engine.(VenueEngine)
+type VenueEngine interface {
+	Venues() []string
+	DoOrderOn(venue string, typ TradeType, price, amount float64) string
+	CancelOrderOn(venue, id string)
+	PositionOn(venue string) (pos, price float64)
+	BalanceOn(venue string) float64
+}
+
+// SymbolEngine symbol metadata api, get it by engine.(SymbolEngine)
+type SymbolEngine interface {
+	TickSize() float64
+	LotSize() float64
+	MinQty() float64
+	MinNotional() float64
+	Multiplier() float64
+	RoundPrice(price float64) float64
+	RoundAmount(amount float64) float64
+}
+
+// IndicatorEngine indicator api, get it by engine.(IndicatorEngine)
+type IndicatorEngine interface {
+	Indicator(binSize, name string, params ...int) indicator.CommonIndicator
+	PlotIndicator(label string, ind indicator.CommonIndicator)
+	Plot(label string, value float64)
+}
+
+// AccountEngine virtual sub-account api, get it by engine.(AccountEngine)
+type AccountEngine interface {
+	SubAccount() bool
+	Realized() float64
+	Fills() int
+}
+
+// volatility kinds of SizingEngine.Volatility
+const (
+	VolATR    = "ATR"
+	VolStdDev = "STDDEV"
+)
+
+// SizingEngine position sizing api, get it by engine.(SizingEngine)
+type SizingEngine interface {
+	RiskAmount(price, stop, risk float64) float64
+	VolTargetAmount(price, vol, target float64) float64
+	Volatility(kind, binSize string, n int) float64
+	KellyAmount(price, winRate, winLoss, fraction, maxRatio float64) float64
+	MaxAmount(price float64) float64
+}
+
+// TimerEngine timer api, get it by engine.(TimerEngine), the timers call OnTimer(id string) of script
+type TimerEngine interface {
+	After(id string, d time.Duration)
+	Cron(id, spec string) error
+	CancelTimer(id string)
+	Now() time.Time
+}
+
 var StringParam = common.StringParam
 var IntParam = common.IntParam
 var FloatParam = common.FloatParam
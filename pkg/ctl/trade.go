@@ -3,14 +3,20 @@ package ctl
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	zexchange "github.com/ztrade/exchange"
 	. "github.com/ztrade/ztrade/pkg/core"
 	"github.com/ztrade/ztrade/pkg/event"
+	"github.com/ztrade/ztrade/pkg/metrics"
+	"github.com/ztrade/ztrade/pkg/netbus"
+	"github.com/ztrade/ztrade/pkg/process/algo"
 	"github.com/ztrade/ztrade/pkg/process/exchange"
 	"github.com/ztrade/ztrade/pkg/process/goscript"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
 	"github.com/ztrade/ztrade/pkg/process/notify"
 	"github.com/ztrade/ztrade/pkg/process/rpt"
 
@@ -25,6 +31,26 @@ func SetConfig(c zexchange.Config) {
 	cfg = c
 }
 
+// roles of trade, the roles can run in separate processes connected by bus.broker
+const (
+	// RoleMarket watch the market data of the default exchange
+	RoleMarket = "market"
+	// RoleStrategy run the scripts
+	RoleStrategy = "strategy"
+	// RoleExecution send the orders to the exchanges, run the algo orders and receive the account data
+	RoleExecution = "execution"
+	// RoleReport record the trades to report and send the notify
+	RoleReport = "report"
+)
+
+// Roles all roles of trade
+var Roles = []string{RoleMarket, RoleStrategy, RoleExecution, RoleReport}
+
+type venue struct {
+	name   string
+	symbol string
+}
+
 // Trade trade with multi scripts
 type Trade struct {
 	exchangeType string
@@ -35,8 +61,12 @@ type Trade struct {
 	rpt          rpt.Reporter
 	proc         *event.Processers
 	engine       *goscript.GoEngine
+	algo         *algo.Executor
+	venues       []venue
 	wg           sync.WaitGroup
 	loadRecent   time.Duration
+	summaryAt    time.Duration
+	roles        map[string]bool
 }
 
 // NewTrade constructor of Trade
@@ -50,14 +80,85 @@ func NewTrade(exchange, symbol string) (b *Trade, err error) {
 		return
 	}
 	b.engine = gEngine
+	b.algo = algo.NewExecutor(symbol)
+	b.algo.SetAccount(exchange)
 	b.loadRecent = time.Hour * 24
+	b.summaryAt = -1
+	err = b.SetRoles(Roles)
+	if err != nil {
+		return
+	}
+	err = b.initSandbox()
+	return
+}
+
+// initSandbox apply the sandbox limits and sub-account of scripts in config
+func (b *Trade) initSandbox() (err error) {
+	if str := cfg.GetString("script.budget"); str != "" {
+		budget, err := time.ParseDuration(str)
+		if err != nil {
+			return fmt.Errorf("parse script.budget failed:%s", err.Error())
+		}
+		b.engine.SetBudget(budget)
+	}
+	var imports []string
+	err = cfg.UnmarshalKey("script.imports", &imports)
+	if err != nil {
+		return fmt.Errorf("parse script.imports failed:%s", err.Error())
+	}
+	engine.SetAllowedImports(imports)
+	b.engine.SetSubAccount(cfg.GetBool("script.subaccount"))
+	return
+}
+
+// AddExchange add an extra exchange venue, name is the exchange config name
+// orders are sent to it by VenueEngine.DoOrderOn
+func (b *Trade) AddExchange(name, symbol string) (err error) {
+	if name == b.exchangeName {
+		err = fmt.Errorf("exchange %s is the default exchange", name)
+		return
+	}
+	for _, v := range b.venues {
+		if v.name == name {
+			err = fmt.Errorf("exchange %s already exist", name)
+			return
+		}
+	}
+	b.venues = append(b.venues, venue{name: name, symbol: symbol})
+	b.engine.AddVenue(name)
+	return
+}
+
+// SetRoles set the roles run by this process, all roles run by default
+func (b *Trade) SetRoles(roles []string) (err error) {
+	if len(roles) == 0 {
+		return errors.New("roles can't be empty")
+	}
+	m := make(map[string]bool)
+	for _, v := range roles {
+		if !slices.Contains(Roles, v) {
+			return fmt.Errorf("unknown role %s, support: %s", v, strings.Join(Roles, ","))
+		}
+		m[v] = true
+	}
+	b.roles = m
 	return
 }
 
+// HasRole return true if the role is run by this process
+func (b *Trade) HasRole(role string) bool {
+	return b.roles[role]
+}
+
 func (b *Trade) SetLoadRecent(recent time.Duration) {
 	b.loadRecent = recent
 }
 
+// SetVolumeProfile set the volume profile used by vwap algo orders
+func (b *Trade) SetVolumeProfile(profile *algo.VolumeProfile) {
+	b.algo.SetVolumeProfile(profile)
+}
+
 func (b *Trade) SetStatusCh(ch chan *goscript.Status) {
 	b.engine.SetStatusCh(ch)
 }
@@ -66,11 +167,41 @@ func (b *Trade) SetReporter(rpt rpt.Reporter) {
 	b.rpt = rpt
 }
 
+// SetDailySummary send the pnl summary to notify every day at the offset of 00:00 UTC, <0 means disabled
+func (b *Trade) SetDailySummary(at time.Duration) {
+	b.summaryAt = at
+}
+
 func (b *Trade) AddScript(name, scriptFile, param string) (err error) {
 	err = b.engine.AddScript(name, scriptFile, param)
 	return
 }
 
+// ReloadScript reload the script without stopping the trade
+func (b *Trade) ReloadScript(name string) (err error) {
+	return b.engine.ReloadScript(name)
+}
+
+// SetWatchInterval reload the scripts when the files modified, check every interval
+func (b *Trade) SetWatchInterval(interval time.Duration) {
+	b.engine.SetWatchInterval(interval)
+}
+
+// SetHistory set the loader of history candles to warm up indicators
+func (b *Trade) SetHistory(fn engine.HistoryFn) {
+	b.engine.SetHistory(fn)
+}
+
+// ScriptStats return the resource usage of scripts
+func (b *Trade) ScriptStats() map[string]goscript.ScriptStats {
+	return b.engine.ScriptStats()
+}
+
+// SetStateStore save the state of scripts every interval and restore them when start
+func (b *Trade) SetStateStore(store goscript.StateStore, interval time.Duration) {
+	b.engine.SetStateStore(store, interval)
+}
+
 func (b *Trade) ScriptCount() int {
 	return b.engine.ScriptCount()
 }
@@ -110,24 +241,73 @@ func (b *Trade) Stop() (err error) {
 func (b *Trade) init() (err error) {
 	b.stop = make(chan bool)
 	param := event.NewBaseProcesser("param")
-	ex, err := exchange.GetTradeExchange(b.exchangeType, cfg, b.exchangeName, b.symbol)
+	b.proc = event.NewProcessers()
+	var policies map[string]string
+	err = cfg.UnmarshalKey("bus.policy", &policies)
 	if err != nil {
-		err = fmt.Errorf("creat exchange trade %s failed:%s", b.exchangeName, err.Error())
+		err = fmt.Errorf("parse bus.policy failed:%s", err.Error())
 		return
 	}
-	notify, err := notify.NewNotify(cfg)
-	if err != nil {
-		log.Errorf("creat notify failed:%s", err.Error())
-		err = nil
+	for k, v := range policies {
+		policy, err := event.ParseOverflowPolicy(v)
+		if err != nil {
+			return err
+		}
+		b.proc.SetPolicy(k, policy)
 	}
-	b.proc = event.NewProcessers()
-	procs := []event.Processer{param, ex, b.engine}
-	if notify != nil {
-		procs = append(procs, notify)
+	if addr := cfg.GetString("bus.broker"); addr != "" {
+		client, err := netbus.Dial(addr)
+		if err != nil {
+			return fmt.Errorf("connect broker %s failed:%s", addr, err.Error())
+		}
+		b.proc.SetTransport(client)
+	} else if len(b.roles) != len(Roles) {
+		log.Warn("bus.broker not set, the roles not run by this process are missing")
 	}
-	if b.rpt != nil {
-		r := rpt.NewRpt(b.rpt)
-		procs = append(procs, r)
+	procs := []event.Processer{param}
+	if b.HasRole(RoleMarket) || b.HasRole(RoleExecution) {
+		ex, err := exchange.GetTradeExchange(b.exchangeType, cfg, b.exchangeName, b.symbol)
+		if err != nil {
+			return fmt.Errorf("creat exchange trade %s failed:%s", b.exchangeName, err.Error())
+		}
+		ex.SetRole(b.HasRole(RoleMarket), b.HasRole(RoleExecution))
+		metrics.RegisterExchange(b.exchangeName, ex.Metrics())
+		procs = append(procs, ex)
+	}
+	if b.HasRole(RoleExecution) {
+		for _, v := range b.venues {
+			exType := cfg.GetString(fmt.Sprintf("exchanges.%s.type", v.name))
+			vex, err := exchange.GetTradeExchange(exType, cfg, v.name, v.symbol)
+			if err != nil {
+				err = fmt.Errorf("creat exchange trade %s failed:%s", v.name, err.Error())
+				return err
+			}
+			vex.SetDefault(false)
+			metrics.RegisterExchange(v.name, vex.Metrics())
+			procs = append(procs, vex)
+		}
+		procs = append(procs, b.algo)
+	}
+	if b.HasRole(RoleStrategy) {
+		procs = append(procs, b.engine)
+	}
+	procs = append(procs, metrics.NewRecorder())
+	if b.HasRole(RoleReport) {
+		notify, err := notify.NewNotify(cfg)
+		if err != nil {
+			log.Errorf("creat notify failed:%s", err.Error())
+		}
+		if notify != nil {
+			procs = append(procs, notify)
+		}
+		if b.rpt != nil {
+			r := rpt.NewRpt(b.rpt)
+			r.SetDailySummary(b.summaryAt)
+			for _, v := range b.venues {
+				r.AddVenue(v.name)
+			}
+			procs = append(procs, r)
+		}
 	}
 
 	err = b.proc.Adds(procs...)
@@ -140,6 +320,14 @@ func (b *Trade) init() (err error) {
 		log.Error("start processers error:", err.Error())
 		return
 	}
+	// the lever is set on exchange, scripts size positions with it as backtest
+	lever := cfg.GetFloat64(fmt.Sprintf("exchanges.%s.lever", b.exchangeName))
+	if lever > 0 && (b.HasRole(RoleStrategy) || b.HasRole(RoleReport)) {
+		param.Send("risk_init", EventRiskLimit, &RiskLimit{Code: b.symbol, Lever: lever})
+	}
+	if !b.HasRole(RoleMarket) {
+		return
+	}
 	candleParam := CandleParam{
 		Start:   time.Now().Add(-1 * b.loadRecent),
 		Symbol:  b.symbol,
@@ -1,12 +1,15 @@
 package event
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ztrade/ztrade/pkg/core"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -22,162 +25,568 @@ type ProcessCallInfo struct {
 }
 type ProcessList []ProcessCallInfo
 
+// Observer observe every event processed by the handlers, used for metrics
+type Observer interface {
+	OnProcess(e *Event, handler string, cost time.Duration, err error)
+}
+
+// OverflowPolicy what to do when the queue of subscriber is full
+type OverflowPolicy int
+
+const (
+	// PolicyBlock block the sender until the queue has space
+	PolicyBlock OverflowPolicy = iota
+	// PolicyDropOldest drop the oldest event in queue
+	PolicyDropOldest
+	// PolicyCoalesce replace the queued event with the same name, exchange and account by the latest one,
+	// block when full and nothing to replace
+	PolicyCoalesce
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case PolicyBlock:
+		return "block"
+	case PolicyDropOldest:
+		return "drop-oldest"
+	case PolicyCoalesce:
+		return "coalesce"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// ParseOverflowPolicy parse policy from string: block, drop-oldest, coalesce
+func ParseOverflowPolicy(str string) (p OverflowPolicy, err error) {
+	switch strings.ToLower(str) {
+	case "", "block":
+		p = PolicyBlock
+	case "drop-oldest", "drop_oldest", "dropoldest":
+		p = PolicyDropOldest
+	case "coalesce":
+		p = PolicyCoalesce
+	default:
+		err = fmt.Errorf("unknown overflow policy: %s", str)
+	}
+	return
+}
+
 // Bus event bus
 type Bus struct {
-	syncMode   bool
-	chs        map[string]chan *Event
-	cache      int
-	procs      map[string]ProcessList
-	procsMutex sync.RWMutex
-
-	processEvent  int64
-	lastEventTime time.Time
+	syncMode bool
+	cache    int
+	subs     map[string][]*subscriber
+	policies map[string]OverflowPolicy
+	mutex    sync.RWMutex
+
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	pending      int64
+	pendingMutex sync.Mutex
+	pendingCond  *sync.Cond
+
+	lastEventTime int64
 	routines      int32
+
+	dropped   map[string]int64
+	dropMutex sync.Mutex
+
+	observer Observer
+
+	transport Transport
+	// event types subscribed from transport
+	remoteSubs map[string]bool
+
+	clock Clock
 }
 
 func NewBus(cache int) *Bus {
-	b := new(Bus)
+	b := newBus()
+	if cache <= 0 {
+		cache = 1
+	}
 	b.cache = cache
-	b.chs = make(map[string]chan *Event)
-	b.procs = make(map[string]ProcessList)
 	return b
 }
 
 func NewSyncBus() *Bus {
-	b := new(Bus)
+	b := newBus()
 	b.syncMode = true
-	b.chs = make(map[string]chan *Event)
-	b.procs = make(map[string]ProcessList)
 	return b
 }
 
-func (b *Bus) runProc(sub string, ch chan *Event) (err error) {
-	atomic.AddInt32(&b.routines, 1)
+func newBus() *Bus {
+	b := new(Bus)
+	b.subs = make(map[string][]*subscriber)
+	b.policies = make(map[string]OverflowPolicy)
+	b.dropped = make(map[string]int64)
+	b.remoteSubs = make(map[string]bool)
+	b.pendingCond = sync.NewCond(&b.pendingMutex)
+	b.clock = WallClock{}
+	return b
+}
+
+// SetClock set the clock of processers, must be called before start
+func (b *Bus) SetClock(c Clock) {
+	b.clock = c
+}
+
+// Clock return the clock of processers
+func (b *Bus) Clock() Clock {
+	return b.clock
+}
+
+// Now current time of the clock
+func (b *Bus) Now() time.Time {
+	return b.clock.Now()
+}
+
+func (b *Bus) runProc(s *subscriber) {
+	defer b.wg.Done()
 	defer atomic.AddInt32(&b.routines, -1)
-	log.Debug("Bus runProc of ", sub)
-	if ch == nil {
-		err = fmt.Errorf("no such event channel: %s", sub)
-		panic(err.Error())
+	log.Debugf("Bus runProc of %s: %s", s.typ, s.Name)
+	for {
+		e, ok := s.pop()
+		if !ok {
+			return
+		}
+		err := b.call(s.ProcessCallInfo, e)
+		if err != nil {
+			b.Send(NewErrorEvent(s.Name, err.Error(), err))
+		}
+		b.done(e)
+	}
+}
+
+// run start the routine of subscriber, must be called with mutex locked
+func (b *Bus) run(s *subscriber) {
+	b.wg.Add(1)
+	atomic.AddInt32(&b.routines, 1)
+	go b.runProc(s)
+}
+
+func (b *Bus) call(p ProcessCallInfo, e *Event) (err error) {
+	if b.observer == nil {
+		return p.Cb(e)
+	}
+	t := time.Now()
+	err = p.Cb(e)
+	b.observer.OnProcess(e, p.Name, time.Since(t), err)
+	return
+}
+
+// SetObserver set observer, must be called before start
+func (b *Bus) SetObserver(o Observer) {
+	b.observer = o
+}
+
+// SetTransport set the transport to exchange events with other buses, should be used with async bus
+// the events sent by local processers are published, and the events subscribed are received from transport
+func (b *Bus) SetTransport(t Transport) (err error) {
+	b.mutex.Lock()
+	b.transport = t
+	var typs []string
+	for k := range b.subs {
+		typs = append(typs, k)
+	}
+	b.mutex.Unlock()
+	for _, v := range typs {
+		err = b.subscribeRemote(v)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// subscribeRemote subscribe event type from transport once
+func (b *Bus) subscribeRemote(typ string) (err error) {
+	b.mutex.Lock()
+	t := b.transport
+	if t == nil || b.remoteSubs[typ] || typ == core.EventError {
+		b.mutex.Unlock()
 		return
 	}
-	b.procsMutex.RLock()
-	procs := b.procs[sub]
-	b.procsMutex.RUnlock()
-	for e := range ch {
-		for _, p := range procs {
-			err = p.Cb(e)
-			if err != nil {
-				b.Send(NewErrorEvent(p.Name, err.Error(), err))
-				// log.Errorf("process %s error: %s", sub, err.Error())
-				continue
-			}
+	b.remoteSubs[typ] = true
+	b.mutex.Unlock()
+	return t.Subscribe(typ, b.receive)
+}
+
+// receive the event from transport, it is only delivered to local subscribers
+func (b *Bus) receive(e *Event) {
+	e.remote = true
+	b.Send(e)
+}
+
+// SetPolicy set the overflow policy of event type, default is PolicyBlock
+func (b *Bus) SetPolicy(sub string, policy OverflowPolicy) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.policies[sub] = policy
+	for _, s := range b.subs[sub] {
+		s.setPolicy(policy)
+	}
+}
+
+// QueueDepth return the count of events waiting in queue of every event type
+func (b *Bus) QueueDepth() (depth map[string]int) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	depth = make(map[string]int, len(b.subs))
+	for k, subs := range b.subs {
+		for _, s := range subs {
+			depth[k] += s.len()
 		}
-		atomic.AddInt64(&b.processEvent, -1)
-		releaseEvent(e)
 	}
 	return
 }
 
-// Subscribe event
+// Dropped return the count of events dropped of every event type
+func (b *Bus) Dropped() (dropped map[string]int64) {
+	b.dropMutex.Lock()
+	defer b.dropMutex.Unlock()
+	dropped = make(map[string]int64, len(b.dropped))
+	for k, v := range b.dropped {
+		dropped[k] = v
+	}
+	return
+}
+
+// Pending return the count of events sent but not processed
+func (b *Bus) Pending() int64 {
+	b.pendingMutex.Lock()
+	defer b.pendingMutex.Unlock()
+	return b.pending
+}
+
+// Routines return the count of running routines
+func (b *Bus) Routines() int32 {
+	return atomic.LoadInt32(&b.routines)
+}
+
+// LastEventTime return the time of last event sent
+func (b *Bus) LastEventTime() time.Time {
+	n := atomic.LoadInt64(&b.lastEventTime)
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// Subscribe event, can be called when bus is running
 func (b *Bus) Subscribe(from, sub string, cb ProcessCall) (err error) {
-	b.procsMutex.Lock()
-	pi := ProcessCallInfo{Cb: cb, Name: from}
-	_, ok := b.procs[sub]
-	if !ok {
-		b.procs[sub] = ProcessList{pi}
+	b.mutex.Lock()
+	s := newSubscriber(ProcessCallInfo{Cb: cb, Name: from}, sub, b.cache, b.policies[sub])
+	old := b.subs[sub]
+	subs := make([]*subscriber, len(old), len(old)+1)
+	copy(subs, old)
+	b.subs[sub] = append(subs, s)
+	if b.started {
+		b.run(s)
+	}
+	b.mutex.Unlock()
+	return b.subscribeRemote(sub)
+}
+
+// Unsubscribe remove all subscriptions of processer from event, the queued events are dropped
+func (b *Bus) Unsubscribe(from, sub string) (err error) {
+	b.mutex.Lock()
+	var subs, removed []*subscriber
+	for _, s := range b.subs[sub] {
+		if s.Name == from {
+			removed = append(removed, s)
+			continue
+		}
+		subs = append(subs, s)
+	}
+	if len(subs) == 0 {
+		delete(b.subs, sub)
 	} else {
-		b.procs[sub] = append(b.procs[sub], pi)
+		b.subs[sub] = subs
+	}
+	b.mutex.Unlock()
+	if len(removed) == 0 {
+		err = fmt.Errorf("%s not subscribe %s", from, sub)
+		return
+	}
+	for _, s := range removed {
+		b.drop(s.close()...)
 	}
-	b.procsMutex.Unlock()
 	return
 }
 
+// Send send event to subscribers, the event type must be registered in core
 func (b *Bus) Send(e *Event) (err error) {
 	typ := e.GetType()
-	procs, ok := b.procs[typ]
-	if !ok {
-		log.Warnf("Send %s event,but no subscribers, skip", e.GetType())
+	if _, ok := core.GetEventSchema(typ); !ok {
+		err = fmt.Errorf("%w: %s", ErrEventNotRegistered, typ)
+		log.Error("Send failed: ", err.Error())
+		releaseEvent(e)
+		return
+	}
+	b.mutex.RLock()
+	subs := b.subs[typ]
+	t := b.transport
+	b.mutex.RUnlock()
+	if t != nil && !e.remote && typ != core.EventError {
+		err = t.Publish(e)
+		if err != nil {
+			log.Errorf("publish %s event failed: %s", typ, err.Error())
+			err = nil
+		}
+	}
+	if len(subs) == 0 {
+		b.dropMutex.Lock()
+		if _, ok := b.dropped[typ]; !ok {
+			log.Warnf("Send %s event,but no subscribers, skip", typ)
+		}
+		b.dropped[typ]++
+		b.dropMutex.Unlock()
+		releaseEvent(e)
 		return
 	}
-	atomic.AddInt64(&b.processEvent, 1)
+	b.addPending(1)
+	atomic.StoreInt64(&b.lastEventTime, time.Now().UnixNano())
 	if b.syncMode {
-		return b.sendSync(procs, e)
+		return b.sendSync(subs, e)
+	}
+	atomic.StoreInt32(&e.refs, int32(len(subs)))
+	for _, s := range subs {
+		if dropped := s.push(e); dropped != nil {
+			b.drop(dropped)
+		}
 	}
-
-	chs := b.chs[typ]
-	b.lastEventTime = time.Now()
-	chs <- e
 	return
 }
-func (b *Bus) sendSync(procs ProcessList, e *Event) (err error) {
-	for _, p := range procs {
-		err = p.Cb(e)
+
+func (b *Bus) sendSync(subs []*subscriber, e *Event) (err error) {
+	for _, s := range subs {
+		err = b.call(s.ProcessCallInfo, e)
 		if err != nil {
 			// log.Errorf("subscribe %s process error: %s", e.GetType(), err.Error())
-			b.Send(NewErrorEvent(p.Name, err.Error(), err))
+			b.Send(NewErrorEvent(s.Name, err.Error(), err))
 			continue
 		}
 	}
 	releaseEvent(e)
-	atomic.AddInt64(&b.processEvent, -1)
+	b.addPending(-1)
 	return
 }
 
-func (b *Bus) WaitEmpty() {
-	//	time.Sleep(time.Millisecond)
-	value := atomic.LoadInt64(&b.processEvent)
-	for value != 0 {
-		time.Sleep(time.Millisecond)
-		value = atomic.LoadInt64(&b.processEvent)
+// done finish one reference of event, the event is released after all subscribers done
+func (b *Bus) done(e *Event) {
+	if atomic.AddInt32(&e.refs, -1) != 0 {
+		return
 	}
+	releaseEvent(e)
+	b.addPending(-1)
 }
 
-func (b *Bus) Close() {
-	t := time.Now()
-	var value int64
-	for {
-		time.Sleep(time.Nanosecond)
-		value = atomic.LoadInt64(&b.processEvent)
-		if value != 0 {
-			continue
-		}
-		if time.Since(b.lastEventTime) > time.Second*5 || time.Since(t) > time.Second*5 {
-			break
-		}
+// drop drop the events which will not be processed by subscriber
+func (b *Bus) drop(events ...*Event) {
+	if len(events) == 0 {
+		return
+	}
+	b.dropMutex.Lock()
+	for _, e := range events {
+		b.dropped[e.GetType()]++
+	}
+	b.dropMutex.Unlock()
+	for _, e := range events {
+		b.done(e)
 	}
+}
 
-	for _, v := range b.chs {
-		close(v)
+func (b *Bus) addPending(n int64) {
+	b.pendingMutex.Lock()
+	b.pending += n
+	if b.pending == 0 {
+		b.pendingCond.Broadcast()
 	}
+	b.pendingMutex.Unlock()
+}
 
-	var n int32
-	for {
-		n = atomic.LoadInt32(&b.routines)
-		if n == 0 {
-			break
+// waitEmpty wait until all events processed, return false if timeout
+func (b *Bus) waitEmpty(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		b.pendingMutex.Lock()
+		b.pendingCond.Broadcast()
+		b.pendingMutex.Unlock()
+	})
+	defer timer.Stop()
+	b.pendingMutex.Lock()
+	defer b.pendingMutex.Unlock()
+	for b.pending != 0 {
+		if !time.Now().Before(deadline) {
+			return false
 		}
-		time.Sleep(time.Millisecond)
-		log.Info("event bus routines all finished, left:", n)
+		b.pendingCond.Wait()
+	}
+	return true
+}
+
+func (b *Bus) WaitEmpty() {
+	b.pendingMutex.Lock()
+	for b.pending != 0 {
+		b.pendingCond.Wait()
+	}
+	b.pendingMutex.Unlock()
+}
+
+// Close wait for the events processed at most 5 seconds, and then stop all routines
+func (b *Bus) Close() {
+	b.mutex.RLock()
+	started := b.started
+	b.mutex.RUnlock()
+	if !started {
+		return
+	}
+	if !b.waitEmpty(time.Second * 5) {
+		log.Warnf("event bus close with %d events not processed", b.Pending())
 	}
+	b.cancel()
+	b.shutdown()
+	b.wg.Wait()
+	if b.transport != nil {
+		b.transport.Close()
+	}
+	log.Info("event bus routines all finished")
 }
 
+// Start start the bus
 func (b *Bus) Start() {
+	b.StartContext(context.Background())
+}
+
+// StartContext start the bus, all routines stop when ctx done, and the events not processed are dropped
+func (b *Bus) StartContext(ctx context.Context) {
 	if b.syncMode {
 		return
 	}
-	for k := range b.procs {
-		ch := make(chan *Event, b.cache)
-		b.chs[k] = ch
-		go b.runProc(k, ch)
+	b.mutex.Lock()
+	if b.started {
+		b.mutex.Unlock()
+		return
 	}
-	// wait for all routines start
-	var n int32
-	for {
-		n = atomic.LoadInt32(&b.routines)
-		if n == int32(len(b.procs)) {
-			break
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.started = true
+	for _, subs := range b.subs {
+		for _, s := range subs {
+			b.run(s)
+		}
+	}
+	b.mutex.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.shutdown()
+	}()
+}
+
+func (b *Bus) shutdown() {
+	b.mutex.Lock()
+	if !b.started {
+		b.mutex.Unlock()
+		return
+	}
+	b.started = false
+	var all []*subscriber
+	for _, subs := range b.subs {
+		all = append(all, subs...)
+	}
+	b.mutex.Unlock()
+	for _, s := range all {
+		b.drop(s.close()...)
+	}
+}
+
+// subscriber queue and routine of one subscription
+type subscriber struct {
+	ProcessCallInfo
+	typ    string
+	size   int
+	policy OverflowPolicy
+
+	queue  []*Event
+	closed bool
+	mutex  sync.Mutex
+	cond   *sync.Cond
+}
+
+func newSubscriber(pi ProcessCallInfo, typ string, size int, policy OverflowPolicy) *subscriber {
+	s := &subscriber{ProcessCallInfo: pi, typ: typ, size: size, policy: policy}
+	s.cond = sync.NewCond(&s.mutex)
+	return s
+}
+
+func (s *subscriber) setPolicy(policy OverflowPolicy) {
+	s.mutex.Lock()
+	s.policy = policy
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+}
+
+func (s *subscriber) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.queue)
+}
+
+// push add event to queue, return the event dropped
+func (s *subscriber) push(e *Event) (dropped *Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.policy == PolicyCoalesce {
+		for i, v := range s.queue {
+			if v.Name == e.Name && v.Exchange == e.Exchange && v.Account == e.Account {
+				dropped = v
+				s.queue[i] = e
+				return
+			}
 		}
-		time.Sleep(time.Millisecond)
-		log.Infof("event bus %d routines, started: %d", len(b.procs), n)
 	}
+	for !s.closed && len(s.queue) >= s.size && s.policy != PolicyDropOldest {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return e
+	}
+	if len(s.queue) >= s.size {
+		dropped = s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+	}
+	s.queue = append(s.queue, e)
+	s.cond.Broadcast()
+	return
+}
+
+// pop wait and return the first event in queue, return false if closed
+func (s *subscriber) pop() (e *Event, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for !s.closed && len(s.queue) == 0 {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return
+	}
+	e = s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	s.cond.Broadcast()
+	return e, true
+}
+
+// close close the queue and return the events left
+func (s *subscriber) close() (left []*Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	left = s.queue
+	s.queue = nil
+	s.cond.Broadcast()
+	return
 }
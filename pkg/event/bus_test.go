@@ -0,0 +1,111 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBusSubscribeRunning(t *testing.T) {
+	b := NewBus(16)
+	b.Start()
+	var n int32
+	b.Subscribe("test", "candle", func(e *Event) error {
+		atomic.AddInt32(&n, 1)
+		return nil
+	})
+	for i := 0; i < 10; i++ {
+		b.Send(NewEvent("candle", "candle", "test", i, nil))
+	}
+	b.WaitEmpty()
+	if atomic.LoadInt32(&n) != 10 {
+		t.Fatalf("processed %d events, expect 10", n)
+	}
+	err := b.Unsubscribe("test", "candle")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b.Send(NewEvent("candle", "candle", "test", 0, nil))
+	if atomic.LoadInt32(&n) != 10 || b.Dropped()["candle"] != 1 {
+		t.Fatalf("event processed after unsubscribe")
+	}
+	b.Close()
+	if b.Routines() != 0 {
+		t.Fatalf("routines left: %d", b.Routines())
+	}
+}
+
+func TestBusOverflowPolicy(t *testing.T) {
+	b := NewBus(2)
+	b.SetPolicy("depth", PolicyCoalesce)
+	b.SetPolicy("trade", PolicyDropOldest)
+	release := make(chan bool)
+	var mutex sync.Mutex
+	var depths, trades []interface{}
+	b.Subscribe("test", "depth", func(e *Event) error {
+		<-release
+		mutex.Lock()
+		depths = append(depths, e.GetData())
+		mutex.Unlock()
+		return nil
+	})
+	b.Subscribe("test", "trade", func(e *Event) error {
+		<-release
+		mutex.Lock()
+		trades = append(trades, e.GetData())
+		mutex.Unlock()
+		return nil
+	})
+	b.Start()
+	// the first event is taken by the handler
+	b.Send(NewEvent("BTCUSDT", "depth", "test", 0, nil))
+	b.Send(NewEvent("BTCUSDT", "trade", "test", 0, nil))
+	for b.QueueDepth()["depth"] != 0 || b.QueueDepth()["trade"] != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i < 5; i++ {
+		b.Send(NewEvent("BTCUSDT", "depth", "test", i, nil))
+		b.Send(NewEvent("BTCUSDT", "trade", "test", i, nil))
+	}
+	close(release)
+	b.WaitEmpty()
+	b.Close()
+	if len(depths) != 2 || depths[1] != 4 {
+		t.Fatalf("coalesce failed: %v", depths)
+	}
+	if len(trades) != 3 || trades[1] != 3 || trades[2] != 4 {
+		t.Fatalf("drop oldest failed: %v", trades)
+	}
+}
+
+func TestBusContextShutdown(t *testing.T) {
+	b := NewBus(4)
+	ctx, cancel := context.WithCancel(context.Background())
+	block := make(chan bool)
+	b.Subscribe("slow", "candle", func(e *Event) error {
+		<-block
+		return nil
+	})
+	var n int32
+	b.Subscribe("fast", "candle", func(e *Event) error {
+		atomic.AddInt32(&n, 1)
+		return nil
+	})
+	b.StartContext(ctx)
+	for i := 0; i < 3; i++ {
+		b.Send(NewEvent("candle", "candle", "test", i, nil))
+	}
+	// slow subscriber don't stall others
+	for atomic.LoadInt32(&n) != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	close(block)
+	b.WaitEmpty()
+	b.wg.Wait()
+	if b.Routines() != 0 {
+		t.Fatalf("routines left: %d", b.Routines())
+	}
+}
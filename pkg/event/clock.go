@@ -0,0 +1,66 @@
+package event
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ztrade/base/common"
+	"github.com/ztrade/trademodel"
+)
+
+// Clock source of current time, processers get it from the bus
+type Clock interface {
+	Now() time.Time
+}
+
+// WallClock clock of system time, used in real trade
+type WallClock struct{}
+
+func (WallClock) Now() time.Time {
+	return time.Now()
+}
+
+// SimClock clock driven by the time of events, used in backtest
+// it's the end of the latest candle, so the orders sent in OnCandle have the same time as real trade
+type SimClock struct {
+	now int64
+}
+
+// NewSimClock constructor of SimClock
+func NewSimClock() *SimClock {
+	return new(SimClock)
+}
+
+func (c *SimClock) Now() time.Time {
+	n := atomic.LoadInt64(&c.now)
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// Set move the clock to t, the clock never goes back
+func (c *SimClock) Set(t time.Time) {
+	n := t.UnixNano()
+	for {
+		old := atomic.LoadInt64(&c.now)
+		if n <= old || atomic.CompareAndSwapInt64(&c.now, old, n) {
+			return
+		}
+	}
+}
+
+// onCandle move the clock to the end of candle
+func (c *SimClock) onCandle(e *Event) (err error) {
+	candle, ok := e.GetData().(*trademodel.Candle)
+	if !ok || candle.ID == -1 {
+		return
+	}
+	binSize, _ := ExtraAs[string](e)
+	dur, err := common.GetBinSizeDuration(binSize)
+	if err != nil || dur <= 0 {
+		dur, err = time.Minute, nil
+	}
+	c.Set(time.Unix(candle.Start, 0).Add(dur))
+	return
+}
@@ -0,0 +1,146 @@
+package event
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ztrade/ztrade/pkg/core"
+)
+
+// Format encoding format of event
+type Format int
+
+const (
+	// FormatJSON json, start with '{'
+	FormatJSON Format = iota
+	// FormatBinary gob with magic header
+	FormatBinary
+)
+
+const binaryMagic = 0x5a
+
+var (
+	ErrEventNotRegistered = errors.New("event not registered")
+	ErrEventVersion       = errors.New("event version not supported")
+	ErrEventFormat        = errors.New("unknown event format")
+)
+
+// jsonEvent json format of event
+type jsonEvent struct {
+	Version  int            `json:"v"`
+	Name     string         `json:"name"`
+	From     string         `json:"from"`
+	Exchange string         `json:"exchange,omitempty"`
+	Account  string         `json:"account,omitempty"`
+	Data     core.EventData `json:"data"`
+}
+
+// binaryEvent binary format of event
+type binaryEvent struct {
+	Version  int
+	Type     string
+	Name     string
+	From     string
+	Exchange string
+	Account  string
+	Data     interface{}
+	Extra    interface{}
+}
+
+// EncodeEvent encode event with the schema version, the data type must match the registry
+func EncodeEvent(e *Event, format Format) (buf []byte, err error) {
+	schema, err := checkEvent(e)
+	if err != nil {
+		return
+	}
+	data := e.GetData()
+	if schema.IsError() && data != nil {
+		data = data.(error).Error()
+	}
+	switch format {
+	case FormatJSON:
+		je := jsonEvent{Version: schema.Version, Name: e.Name, From: e.From, Exchange: e.Exchange, Account: e.Account, Data: e.Data}
+		return json.Marshal(je)
+	case FormatBinary:
+		be := binaryEvent{Version: schema.Version, Type: e.GetType(), Name: e.Name, From: e.From, Exchange: e.Exchange, Account: e.Account, Data: data, Extra: e.GetExtra()}
+		var b bytes.Buffer
+		b.WriteByte(binaryMagic)
+		err = gob.NewEncoder(&b).Encode(&be)
+		buf = b.Bytes()
+	default:
+		err = ErrEventFormat
+	}
+	return
+}
+
+// DecodeEvent decode event encoded by EncodeEvent, the format is detected by the first byte
+func DecodeEvent(buf []byte) (e *Event, err error) {
+	if len(buf) == 0 {
+		err = ErrEventFormat
+		return
+	}
+	e = new(Event)
+	var version int
+	switch buf[0] {
+	case '{':
+		var je jsonEvent
+		err = json.Unmarshal(buf, &je)
+		if err != nil {
+			return
+		}
+		version = je.Version
+		e.Name, e.From, e.Exchange, e.Account, e.Data = je.Name, je.From, je.Exchange, je.Account, je.Data
+	case binaryMagic:
+		var be binaryEvent
+		err = gob.NewDecoder(bytes.NewReader(buf[1:])).Decode(&be)
+		if err != nil {
+			return
+		}
+		version = be.Version
+		e.Name, e.From, e.Exchange, e.Account = be.Name, be.From, be.Exchange, be.Account
+		e.Data = core.EventData{Type: be.Type, Data: be.Data, Extra: be.Extra}
+		if msg, ok := be.Data.(string); ok && be.Type == core.EventError {
+			e.Data.Data = errors.New(msg)
+		}
+	default:
+		err = ErrEventFormat
+		return
+	}
+	schema, err := checkEvent(e)
+	if err != nil {
+		return
+	}
+	if version > schema.Version {
+		err = fmt.Errorf("%w: %s version %d, support %d", ErrEventVersion, e.GetType(), version, schema.Version)
+		return
+	}
+	return
+}
+
+// checkEvent check the event data and extra with registry
+func checkEvent(e *Event) (schema core.EventSchema, err error) {
+	typ := e.GetType()
+	schema, ok := core.GetEventSchema(typ)
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrEventNotRegistered, typ)
+		return
+	}
+	if data := e.GetData(); data != nil {
+		if schema.IsError() {
+			if _, ok = data.(error); !ok {
+				err = fmt.Errorf("event %s data should be error, got %T", typ, data)
+				return
+			}
+		} else if reflect.TypeOf(data) != reflect.PointerTo(schema.Data) {
+			err = fmt.Errorf("event %s data should be *%s, got %T", typ, schema.Data, data)
+			return
+		}
+	}
+	if extra := e.GetExtra(); extra != nil && schema.Extra != nil && reflect.TypeOf(extra) != schema.Extra {
+		err = fmt.Errorf("event %s extra should be %s, got %T", typ, schema.Extra, extra)
+	}
+	return
+}
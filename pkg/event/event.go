@@ -18,6 +18,15 @@ type Event struct {
 	Name string
 	// Time time.Time
 	From string
+	// Exchange exchange type of the event, empty means the default exchange
+	Exchange string
+	// Account exchange account(config name) of the event, empty means the default account
+	Account string
+
+	// subscribers not processed yet
+	refs int32
+	// received from transport
+	remote bool
 }
 
 func NewErrorEvent(from, msg string, err error) *Event {
@@ -39,6 +48,9 @@ func NewEvent(name, strType, from string, data interface{}, extra interface{}) *
 	e.Data.Data = data
 	// e.Time = time.Now()
 	e.Data.Extra = extra
+	e.Exchange = ""
+	e.Account = ""
+	e.remote = false
 	return e
 }
 
@@ -60,6 +72,14 @@ func (e *Event) GetType() string {
 // 	return e.Time
 // }
 
+func (e *Event) GetExchange() string {
+	return e.Exchange
+}
+
+func (e *Event) GetAccount() string {
+	return e.Account
+}
+
 func (e *Event) GetFrom() string {
 	return e.From
 }
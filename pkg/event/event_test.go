@@ -1,8 +1,11 @@
 package event
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/ztrade/trademodel"
 	"github.com/ztrade/ztrade/pkg/core"
 )
 
@@ -19,3 +22,122 @@ func TestUnmarshalEvent(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 }
+
+func TestEncodeEvent(t *testing.T) {
+	e := NewEvent("candle", core.EventCandle, "test", &trademodel.Candle{Start: 1, Close: 100}, "1m")
+	for _, format := range []Format{FormatJSON, FormatBinary} {
+		buf, err := EncodeEvent(e, format)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		ret, err := DecodeEvent(buf)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		candle, ok := ret.GetData().(*trademodel.Candle)
+		if !ok || candle.Close != 100 || ret.GetExtra() != "1m" || ret.GetName() != "candle" {
+			t.Fatalf("decode %d failed: %#v", format, ret)
+		}
+	}
+	errEvent := NewErrorEvent("test", "failed", errors.New("failed"))
+	buf, err := EncodeEvent(errEvent, FormatJSON)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ret, err := DecodeEvent(buf)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if e, ok := ret.GetData().(error); !ok || e.Error() != "failed" {
+		t.Fatalf("decode error event failed: %#v", ret.GetData())
+	}
+	_, err = DecodeEvent([]byte(`{"v":100,"data":{"type":"candle","data":{}}}`))
+	if !errors.Is(err, ErrEventVersion) {
+		t.Fatalf("newer version should fail: %v", err)
+	}
+	_, err = EncodeEvent(NewEvent("candle", core.EventCandle, "test", &trademodel.Trade{}, nil), FormatJSON)
+	if err == nil {
+		t.Fatal("encode wrong data type should fail")
+	}
+}
+
+func TestSubscribeData(t *testing.T) {
+	p := NewBaseProcesser("test")
+	p.Init(NewSyncBus())
+	var closed float64
+	err := SubscribeData(p, core.EventCandle, func(e *Event, candle *trademodel.Candle) error {
+		closed = candle.Close
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = SubscribeData(p, core.EventTrade, func(e *Event, candle *trademodel.Candle) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatal("subscribe with wrong type should fail")
+	}
+	p.Send("candle", core.EventCandle, &trademodel.Candle{Close: 10})
+	if closed != 10 {
+		t.Fatalf("typed handler not called")
+	}
+}
+
+// badProcesser subscribe event with wrong data type
+type badProcesser struct {
+	BaseProcesser
+}
+
+func (p *badProcesser) Init(bus *Bus) (err error) {
+	p.BaseProcesser.Init(bus)
+	return SubscribeData(p, core.EventTrade, func(e *Event, candle *trademodel.Candle) error {
+		return nil
+	})
+}
+
+func TestInitError(t *testing.T) {
+	procs := NewSyncProcessers()
+	procs.Add(&badProcesser{BaseProcesser: BaseProcesser{Name: "bad"}})
+	err := procs.Start()
+	if err == nil {
+		t.Fatal("start should fail when subscribe failed")
+	}
+}
+
+type clockProcesser struct {
+	BaseProcesser
+	now []time.Time
+}
+
+func (p *clockProcesser) Init(bus *Bus) (err error) {
+	p.BaseProcesser.Init(bus)
+	return SubscribeData(p, core.EventCandle, func(e *Event, candle *trademodel.Candle) error {
+		p.now = append(p.now, p.Now())
+		return nil
+	})
+}
+
+func TestSimClock(t *testing.T) {
+	procs := NewSyncProcessers()
+	procs.SetClock(NewSimClock())
+	p := &clockProcesser{BaseProcesser: BaseProcesser{Name: "clock"}}
+	procs.Add(p)
+	err := procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.SendWithExtra("candle", core.EventCandle, &trademodel.Candle{Start: start.Unix()}, "1m")
+	p.SendWithExtra("candle", core.EventCandle, &trademodel.Candle{ID: -1, Start: start.Add(time.Hour).Unix()}, "1m")
+	p.SendWithExtra("candle", core.EventCandle, &trademodel.Candle{Start: start.Add(time.Minute).Unix()}, "1m")
+	expect := []time.Time{start.Add(time.Minute), start.Add(time.Minute), start.Add(time.Minute * 2)}
+	if len(p.now) != len(expect) {
+		t.Fatalf("candles received: %d", len(p.now))
+	}
+	for i, v := range expect {
+		if !p.now[i].Equal(v) {
+			t.Fatalf("clock %d error: %s, expect %s", i, p.now[i], v)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package event
 
+import "time"
+
 // Processer handler of event
 type Processer interface {
 	Init(*Bus) error
@@ -24,8 +26,7 @@ func NewBaseProcesser(name string) *BaseProcesser {
 
 // Subscribe event
 func (b *BaseProcesser) Subscribe(sub string, cb ProcessCall) (err error) {
-	b.Bus.Subscribe(b.Name, sub, cb)
-	return
+	return b.Bus.Subscribe(b.Name, sub, cb)
 }
 
 // Send send event
@@ -54,11 +55,26 @@ func (b *BaseProcesser) Stop() (err error) {
 	return
 }
 
+// Now current time of the bus clock, it's the time of events in backtest
+func (b *BaseProcesser) Now() time.Time {
+	if b.Bus == nil {
+		return time.Now()
+	}
+	return b.Bus.Now()
+}
+
 // GetName return the processer name
 func (b *BaseProcesser) GetName() string {
 	return b.Name
 }
 
+// SendToAccount send event to the exchange account
+func (b *BaseProcesser) SendToAccount(account, name, strType string, data interface{}) {
+	e := NewEvent(name, strType, b.Name, data, nil)
+	e.Account = account
+	b.Bus.Send(e)
+}
+
 // CreateEvent create new event
 func (b *BaseProcesser) CreateEvent(name, strType string, data interface{}) *Event {
 	return NewEvent(name, strType, b.Name, data, nil)
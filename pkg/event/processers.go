@@ -20,6 +20,8 @@ type Processers struct {
 func NewProcessers() *Processers {
 	p := new(Processers)
 	p.bus = NewBus(1024)
+	// only the latest depth is useful
+	p.bus.SetPolicy(core.EventDepth, PolicyCoalesce)
 	return p
 }
 
@@ -35,6 +37,21 @@ func (h *Processers) SetErrorCallback(fn ErrorCallback) {
 
 }
 
+// SetTransport set the transport to exchange events with other processes
+func (h *Processers) SetTransport(t Transport) (err error) {
+	return h.bus.SetTransport(t)
+}
+
+// SetClock set the clock of all processers, SimClock is driven by the candles
+func (h *Processers) SetClock(c Clock) {
+	h.bus.SetClock(c)
+}
+
+// SetPolicy set the overflow policy of event type
+func (h *Processers) SetPolicy(typ string, policy OverflowPolicy) {
+	h.bus.SetPolicy(typ, policy)
+}
+
 func (h *Processers) onError(e *Event) error {
 	errInfo := e.Data.Data.(error)
 	if h.errorCb == nil {
@@ -63,13 +80,24 @@ func (h *Processers) Add(eh Processer) (err error) {
 
 // Start start all processers
 func (h *Processers) Start() (err error) {
+	// move the clock before the processers receive the candle
+	if c, ok := h.bus.Clock().(*SimClock); ok {
+		err = h.bus.Subscribe("Processers", core.EventCandle, c.onCandle)
+		if err != nil {
+			return
+		}
+	}
 	for _, p := range h.handlers {
 		err = p.Init(h.bus)
 		if err != nil {
+			err = fmt.Errorf("init processer %s failed:%s", p.GetName(), err.Error())
 			return
 		}
 	}
-	h.bus.Subscribe("Processers", core.EventError, h.onError)
+	err = h.bus.Subscribe("Processers", core.EventError, h.onError)
+	if err != nil {
+		return
+	}
 	h.bus.Start()
 	for _, p := range h.handlers {
 		err = p.Start()
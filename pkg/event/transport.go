@@ -0,0 +1,111 @@
+package event
+
+import (
+	"sync"
+)
+
+// Transport deliver events between buses, the buses may be in other processes or hosts
+type Transport interface {
+	// Publish publish the event sent by local processers
+	Publish(e *Event) error
+	// Subscribe receive the events of type published by others
+	Subscribe(typ string, cb func(e *Event)) error
+	Close() error
+}
+
+// LocalHub connect buses in one process
+type LocalHub struct {
+	mutex sync.RWMutex
+	subs  map[string][]*localTransport
+}
+
+// NewLocalHub create in-process hub
+func NewLocalHub() *LocalHub {
+	h := new(LocalHub)
+	h.subs = make(map[string][]*localTransport)
+	return h
+}
+
+// Connect create transport of bus connected to hub
+func (h *LocalHub) Connect() Transport {
+	return &localTransport{hub: h, cbs: make(map[string]func(*Event))}
+}
+
+func (h *LocalHub) publish(from *localTransport, e *Event) {
+	h.mutex.RLock()
+	subs := h.subs[e.GetType()]
+	h.mutex.RUnlock()
+	for _, t := range subs {
+		if t == from {
+			continue
+		}
+		t.receive(e)
+	}
+}
+
+func (h *LocalHub) subscribe(t *localTransport, typ string) {
+	h.mutex.Lock()
+	h.subs[typ] = append(h.subs[typ], t)
+	h.mutex.Unlock()
+}
+
+func (h *LocalHub) remove(t *localTransport) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for k, subs := range h.subs {
+		var left []*localTransport
+		for _, v := range subs {
+			if v != t {
+				left = append(left, v)
+			}
+		}
+		h.subs[k] = left
+	}
+}
+
+// localTransport transport of one bus in LocalHub
+type localTransport struct {
+	hub    *LocalHub
+	mutex  sync.RWMutex
+	cbs    map[string]func(*Event)
+	closed bool
+}
+
+func (t *localTransport) Publish(e *Event) (err error) {
+	t.hub.publish(t, e)
+	return
+}
+
+func (t *localTransport) Subscribe(typ string, cb func(e *Event)) (err error) {
+	t.mutex.Lock()
+	_, ok := t.cbs[typ]
+	t.cbs[typ] = cb
+	t.mutex.Unlock()
+	if !ok {
+		t.hub.subscribe(t, typ)
+	}
+	return
+}
+
+func (t *localTransport) receive(e *Event) {
+	t.mutex.RLock()
+	cb, ok := t.cbs[e.GetType()]
+	closed := t.closed
+	t.mutex.RUnlock()
+	if !ok || closed {
+		return
+	}
+	// every bus release its events, so copy it
+	ne := NewEvent(e.Name, e.GetType(), e.From, e.GetData(), e.GetExtra())
+	ne.Exchange = e.Exchange
+	ne.Account = e.Account
+	cb(ne)
+}
+
+func (t *localTransport) Close() (err error) {
+	t.mutex.Lock()
+	t.closed = true
+	t.mutex.Unlock()
+	t.hub.remove(t)
+	return
+}
@@ -0,0 +1,37 @@
+package event
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ztrade/ztrade/pkg/core"
+)
+
+// Subscriber can subscribe event, eg: BaseProcesser
+type Subscriber interface {
+	Subscribe(sub string, cb ProcessCall) error
+}
+
+// SubscribeData subscribe event with typed data, T must be the data type registered in core
+func SubscribeData[T any](p Subscriber, sub string, cb func(e *Event, data *T) error) (err error) {
+	schema, ok := core.GetEventSchema(sub)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrEventNotRegistered, sub)
+	}
+	if typ := reflect.TypeOf((*T)(nil)).Elem(); typ != schema.Data {
+		return fmt.Errorf("event %s data is %s, not %s", sub, schema.Data, typ)
+	}
+	return p.Subscribe(sub, func(e *Event) error {
+		data, ok := e.GetData().(*T)
+		if !ok {
+			return fmt.Errorf("event %s from %s data type error: %T", sub, e.GetFrom(), e.GetData())
+		}
+		return cb(e, data)
+	})
+}
+
+// ExtraAs return the extra of event as E
+func ExtraAs[E any](e *Event) (v E, ok bool) {
+	v, ok = e.GetExtra().(E)
+	return
+}
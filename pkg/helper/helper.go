@@ -4,10 +4,29 @@ import (
 	"github.com/ztrade/base/common"
 	"github.com/ztrade/base/engine"
 	. "github.com/ztrade/trademodel"
+	zengine "github.com/ztrade/ztrade/pkg/process/goscript/engine"
 )
 
 type CandleFn func(candle Candle)
 type Engine = engine.Engine
+
+// the extra apis of engine, get them by engine.(XxxEngine)
+type (
+	AlgoEngine      = zengine.AlgoEngine
+	VenueEngine     = zengine.VenueEngine
+	SymbolEngine    = zengine.SymbolEngine
+	IndicatorEngine = zengine.IndicatorEngine
+	AccountEngine   = zengine.AccountEngine
+	SizingEngine    = zengine.SizingEngine
+	TimerEngine     = zengine.TimerEngine
+)
+
+// volatility kinds of SizingEngine.Volatility
+const (
+	VolATR    = zengine.VolATR
+	VolStdDev = zengine.VolStdDev
+)
+
 type Param = common.Param
 type ParamData = common.ParamData
 
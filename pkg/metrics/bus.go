@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+var (
+	queueDepthDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "bus", "queue_depth"),
+		"events waiting in queue of every event type", []string{"event"}, nil)
+	pendingDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "bus", "pending_events"),
+		"events sent but not processed", nil, nil)
+	routinesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "bus", "routines"),
+		"running routines of bus", nil, nil)
+	droppedDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "bus", "dropped_events_total"),
+		"events dropped by overflow policy or without subscribers", []string{"event"}, nil)
+	lastEventDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "bus", "last_event_timestamp_seconds"),
+		"unix time of last event sent", nil, nil)
+)
+
+// busCollector collect the state of bus when scraped
+type busCollector struct {
+	bus   *Bus
+	mutex sync.Mutex
+}
+
+func (c *busCollector) setBus(bus *Bus) {
+	c.mutex.Lock()
+	c.bus = bus
+	c.mutex.Unlock()
+}
+
+func (c *busCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- pendingDesc
+	ch <- routinesDesc
+	ch <- droppedDesc
+	ch <- lastEventDesc
+}
+
+func (c *busCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	bus := c.bus
+	c.mutex.Unlock()
+	if bus == nil {
+		return
+	}
+	for k, v := range bus.QueueDepth() {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(v), k)
+	}
+	ch <- prometheus.MustNewConstMetric(pendingDesc, prometheus.GaugeValue, float64(bus.Pending()))
+	ch <- prometheus.MustNewConstMetric(routinesDesc, prometheus.GaugeValue, float64(bus.Routines()))
+	for k, v := range bus.Dropped() {
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(v), k)
+	}
+	var last float64
+	if t := bus.LastEventTime(); !t.IsZero() {
+		last = float64(t.UnixNano()) / 1e9
+	}
+	ch <- prometheus.MustNewConstMetric(lastEventDesc, prometheus.GaugeValue, last)
+}
+
+// busObserver record the processing latency and errors of handlers
+type busObserver struct{}
+
+func (o busObserver) OnProcess(e *Event, handler string, cost time.Duration, err error) {
+	eventDuration.WithLabelValues(e.GetType(), handler).Observe(cost.Seconds())
+	if err != nil {
+		handlerErrors.WithLabelValues(handler).Inc()
+	}
+}
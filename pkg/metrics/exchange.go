@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ztrade/ztrade/pkg/process/exchange"
+)
+
+var (
+	exchangeCallsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "exchange", "calls_total"),
+		"calls of exchange api by result", []string{"account", "endpoint", "type"}, nil)
+	exchangeLimitWaitDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "exchange", "limit_wait_seconds_total"),
+		"time blocked by local rate limiter", []string{"account", "endpoint"}, nil)
+)
+
+// exchangeCollector collect the call metrics of exchanges
+type exchangeCollector struct {
+	metrics map[string]*exchange.Metrics
+	mutex   sync.Mutex
+}
+
+// RegisterExchange register the call metrics of exchange account
+func RegisterExchange(account string, m *exchange.Metrics) {
+	exchangeStats.mutex.Lock()
+	exchangeStats.metrics[account] = m
+	exchangeStats.mutex.Unlock()
+}
+
+func (c *exchangeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- exchangeCallsDesc
+	ch <- exchangeLimitWaitDesc
+}
+
+func (c *exchangeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for account, m := range c.metrics {
+		c.collect(ch, account, m)
+	}
+}
+
+func (c *exchangeCollector) collect(ch chan<- prometheus.Metric, account string, m *exchange.Metrics) {
+	for k, v := range m.Snapshot() {
+		values := map[string]int64{
+			"call":        v.Calls,
+			"retry":       v.Retries,
+			"ratelimited": v.RateLimited,
+			"rejected":    v.Rejected,
+			"failed":      v.Failed,
+			"limit_wait":  v.LimitWaits,
+		}
+		for typ, n := range values {
+			ch <- prometheus.MustNewConstMetric(exchangeCallsDesc, prometheus.CounterValue, float64(n), account, k, typ)
+		}
+		ch <- prometheus.MustNewConstMetric(exchangeLimitWaitDesc, prometheus.CounterValue, v.LimitWait.Seconds(), account, k)
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ztrade/ztrade/pkg/process/exchange"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const namespace = "ztrade"
+
+var (
+	registry = prometheus.NewRegistry()
+	mux      = http.NewServeMux()
+
+	busStats      = &busCollector{}
+	exchangeStats = &exchangeCollector{metrics: make(map[string]*exchange.Metrics)}
+
+	eventDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "event_process_seconds",
+		Help:      "processing latency of event by handler",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	}, []string{"event", "processer"})
+
+	handlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handler_errors_total",
+		Help:      "errors returned by event handlers",
+	}, []string{"processer"})
+
+	orderLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "order_roundtrip_seconds",
+		Help:      "latency from order sent to trade received",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"account"})
+
+	fills = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fills_total",
+		Help:      "filled orders",
+	}, []string{"account", "action"})
+
+	orderFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_failed_total",
+		Help:      "orders failed",
+	}, []string{"account"})
+
+	position = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "position",
+		Help:      "position hold",
+	}, []string{"account", "symbol"})
+
+	equity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "equity",
+		Help:      "account balance",
+	}, []string{"account", "currency"})
+
+	scriptDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "script_callback_seconds",
+		Help:      "duration of script callbacks",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	}, []string{"script", "callback"})
+
+	scriptAlloc = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "script_alloc_bytes_total",
+		Help:      "bytes allocated in script callbacks",
+	}, []string{"script"})
+
+	scriptFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "script_failed_total",
+		Help:      "scripts stopped by sandbox",
+	}, []string{"script", "reason"})
+)
+
+func init() {
+	registry.MustRegister(collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		eventDuration,
+		handlerErrors,
+		orderLatency,
+		fills,
+		orderFailed,
+		position,
+		equity,
+		scriptDuration,
+		scriptAlloc,
+		scriptFailed,
+		busStats,
+		exchangeStats)
+}
+
+// Register register extra collector
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+// ObserveScript observe the duration of script callback
+func ObserveScript(script, callback string, start time.Time) {
+	scriptDuration.WithLabelValues(script, callback).Observe(time.Since(start).Seconds())
+}
+
+// ObserveScriptAlloc add the bytes allocated by script callback
+func ObserveScriptAlloc(script string, bytes uint64) {
+	scriptAlloc.WithLabelValues(script).Add(float64(bytes))
+}
+
+// ScriptFailed count the script stopped by sandbox, reason is panic or timeout
+func ScriptFailed(script, reason string) {
+	scriptFailed.WithLabelValues(script, reason).Inc()
+}
+
+// Handler http handler of metrics in prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Handle add handler to the metrics server, such as the live pnl api
+func Handle(pattern string, handler http.Handler) {
+	mux.Handle(pattern, handler)
+}
+
+// Serve serve metrics at addr/metrics in background
+func Serve(addr string) {
+	mux.Handle("/metrics", Handler())
+	go func() {
+		log.Infof("metrics serve at %s/metrics", addr)
+		err := http.ListenAndServe(addr, mux)
+		if err != nil {
+			log.Errorf("metrics serve at %s failed: %s", addr, err.Error())
+		}
+	}()
+}
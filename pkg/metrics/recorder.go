@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+// sentOrder the order waiting for fill
+type sentOrder struct {
+	account string
+	time    time.Time
+}
+
+// Recorder collect the metrics of bus and trading loop
+type Recorder struct {
+	BaseProcesser
+	orders map[string]sentOrder
+	mutex  sync.Mutex
+}
+
+// NewRecorder constructor of Recorder
+func NewRecorder() *Recorder {
+	p := new(Recorder)
+	p.Name = "metrics"
+	p.orders = make(map[string]sentOrder)
+	return p
+}
+
+func (p *Recorder) Init(bus *Bus) (err error) {
+	p.BaseProcesser.Init(bus)
+	bus.SetObserver(busObserver{})
+	busStats.setBus(bus)
+	return errors.Join(
+		SubscribeData(p, EventOrder, p.onEventOrder),
+		SubscribeData(p, EventOrderCanceled, p.onEventOrderCanceled),
+		SubscribeData(p, EventTrade, p.onEventTrade),
+		SubscribeData(p, EventPosition, p.onEventPosition),
+		SubscribeData(p, EventBalance, p.onEventBalance),
+	)
+}
+
+func (p *Recorder) onEventOrder(e *Event, act *TradeAction) (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	switch {
+	case act.Action == CancelAll:
+		for k, v := range p.orders {
+			if v.account == e.GetAccount() {
+				delete(p.orders, k)
+			}
+		}
+	case act.Action == CancelOne:
+		delete(p.orders, act.ID)
+	case act.ID != "":
+		p.orders[act.ID] = sentOrder{account: e.GetAccount(), time: time.Now()}
+	}
+	return
+}
+
+func (p *Recorder) onEventOrderCanceled(e *Event, act *TradeAction) (err error) {
+	p.mutex.Lock()
+	delete(p.orders, act.ID)
+	p.mutex.Unlock()
+	return
+}
+
+func (p *Recorder) onEventTrade(e *Event, tr *Trade) (err error) {
+	p.mutex.Lock()
+	o, ok := p.orders[tr.ID]
+	delete(p.orders, tr.ID)
+	p.mutex.Unlock()
+	if strings.HasPrefix(tr.Remark, "failed") {
+		orderFailed.WithLabelValues(e.GetAccount()).Inc()
+		return
+	}
+	if ok {
+		orderLatency.WithLabelValues(e.GetAccount()).Observe(time.Since(o.time).Seconds())
+	}
+	fills.WithLabelValues(e.GetAccount(), tr.Action.String()).Inc()
+	return
+}
+
+func (p *Recorder) onEventPosition(e *Event, pos *Position) (err error) {
+	position.WithLabelValues(e.GetAccount(), pos.Symbol).Set(pos.Hold)
+	return
+}
+
+func (p *Recorder) onEventBalance(e *Event, balance *Balance) (err error) {
+	equity.WithLabelValues(e.GetAccount(), balance.Currency).Set(balance.Balance)
+	return
+}
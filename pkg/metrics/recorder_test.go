@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"testing"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+func TestRecorderOrders(t *testing.T) {
+	p := NewRecorder()
+	sender := NewBaseProcesser("sender")
+	procs := NewSyncProcessers()
+	procs.Adds(sender, p)
+	err := procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer procs.Stop()
+	for _, v := range []string{"filled", "canceled", "cancel", "all", "other"} {
+		sender.Send(EventOrder, EventOrder, &TradeAction{ID: v, Action: OpenLong, Price: 100, Amount: 1})
+	}
+	sender.SendToAccount("venue", EventOrder, EventOrder, &TradeAction{ID: "venue", Action: OpenLong, Price: 100, Amount: 1})
+	sender.Send("trade", EventTrade, &Trade{ID: "filled", Action: OpenLong, Price: 100, Amount: 1})
+	sender.Send("canceled", EventOrderCanceled, &TradeAction{ID: "canceled"})
+	sender.Send(EventOrder, EventOrder, &TradeAction{ID: "cancel", Action: CancelOne})
+	if len(p.orders) != 3 {
+		t.Fatalf("orders not deleted: %#v", p.orders)
+	}
+	// cancel all only cancel the orders of the account
+	sender.Send(EventOrder, EventOrder, &TradeAction{Action: CancelAll})
+	if _, ok := p.orders["venue"]; !ok || len(p.orders) != 1 {
+		t.Fatalf("cancel all error: %#v", p.orders)
+	}
+}
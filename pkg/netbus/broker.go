@@ -0,0 +1,187 @@
+package netbus
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	opSub   = "sub"
+	opUnsub = "unsub"
+	opPub   = "pub"
+	opMsg   = "msg"
+
+	writeTimeout = time.Second * 5
+	// max size of one frame
+	maxFrame = 16 * 1024 * 1024
+)
+
+// frame one line of json between broker and transport
+type frame struct {
+	Op    string              `json:"op"`
+	Type  string              `json:"type"`
+	Event jsoniter.RawMessage `json:"event,omitempty"`
+}
+
+// Broker embedded pub-sub broker, forward the events published by one client to the others subscribed
+type Broker struct {
+	addr  string
+	ln    net.Listener
+	mutex sync.RWMutex
+	conns map[*brokerConn]bool
+	wg    sync.WaitGroup
+}
+
+// NewBroker create broker listen on addr
+func NewBroker(addr string) *Broker {
+	b := new(Broker)
+	b.addr = addr
+	b.conns = make(map[*brokerConn]bool)
+	return b
+}
+
+// Start listen and serve in background
+func (b *Broker) Start() (err error) {
+	b.ln, err = net.Listen("tcp", b.addr)
+	if err != nil {
+		return
+	}
+	log.Infof("broker listen at %s", b.ln.Addr().String())
+	b.wg.Add(1)
+	go b.serve()
+	return
+}
+
+// Addr return the listen address
+func (b *Broker) Addr() string {
+	if b.ln == nil {
+		return b.addr
+	}
+	return b.ln.Addr().String()
+}
+
+// Close close the listener and all clients
+func (b *Broker) Close() (err error) {
+	if b.ln != nil {
+		err = b.ln.Close()
+	}
+	b.mutex.Lock()
+	for c := range b.conns {
+		c.conn.Close()
+	}
+	b.mutex.Unlock()
+	b.wg.Wait()
+	return
+}
+
+func (b *Broker) serve() {
+	defer b.wg.Done()
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			log.Info("broker stop accept:", err.Error())
+			return
+		}
+		c := &brokerConn{conn: conn, w: bufio.NewWriter(conn), subs: make(map[string]bool)}
+		b.mutex.Lock()
+		b.conns[c] = true
+		b.mutex.Unlock()
+		b.wg.Add(1)
+		go b.serveConn(c)
+	}
+}
+
+func (b *Broker) serveConn(c *brokerConn) {
+	defer b.wg.Done()
+	defer func() {
+		b.mutex.Lock()
+		delete(b.conns, c)
+		b.mutex.Unlock()
+		c.conn.Close()
+	}()
+	log.Infof("broker client %s connected", c.conn.RemoteAddr())
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 4096), maxFrame)
+	for scanner.Scan() {
+		var f frame
+		err := json.Unmarshal(scanner.Bytes(), &f)
+		if err != nil {
+			log.Errorf("broker client %s frame error: %s", c.conn.RemoteAddr(), err.Error())
+			continue
+		}
+		switch f.Op {
+		case opSub:
+			b.mutex.Lock()
+			c.subs[f.Type] = true
+			b.mutex.Unlock()
+		case opUnsub:
+			b.mutex.Lock()
+			delete(c.subs, f.Type)
+			b.mutex.Unlock()
+		case opPub:
+			f.Op = opMsg
+			b.publish(c, &f)
+		default:
+			log.Errorf("broker client %s unknown op: %s", c.conn.RemoteAddr(), f.Op)
+		}
+	}
+	log.Infof("broker client %s disconnected", c.conn.RemoteAddr())
+}
+
+func (b *Broker) publish(from *brokerConn, f *frame) {
+	buf, err := json.Marshal(f)
+	if err != nil {
+		log.Error("broker marshal frame failed:", err.Error())
+		return
+	}
+	var dsts []*brokerConn
+	b.mutex.RLock()
+	for c := range b.conns {
+		if c != from && c.subs[f.Type] {
+			dsts = append(dsts, c)
+		}
+	}
+	b.mutex.RUnlock()
+	for _, c := range dsts {
+		err = c.write(buf)
+		if err != nil {
+			log.Errorf("broker write to %s failed: %s", c.conn.RemoteAddr(), err.Error())
+			c.conn.Close()
+		}
+	}
+}
+
+// brokerConn client connection of broker, subs is protected by the mutex of broker
+type brokerConn struct {
+	conn   net.Conn
+	w      *bufio.Writer
+	wMutex sync.Mutex
+	subs   map[string]bool
+}
+
+func (c *brokerConn) write(buf []byte) (err error) {
+	c.wMutex.Lock()
+	defer c.wMutex.Unlock()
+	return writeFrame(c.conn, c.w, buf)
+}
+
+func writeFrame(conn net.Conn, w *bufio.Writer, buf []byte) (err error) {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = w.Write(buf)
+	if err != nil {
+		return
+	}
+	err = w.WriteByte('\n')
+	if err != nil {
+		return
+	}
+	return w.Flush()
+}
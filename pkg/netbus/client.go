@@ -0,0 +1,207 @@
+package netbus
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/ztrade/ztrade/pkg/event"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotConnected = errors.New("netbus not connected")
+	ErrClosed       = errors.New("netbus closed")
+)
+
+const (
+	dialTimeout  = time.Second * 5
+	reconnectMin = time.Millisecond * 100
+	reconnectMax = time.Second * 10
+)
+
+// Client network transport connected to broker, events are encoded in json
+// it reconnect and subscribe again when the connection broken
+type Client struct {
+	addr string
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	w      *bufio.Writer
+	subs   map[string]func(*Event)
+	closed bool
+
+	closeCh chan bool
+	wg      sync.WaitGroup
+}
+
+var _ Transport = (*Client)(nil)
+
+// Dial connect to broker
+func Dial(addr string) (c *Client, err error) {
+	c = new(Client)
+	c.addr = addr
+	c.subs = make(map[string]func(*Event))
+	c.closeCh = make(chan bool)
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return
+	}
+	c.setConn(conn)
+	c.wg.Add(1)
+	go c.readRoutine(conn)
+	return
+}
+
+func (c *Client) setConn(conn net.Conn) {
+	c.mutex.Lock()
+	c.conn = conn
+	c.w = bufio.NewWriter(conn)
+	c.mutex.Unlock()
+}
+
+// Publish publish event to broker
+func (c *Client) Publish(e *Event) (err error) {
+	buf, err := EncodeEvent(e, FormatJSON)
+	if err != nil {
+		return
+	}
+	return c.write(&frame{Op: opPub, Type: e.GetType(), Event: buf})
+}
+
+// Subscribe receive events of typ from broker
+func (c *Client) Subscribe(typ string, cb func(e *Event)) (err error) {
+	c.mutex.Lock()
+	c.subs[typ] = cb
+	c.mutex.Unlock()
+	err = c.write(&frame{Op: opSub, Type: typ})
+	if errors.Is(err, ErrNotConnected) {
+		// subscribe again when reconnected
+		err = nil
+	}
+	return
+}
+
+// Close close the connection
+func (c *Client) Close() (err error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.closeCh)
+	if c.conn != nil {
+		err = c.conn.Close()
+	}
+	c.mutex.Unlock()
+	c.wg.Wait()
+	return
+}
+
+func (c *Client) write(f *frame) (err error) {
+	buf, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	err = writeFrame(c.conn, c.w, buf)
+	if err != nil {
+		// readRoutine will reconnect
+		c.conn.Close()
+	}
+	return
+}
+
+func (c *Client) readRoutine(conn net.Conn) {
+	defer c.wg.Done()
+	for {
+		c.read(conn)
+		c.mutex.Lock()
+		c.conn = nil
+		c.mutex.Unlock()
+		conn = c.reconnect()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+func (c *Client) read(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxFrame)
+	for scanner.Scan() {
+		var f frame
+		err := json.Unmarshal(scanner.Bytes(), &f)
+		if err != nil || f.Op != opMsg {
+			log.Errorf("netbus invalid frame: %s", scanner.Text())
+			continue
+		}
+		e, err := DecodeEvent(f.Event)
+		if err != nil {
+			log.Errorf("netbus decode %s event failed: %s", f.Type, err.Error())
+			continue
+		}
+		c.mutex.Lock()
+		cb := c.subs[e.GetType()]
+		c.mutex.Unlock()
+		if cb != nil {
+			cb(e)
+		}
+	}
+}
+
+// reconnect connect to broker again and resubscribe, return nil if closed
+func (c *Client) reconnect() net.Conn {
+	delay := reconnectMin
+	for {
+		select {
+		case <-c.closeCh:
+			return nil
+		case <-time.After(delay):
+		}
+		conn, err := net.DialTimeout("tcp", c.addr, dialTimeout)
+		if err != nil {
+			log.Warnf("netbus reconnect %s failed: %s", c.addr, err.Error())
+			delay *= 2
+			if delay > reconnectMax {
+				delay = reconnectMax
+			}
+			continue
+		}
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			conn.Close()
+			return nil
+		}
+		c.conn = conn
+		c.w = bufio.NewWriter(conn)
+		var subs []string
+		for k := range c.subs {
+			subs = append(subs, k)
+		}
+		c.mutex.Unlock()
+		for _, v := range subs {
+			err = c.write(&frame{Op: opSub, Type: v})
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
+		log.Infof("netbus reconnected to %s", c.addr)
+		return conn
+	}
+}
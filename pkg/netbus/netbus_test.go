@@ -0,0 +1,119 @@
+package netbus
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker("127.0.0.1:0")
+	err := broker.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer broker.Close()
+
+	// market data process
+	market := NewBus(16)
+	client, err := Dial(broker.Addr())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	market.SetTransport(client)
+	market.Start()
+
+	// strategy process
+	strategy := NewBus(16)
+	client2, err := Dial(broker.Addr())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	strategy.SetTransport(client2)
+	candles := make(chan *Candle, 1)
+	p := NewBaseProcesser("strategy")
+	p.Init(strategy)
+	SubscribeData(p, EventCandle, func(e *Event, candle *Candle) error {
+		if binSize, _ := ExtraAs[string](e); binSize != "1m" {
+			t.Errorf("extra error: %v", e.GetExtra())
+		}
+		candles <- candle
+		return nil
+	})
+	strategy.Start()
+	// wait for the subscription arrived at broker
+	time.Sleep(time.Millisecond * 100)
+
+	market.Send(NewEvent("candle", EventCandle, "market", &Candle{Start: 1, Close: 100}, "1m"))
+	select {
+	case candle := <-candles:
+		if candle.Close != 100 {
+			t.Fatalf("candle error: %#v", candle)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("candle not received")
+	}
+	market.Close()
+	strategy.Close()
+}
+
+func TestBrokerWatch(t *testing.T) {
+	broker := NewBroker("127.0.0.1:0")
+	err := broker.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer broker.Close()
+
+	// strategy process send the watches
+	strategy := NewBus(16)
+	client, err := Dial(broker.Addr())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	strategy.SetTransport(client)
+	strategy.Start()
+
+	// market process serve the watches
+	market := NewBus(16)
+	client2, err := Dial(broker.Addr())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	market.SetTransport(client2)
+	watches := make(chan *WatchParam, 2)
+	p := NewBaseProcesser("market")
+	p.Init(market)
+	SubscribeData(p, EventWatch, func(e *Event, param *WatchParam) error {
+		watches <- param
+		return nil
+	})
+	market.Start()
+	time.Sleep(time.Millisecond * 100)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	strategy.Send(NewEvent("candle", EventWatch, "strategy", NewWatchCandle(&CandleParam{Start: start, Symbol: "BTCUSDT", BinSize: "1m"}), nil))
+	strategy.Send(NewEvent("trade", EventWatch, "strategy", &WatchParam{Type: EventTradeMarket, Extra: "BTCUSDT", Data: map[string]interface{}{"name": "market"}}, nil))
+	var params []*WatchParam
+	for len(params) < 2 {
+		select {
+		case param := <-watches:
+			params = append(params, param)
+		case <-time.After(time.Second * 5):
+			t.Fatal("watch not received")
+		}
+	}
+	cParam, ok := params[0].Data.(*CandleParam)
+	if !ok || params[0].Type != EventWatchCandle || !cParam.Start.Equal(start) || cParam.Symbol != "BTCUSDT" || params[0].Extra != "BTCUSDT" {
+		t.Fatalf("candle watch error: %#v %#v", params[0], params[0].Data)
+	}
+	data, ok := params[1].Data.(map[string]interface{})
+	if !ok || params[1].Type != EventTradeMarket || data["name"] != "market" || params[1].Extra != "BTCUSDT" {
+		t.Fatalf("trade watch error: %#v %#v", params[1], params[1].Data)
+	}
+	market.Close()
+	strategy.Close()
+}
@@ -0,0 +1,466 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/ztrade/trademodel"
+)
+
+const amountEpsilon = 1e-9
+
+type parentOrder struct {
+	AlgoOrder
+	start     time.Time
+	end       time.Time
+	interval  time.Duration
+	nextSlice time.Time
+	slice     int
+
+	// twap/vwap: amount expected to be filled by the current slice
+	target   float64
+	filled   float64
+	cost     float64
+	children int
+	// working child order
+	child      string
+	childPrice float64
+	// children canceled but not confirmed, they may still be filled
+	canceling int
+
+	status string
+	msg    string
+}
+
+func (p *parentOrder) remain() float64 {
+	return p.Amount - p.filled
+}
+
+// busy check if a child order may still be filled
+func (p *parentOrder) busy() bool {
+	return p.child != "" || p.canceling > 0
+}
+
+func (p *parentOrder) progress(t time.Time) *AlgoProgress {
+	prog := &AlgoProgress{
+		ID:       p.ID,
+		Algo:     p.Algo,
+		Action:   p.Action,
+		Amount:   p.Amount,
+		Filled:   p.filled,
+		Children: p.children,
+		Status:   p.status,
+		Msg:      p.msg,
+		Time:     t,
+	}
+	if p.filled > 0 {
+		prog.AvgPrice = p.cost / p.filled
+	}
+	return prog
+}
+
+// childOrder child order sent to the exchange
+type childOrder struct {
+	parent    *parentOrder
+	amount    float64
+	filled    float64
+	canceling bool
+}
+
+// output events created while holding the lock, sent after unlock
+type output struct {
+	acts  []*TradeAction
+	progs []*AlgoProgress
+}
+
+// Executor execution algo layer between scripts and exchange
+// it splits AlgoOrder into child orders and reports AlgoProgress
+type Executor struct {
+	BaseProcesser
+	symbol  string
+	account string
+	profile *VolumeProfile
+
+	orders   map[string]*parentOrder
+	children map[string]*childOrder
+
+	now   time.Time
+	price float64
+	bid   float64
+	ask   float64
+	mutex sync.Mutex
+}
+
+// NewExecutor constructor of Executor
+func NewExecutor(symbol string) *Executor {
+	ex := new(Executor)
+	ex.Name = "algo"
+	ex.symbol = symbol
+	ex.profile = NewVolumeProfile()
+	ex.orders = make(map[string]*parentOrder)
+	ex.children = make(map[string]*childOrder)
+	return ex
+}
+
+// SetAccount set the exchange account(config name) of the executor
+// only the events of the account or not tagged are processed, child orders are sent to it
+func (ex *Executor) SetAccount(account string) {
+	ex.account = account
+}
+
+// accept check if the event belongs to the account of executor
+func (ex *Executor) accept(e *Event) bool {
+	return e.GetAccount() == "" || e.GetAccount() == ex.account
+}
+
+// SetVolumeProfile set the volume profile used by vwap
+func (ex *Executor) SetVolumeProfile(profile *VolumeProfile) {
+	ex.profile = profile
+}
+
+func (ex *Executor) Init(bus *Bus) (err error) {
+	ex.BaseProcesser.Init(bus)
+	return errors.Join(
+		SubscribeData(ex, EventAlgoOrder, ex.onEventAlgoOrder),
+		SubscribeData(ex, EventCandle, ex.onEventCandle),
+		SubscribeData(ex, EventDepth, ex.onEventDepth),
+		SubscribeData(ex, EventTrade, ex.onEventTrade),
+		SubscribeData(ex, EventOrder, ex.onEventOrder),
+		SubscribeData(ex, EventOrderCanceled, ex.onEventOrderCanceled),
+	)
+}
+
+func (ex *Executor) send(out *output) {
+	for _, v := range out.acts {
+		ex.SendToAccount(ex.account, EventOrder, EventOrder, v)
+	}
+	for _, v := range out.progs {
+		log.Debugf("algo order %s %s: %f/%f", v.ID, v.Status, v.Filled, v.Amount)
+		ex.Send(v.ID, EventAlgoProgress, v)
+	}
+}
+
+func (ex *Executor) updateTime(t time.Time) {
+	if t.After(ex.now) {
+		ex.now = t
+	}
+}
+
+func (ex *Executor) onEventAlgoOrder(e *Event, o *AlgoOrder) (err error) {
+	if !ex.accept(e) {
+		return
+	}
+	var out output
+	ex.mutex.Lock()
+	err = ex.addOrder(*o, &out)
+	if err == nil {
+		ex.work(&out)
+	}
+	ex.mutex.Unlock()
+	ex.send(&out)
+	return
+}
+
+func (ex *Executor) addOrder(o AlgoOrder, out *output) (err error) {
+	if _, ok := ex.orders[o.ID]; ok {
+		err = fmt.Errorf("algo order %s already exist", o.ID)
+		return
+	}
+	p := &parentOrder{AlgoOrder: o, status: AlgoStatusRunning}
+	if p.Symbol == "" {
+		p.Symbol = ex.symbol
+	}
+	p.start = ex.now
+	if p.start.IsZero() {
+		p.start = o.Time
+	}
+	switch o.Algo {
+	case AlgoTWAP, AlgoVWAP:
+		if p.Slices <= 0 {
+			p.Slices = 1
+		}
+		p.end = p.start.Add(p.Duration)
+		p.interval = p.Duration / time.Duration(p.Slices)
+		p.nextSlice = p.start
+	case AlgoIceberg:
+		if p.Visible <= 0 {
+			p.Visible = p.Amount
+		}
+	case AlgoPeg:
+	default:
+		err = fmt.Errorf("unsupport algo: %s", o.Algo)
+	}
+	if err == nil && p.Amount <= 0 {
+		err = fmt.Errorf("algo order %s amount invalid: %f", o.ID, o.Amount)
+	}
+	if err != nil {
+		p.status = AlgoStatusFailed
+		p.msg = err.Error()
+		out.progs = append(out.progs, p.progress(ex.now))
+		return
+	}
+	ex.orders[p.ID] = p
+	out.progs = append(out.progs, p.progress(ex.now))
+	return
+}
+
+func (ex *Executor) onEventCandle(e *Event, candle *Candle) (err error) {
+	// recent candles are history, never trade on them
+	if !ex.accept(e) || e.GetName() == "recent" || candle.ID == -1 {
+		return
+	}
+	var out output
+	ex.mutex.Lock()
+	ex.updateTime(candle.Time())
+	ex.price = candle.Close
+	ex.work(&out)
+	ex.mutex.Unlock()
+	ex.send(&out)
+	return
+}
+
+func (ex *Executor) onEventDepth(e *Event, depth *Depth) (err error) {
+	if !ex.accept(e) {
+		return
+	}
+	var out output
+	ex.mutex.Lock()
+	if len(depth.Buys) > 0 {
+		ex.bid = depth.Buys[0].Price
+	}
+	if len(depth.Sells) > 0 {
+		ex.ask = depth.Sells[0].Price
+	}
+	ex.updateTime(depth.UpdateTime)
+	ex.work(&out)
+	ex.mutex.Unlock()
+	ex.send(&out)
+	return
+}
+
+func (ex *Executor) onEventTrade(e *Event, tr *Trade) (err error) {
+	if !ex.accept(e) {
+		return
+	}
+	var out output
+	ex.mutex.Lock()
+	ex.onTrade(tr, &out)
+	ex.mutex.Unlock()
+	ex.send(&out)
+	return
+}
+
+func (ex *Executor) onTrade(tr *Trade, out *output) {
+	c, ok := ex.children[tr.ID]
+	if !ok {
+		return
+	}
+	p := c.parent
+	if strings.HasPrefix(tr.Remark, "failed") {
+		ex.finishChild(tr.ID, c)
+		if p.status == AlgoStatusRunning {
+			p.status = AlgoStatusFailed
+			p.msg = tr.Remark
+			out.progs = append(out.progs, p.progress(ex.now))
+		}
+		return
+	}
+	c.filled += tr.Amount
+	if c.amount-c.filled <= amountEpsilon {
+		ex.finishChild(tr.ID, c)
+	}
+	p.filled += tr.Amount
+	p.cost += tr.Price * tr.Amount
+	if p.status == AlgoStatusRunning && p.remain() <= amountEpsilon {
+		p.status = AlgoStatusFinished
+	}
+	out.progs = append(out.progs, p.progress(ex.now))
+}
+
+// finishChild the child is fully filled, failed or canceled, it will not be filled any more
+func (ex *Executor) finishChild(id string, c *childOrder) {
+	delete(ex.children, id)
+	p := c.parent
+	if p.child == id {
+		p.child = ""
+	}
+	if c.canceling {
+		p.canceling--
+	}
+}
+
+func (ex *Executor) onEventOrder(e *Event, act *TradeAction) (err error) {
+	if !ex.accept(e) || (act.Action != CancelAll && act.Action != CancelOne) {
+		return
+	}
+	var out output
+	ex.mutex.Lock()
+	for _, p := range ex.running() {
+		if act.Action == CancelOne && act.ID != p.ID {
+			continue
+		}
+		// CancelAll will cancel the child orders in exchange too
+		if act.Action == CancelOne {
+			ex.cancelChild(p, &out)
+		}
+		p.status = AlgoStatusCanceled
+		out.progs = append(out.progs, p.progress(ex.now))
+	}
+	ex.mutex.Unlock()
+	ex.send(&out)
+	return
+}
+
+// onEventOrderCanceled the child is canceled by the executor or outside, the unfilled amount will be placed again on next tick
+func (ex *Executor) onEventOrderCanceled(e *Event, act *TradeAction) (err error) {
+	if !ex.accept(e) {
+		return
+	}
+	ex.mutex.Lock()
+	c, ok := ex.children[act.ID]
+	if ok {
+		if !c.canceling {
+			log.Infof("algo order %s child %s canceled", c.parent.ID, act.ID)
+		}
+		ex.finishChild(act.ID, c)
+	}
+	ex.mutex.Unlock()
+	return
+}
+
+// running return running orders sorted by id
+func (ex *Executor) running() (orders []*parentOrder) {
+	for _, v := range ex.orders {
+		if v.status == AlgoStatusRunning {
+			orders = append(orders, v)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+	return
+}
+
+func (ex *Executor) work(out *output) {
+	for _, p := range ex.running() {
+		switch p.Algo {
+		case AlgoTWAP, AlgoVWAP:
+			ex.workSlice(p, out)
+		case AlgoIceberg:
+			if !p.busy() {
+				ex.placeChild(p, p.Price, math.Min(p.Visible, p.remain()), out)
+			}
+		case AlgoPeg:
+			// the replaced child may be filled before it is canceled, wait for it
+			if p.canceling > 0 {
+				continue
+			}
+			price := ex.pegPrice(p)
+			if price <= 0 || (p.child != "" && price == p.childPrice) {
+				continue
+			}
+			if p.child != "" {
+				ex.cancelChild(p, out)
+				continue
+			}
+			ex.placeChild(p, price, p.remain(), out)
+		}
+	}
+	for k, v := range ex.children {
+		if v.parent.status != AlgoStatusRunning {
+			delete(ex.children, k)
+		}
+	}
+	for k, v := range ex.orders {
+		if v.status != AlgoStatusRunning {
+			delete(ex.orders, k)
+		}
+	}
+}
+
+// workSlice cancel the working child at every slice, then place a new child with the unfilled amount
+// when the canceled child is confirmed
+func (ex *Executor) workSlice(p *parentOrder, out *output) {
+	if p.slice < p.Slices && !ex.now.Before(p.nextSlice) {
+		ex.cancelChild(p, out)
+		p.slice++
+		p.nextSlice = p.nextSlice.Add(p.interval)
+		var ratio float64
+		if p.slice >= p.Slices {
+			ratio = 1
+		} else if p.Algo == AlgoVWAP {
+			ratio = ex.profile.Fraction(p.start, p.end, p.nextSlice)
+		} else {
+			ratio = float64(p.slice) / float64(p.Slices)
+		}
+		p.target = p.Amount * ratio
+	}
+	if p.busy() {
+		return
+	}
+	amount := p.target - p.filled
+	if amount <= amountEpsilon {
+		return
+	}
+	price := p.Price
+	if price <= 0 {
+		price = ex.price
+	}
+	ex.placeChild(p, price, amount, out)
+}
+
+func (ex *Executor) pegPrice(p *parentOrder) (price float64) {
+	if p.Action.IsLong() {
+		price = ex.bid
+		if price <= 0 {
+			price = ex.price
+		}
+		if price > 0 {
+			price -= p.Offset
+		}
+	} else {
+		price = ex.ask
+		if price <= 0 {
+			price = ex.price
+		}
+		if price > 0 {
+			price += p.Offset
+		}
+	}
+	return
+}
+
+func (ex *Executor) placeChild(p *parentOrder, price, amount float64, out *output) {
+	if amount <= amountEpsilon || price <= 0 {
+		return
+	}
+	p.children++
+	id := fmt.Sprintf("%s-%d", p.ID, p.children)
+	act := &TradeAction{ID: id, Action: p.Action, Symbol: p.Symbol, Amount: amount, Price: price, Time: ex.now}
+	p.child = id
+	p.childPrice = price
+	ex.children[id] = &childOrder{parent: p, amount: amount}
+	out.acts = append(out.acts, act)
+}
+
+// cancelChild cancel the working child, it is kept until the cancel is confirmed or it is filled
+func (ex *Executor) cancelChild(p *parentOrder, out *output) {
+	if p.child == "" {
+		return
+	}
+	if c, ok := ex.children[p.child]; ok && !c.canceling {
+		c.canceling = true
+		p.canceling++
+	}
+	out.acts = append(out.acts, &TradeAction{ID: p.child, Action: CancelOne, Symbol: p.Symbol, Time: ex.now})
+	p.child = ""
+}
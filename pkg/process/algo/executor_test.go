@@ -0,0 +1,196 @@
+package algo
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeExchange record the child orders, cancels are confirmed when confirm is true
+type fakeExchange struct {
+	BaseProcesser
+	confirm bool
+	orders  []TradeAction
+	cancels []string
+	progs   []AlgoProgress
+}
+
+func (f *fakeExchange) Init(bus *Bus) (err error) {
+	f.BaseProcesser.Init(bus)
+	SubscribeData(f, EventOrder, f.onEventOrder)
+	SubscribeData(f, EventAlgoProgress, func(e *Event, prog *AlgoProgress) error {
+		f.progs = append(f.progs, *prog)
+		return nil
+	})
+	return
+}
+
+func (f *fakeExchange) onEventOrder(e *Event, act *TradeAction) (err error) {
+	if act.Action != CancelOne {
+		f.orders = append(f.orders, *act)
+		return
+	}
+	f.cancels = append(f.cancels, act.ID)
+	if f.confirm {
+		f.Send(act.ID, EventOrderCanceled, act)
+	}
+	return
+}
+
+func (f *fakeExchange) last() TradeAction {
+	return f.orders[len(f.orders)-1]
+}
+
+func (f *fakeExchange) fill(id string, price, amount float64) {
+	f.Send("trade", EventTrade, &Trade{ID: id, Action: OpenLong, Price: price, Amount: amount})
+}
+
+func (f *fakeExchange) candle(n int, price float64) {
+	f.Send("candle", EventCandle, &Candle{Start: testStart.Add(time.Minute * time.Duration(n)).Unix(), Close: price})
+}
+
+func (f *fakeExchange) depth(bid, ask float64) {
+	f.Send("depth", EventDepth, &Depth{Buys: []DepthInfo{{Price: bid}}, Sells: []DepthInfo{{Price: ask}}})
+}
+
+func newTestExecutor(t *testing.T) (ex *Executor, f *fakeExchange) {
+	ex = NewExecutor("BTCUSDT")
+	f = &fakeExchange{BaseProcesser: BaseProcesser{Name: "exchange"}, confirm: true}
+	procs := NewSyncProcessers()
+	procs.Adds(f, ex)
+	err := procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() { procs.Stop() })
+	return
+}
+
+func checkFilled(t *testing.T, f *fakeExchange, filled float64, status string) {
+	prog := f.progs[len(f.progs)-1]
+	if prog.Filled != filled || prog.Status != status {
+		t.Fatalf("progress error, filled: %f, status: %s", prog.Filled, prog.Status)
+	}
+}
+
+func TestIcebergPartialFill(t *testing.T) {
+	_, f := newTestExecutor(t)
+	f.Send(EventAlgoOrder, EventAlgoOrder, &AlgoOrder{TradeAction: TradeAction{ID: "a", Action: OpenLong, Price: 100, Amount: 10}, Algo: AlgoIceberg, Visible: 4})
+	if len(f.orders) != 1 || f.last().Amount != 4 {
+		t.Fatalf("first child error: %#v", f.orders)
+	}
+	child := f.last().ID
+	f.fill(child, 100, 1)
+	f.candle(1, 100)
+	if len(f.orders) != 1 {
+		t.Fatalf("new child placed after partial fill: %#v", f.orders)
+	}
+	f.fill(child, 100, 3)
+	f.candle(2, 100)
+	if len(f.orders) != 2 || f.last().Amount != 4 {
+		t.Fatalf("second child error: %#v", f.orders)
+	}
+	f.fill(f.last().ID, 100, 4)
+	f.candle(3, 100)
+	if len(f.orders) != 3 || f.last().Amount != 2 {
+		t.Fatalf("last child error: %#v", f.orders)
+	}
+	f.fill(f.last().ID, 100, 2)
+	checkFilled(t, f, 10, AlgoStatusFinished)
+}
+
+func TestPegReplace(t *testing.T) {
+	_, f := newTestExecutor(t)
+	f.confirm = false
+	f.depth(99, 101)
+	f.Send(EventAlgoOrder, EventAlgoOrder, &AlgoOrder{TradeAction: TradeAction{ID: "p", Action: OpenLong, Amount: 5}, Algo: AlgoPeg})
+	if len(f.orders) != 1 || f.last().Price != 99 || f.last().Amount != 5 {
+		t.Fatalf("first child error: %#v", f.orders)
+	}
+	first := f.last().ID
+	f.depth(100, 101)
+	if len(f.cancels) != 1 || f.cancels[0] != first {
+		t.Fatalf("child not canceled: %#v", f.cancels)
+	}
+	// the canceled child may still be filled, no new child before it's done
+	f.depth(100, 101)
+	if len(f.orders) != 1 {
+		t.Fatalf("new child placed before cancel confirmed: %#v", f.orders)
+	}
+	f.fill(first, 99, 2)
+	f.Send(first, EventOrderCanceled, &TradeAction{ID: first, Action: OpenLong})
+	f.depth(100, 101)
+	if len(f.orders) != 2 || f.last().Price != 100 || f.last().Amount != 3 {
+		t.Fatalf("replaced child error: %#v", f.orders)
+	}
+	f.fill(f.last().ID, 100, 3)
+	checkFilled(t, f, 5, AlgoStatusFinished)
+}
+
+func TestTWAPSlices(t *testing.T) {
+	_, f := newTestExecutor(t)
+	f.candle(0, 100)
+	f.Send(EventAlgoOrder, EventAlgoOrder, &AlgoOrder{TradeAction: TradeAction{ID: "t", Action: OpenLong, Amount: 9}, Algo: AlgoTWAP, Duration: 3 * time.Minute, Slices: 3})
+	if len(f.orders) != 1 || f.last().Amount != 3 {
+		t.Fatalf("first slice error: %#v", f.orders)
+	}
+	f.fill(f.last().ID, 100, 1)
+	// the unfilled amount is rolled into the next slice after the cancel is confirmed
+	f.candle(1, 100)
+	if len(f.cancels) != 1 || len(f.orders) != 1 {
+		t.Fatalf("slice not canceled: %#v %#v", f.cancels, f.orders)
+	}
+	f.candle(1, 100)
+	if len(f.orders) != 2 || f.last().Amount != 5 {
+		t.Fatalf("second slice error: %#v", f.orders)
+	}
+	f.fill(f.last().ID, 100, 5)
+	f.candle(2, 100)
+	if len(f.orders) != 3 || f.last().Amount != 3 {
+		t.Fatalf("last slice error: %#v", f.orders)
+	}
+	f.fill(f.last().ID, 100, 3)
+	checkFilled(t, f, 9, AlgoStatusFinished)
+}
+
+func TestCancelAlgoOrder(t *testing.T) {
+	_, f := newTestExecutor(t)
+	f.Send(EventAlgoOrder, EventAlgoOrder, &AlgoOrder{TradeAction: TradeAction{ID: "c", Action: OpenLong, Price: 100, Amount: 10}, Algo: AlgoIceberg, Visible: 2})
+	f.Send(EventOrder, EventOrder, &TradeAction{ID: "c", Action: CancelOne})
+	// the exchange get the cancel of parent and child
+	if len(f.cancels) != 2 || (f.cancels[0] != f.last().ID && f.cancels[1] != f.last().ID) {
+		t.Fatalf("child not canceled: %#v", f.cancels)
+	}
+	checkFilled(t, f, 0, AlgoStatusCanceled)
+	f.candle(1, 100)
+	if len(f.orders) != 1 {
+		t.Fatalf("child placed after cancel: %#v", f.orders)
+	}
+}
+
+func TestExecutorAccount(t *testing.T) {
+	ex, f := newTestExecutor(t)
+	ex.SetAccount("main")
+	var accounts []string
+	SubscribeData(f, EventOrder, func(e *Event, act *TradeAction) error {
+		accounts = append(accounts, e.GetAccount())
+		return nil
+	})
+	f.candle(0, 100)
+	// market data of other venues are ignored
+	e := f.CreateEvent("candle", EventCandle, &Candle{Start: testStart.Unix(), Close: 200})
+	e.Account = "other"
+	f.Bus.Send(e)
+	f.Send(EventAlgoOrder, EventAlgoOrder, &AlgoOrder{TradeAction: TradeAction{ID: "v", Action: OpenLong, Amount: 1}, Algo: AlgoTWAP, Duration: time.Minute, Slices: 1})
+	if len(f.orders) != 1 || f.last().Price != 100 {
+		t.Fatalf("child error: %#v", f.orders)
+	}
+	if len(accounts) != 1 || accounts[0] != "main" {
+		t.Fatalf("child not sent to the account: %#v", accounts)
+	}
+}
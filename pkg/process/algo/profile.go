@@ -0,0 +1,86 @@
+package algo
+
+import (
+	"time"
+
+	"github.com/ztrade/ztrade/pkg/process/dbstore"
+
+	. "github.com/ztrade/trademodel"
+)
+
+const minutesOfDay = 24 * 60
+
+// VolumeProfile average volume of every minute in a day, used by vwap
+type VolumeProfile struct {
+	weights [minutesOfDay]float64
+	counts  [minutesOfDay]int
+}
+
+// NewVolumeProfile create a flat profile, vwap works like twap until it is updated
+func NewVolumeProfile() *VolumeProfile {
+	return new(VolumeProfile)
+}
+
+// LoadVolumeProfile build profile from the 1m candles of recent days before end
+func LoadVolumeProfile(db *dbstore.DBStore, exchange, symbol string, end time.Time, days int) (p *VolumeProfile, err error) {
+	p = NewVolumeProfile()
+	tbl := db.GetKlineTbl(exchange, symbol, "1m")
+	start := end.Add(-time.Duration(days) * time.Hour * 24)
+	datas, err := tbl.DataChan(start, end, "1m")
+	if err != nil {
+		return
+	}
+	for v := range datas {
+		for _, d := range v {
+			candle, ok := d.(*Candle)
+			if !ok {
+				continue
+			}
+			p.Update(candle)
+		}
+	}
+	return
+}
+
+func minuteOfDay(t time.Time) int {
+	t = t.UTC()
+	return t.Hour()*60 + t.Minute()
+}
+
+// Update add a 1m candle to the profile
+func (p *VolumeProfile) Update(candle *Candle) {
+	n := minuteOfDay(candle.Time())
+	p.weights[n] = (p.weights[n]*float64(p.counts[n]) + candle.Volume) / float64(p.counts[n]+1)
+	p.counts[n]++
+}
+
+func (p *VolumeProfile) weight(t time.Time) float64 {
+	n := minuteOfDay(t)
+	if p.counts[n] == 0 {
+		return 1
+	}
+	// keep a tiny weight so that empty minutes still advance the schedule
+	return p.weights[n] + 1e-9
+}
+
+// Fraction return expected volume ratio traded in [start, t) of the whole range [start, end)
+func (p *VolumeProfile) Fraction(start, end, t time.Time) float64 {
+	if !t.After(start) {
+		return 0
+	}
+	if !t.Before(end) {
+		return 1
+	}
+	var total, done float64
+	for tm := start; tm.Before(end); tm = tm.Add(time.Minute) {
+		w := p.weight(tm)
+		total += w
+		if tm.Before(t) {
+			done += w
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return done / total
+}
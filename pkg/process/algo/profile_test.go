@@ -0,0 +1,41 @@
+package algo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+)
+
+func TestVolumeProfileFlat(t *testing.T) {
+	p := NewVolumeProfile()
+	end := testStart.Add(4 * time.Minute)
+	for i, v := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		f := p.Fraction(testStart, end, testStart.Add(time.Minute*time.Duration(i)))
+		if math.Abs(f-v) > 1e-9 {
+			t.Fatalf("fraction of minute %d error: %f", i, f)
+		}
+	}
+	if f := p.Fraction(testStart, end, testStart.Add(-time.Minute)); f != 0 {
+		t.Fatalf("fraction before start error: %f", f)
+	}
+	if f := p.Fraction(testStart, end, end.Add(time.Minute)); f != 1 {
+		t.Fatalf("fraction after end error: %f", f)
+	}
+}
+
+func TestVolumeProfileUpdate(t *testing.T) {
+	p := NewVolumeProfile()
+	// two days of candles, volume of the first minute is 3 times of the second
+	for day := 0; day < 2; day++ {
+		dayStart := testStart.Add(-24 * time.Hour * time.Duration(day+1))
+		p.Update(&Candle{Start: dayStart.Unix(), Volume: float64(2 + day*2)})
+		p.Update(&Candle{Start: dayStart.Add(time.Minute).Unix(), Volume: 1})
+	}
+	end := testStart.Add(2 * time.Minute)
+	f := p.Fraction(testStart, end, testStart.Add(time.Minute))
+	if math.Abs(f-0.75) > 1e-6 {
+		t.Fatalf("fraction error: %f", f)
+	}
+}
@@ -68,7 +68,7 @@ func (dr *DBStore) initDB() (err error) {
 		err = fmt.Errorf("init db failed:%s", err.Error())
 		return
 	}
-	err = dr.engine.Sync2(&SymbolInfo{})
+	err = dr.engine.Sync2(&SymbolInfo{}, &ScriptState{}, &BacktestRun{})
 	return
 }
 
@@ -79,7 +79,7 @@ func (dr *DBStore) GetTableSession(tbl string, data TimeData) (sess *xorm.Sessio
 		log.Error("dbstore get table failed:", err.Error())
 	}
 	if !bExit {
-		log.Debugf("create table %s ", dr.table, reflect.TypeOf(data))
+		log.Debugf("create table %s %s", dr.table, reflect.TypeOf(data))
 		data.SetTable(tbl)
 		fmt.Println(tbl, reflect.TypeOf(data))
 		dr.engine.Sync2(data)
@@ -2,7 +2,9 @@ package dbstore
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/ztrade/base/common"
 	. "github.com/ztrade/ztrade/pkg/core"
 	. "github.com/ztrade/ztrade/pkg/event"
 
@@ -39,10 +41,12 @@ func (tbl *KlineTbl) SetLoadDataMode(bLoad bool) {
 func (tbl *KlineTbl) Init(bus *Bus) (err error) {
 	tbl.BaseProcesser.Init(bus)
 	if !tbl.loadData {
-		tbl.Subscribe(EventCandle, tbl.onEventCandle)
+		err = SubscribeData(tbl, EventCandle, tbl.onEventCandle)
+		if err != nil {
+			return
+		}
 	}
-	tbl.Subscribe(EventWatch, tbl.onEventCandleParam)
-	return
+	return SubscribeData(tbl, EventWatch, tbl.onEventCandleParam)
 }
 
 func (tbl *KlineTbl) GetSlice(data interface{}) (rets []interface{}) {
@@ -78,8 +82,7 @@ func (tbl *KlineTbl) emitCandles(param CandleParam) {
 	}
 }
 
-func (tbl *KlineTbl) onEventCandle(e *Event) (err error) {
-	candle := e.GetData().(*Candle)
+func (tbl *KlineTbl) onEventCandle(e *Event, candle *Candle) (err error) {
 	err = tbl.WriteData(candle)
 	if err != nil {
 		return
@@ -87,12 +90,7 @@ func (tbl *KlineTbl) onEventCandle(e *Event) (err error) {
 	return
 }
 
-func (tbl *KlineTbl) onEventCandleParam(e *Event) (err error) {
-	wParam, ok := e.GetData().(*WatchParam)
-	if !ok {
-		err = fmt.Errorf("event not watch %s %#v", e.Name, e.Data)
-		return
-	}
+func (tbl *KlineTbl) onEventCandleParam(e *Event, wParam *WatchParam) (err error) {
 	candleParam, _ := wParam.Data.(*CandleParam)
 	if candleParam == nil {
 		err = fmt.Errorf("event not CandleParam %s %#v", e.Name, e.Data)
@@ -101,3 +99,23 @@ func (tbl *KlineTbl) onEventCandleParam(e *Event) (err error) {
 	go tbl.emitCandles(*candleParam)
 	return
 }
+
+// CandleHistory return the loader of candles in [start, end)
+func (dr *DBStore) CandleHistory(exchange, symbol, binSize string) func(start, end time.Time) ([]*Candle, error) {
+	tbl := dr.GetKlineTbl(exchange, symbol, binSize)
+	return func(start, end time.Time) (candles []*Candle, err error) {
+		dur, err := common.GetBinSizeDuration(binSize)
+		if err != nil {
+			return
+		}
+		datas, err := tbl.GetDatas(start, end, int(end.Sub(start)/dur)+1)
+		if err != nil {
+			return
+		}
+		candles = make([]*Candle, 0, len(datas))
+		for _, v := range datas {
+			candles = append(candles, v.(*Candle))
+		}
+		return
+	}
+}
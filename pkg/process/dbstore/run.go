@@ -0,0 +1,34 @@
+package dbstore
+
+import (
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// SaveBacktestRun add the run to registry, the ID is set after saved
+func (dr *DBStore) SaveBacktestRun(run *BacktestRun) (err error) {
+	run.ID = 0
+	_, err = dr.engine.Insert(run)
+	return
+}
+
+// GetBacktestRuns get the runs by ids, in the order of ids
+func (dr *DBStore) GetBacktestRuns(ids ...int64) (runs []*BacktestRun, err error) {
+	for _, id := range ids {
+		var run BacktestRun
+		has, err := dr.engine.ID(id).Get(&run)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			continue
+		}
+		runs = append(runs, &run)
+	}
+	return
+}
+
+// ListBacktestRuns list the latest runs without result and equity
+func (dr *DBStore) ListBacktestRuns(limit int) (runs []*BacktestRun, err error) {
+	err = dr.engine.Omit("result", "equity").Desc("id").Limit(limit).Find(&runs)
+	return
+}
@@ -0,0 +1,40 @@
+package dbstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+func TestBacktestRuns(t *testing.T) {
+	db, err := NewDBStore("sqlite", filepath.Join(t.TempDir(), "run.db"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer db.Close()
+	for _, v := range []string{"a", "b", "c"} {
+		run := &BacktestRun{ID: 100, Name: v, Result: []byte(`{"TotalProfit":1}`)}
+		err = db.SaveBacktestRun(run)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if run.ID == 100 || run.ID == 0 {
+			t.Fatalf("id of run %s not set: %d", v, run.ID)
+		}
+	}
+	runs, err := db.GetBacktestRuns(3, 99, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(runs) != 2 || runs[0].Name != "c" || runs[1].Name != "a" || string(runs[0].Result) != `{"TotalProfit":1}` {
+		t.Fatalf("get runs error: %#v", runs)
+	}
+	runs, err = db.ListBacktestRuns(2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(runs) != 2 || runs[0].Name != "c" || runs[1].Name != "b" || runs[0].Result != nil {
+		t.Fatalf("list runs error: %#v", runs)
+	}
+}
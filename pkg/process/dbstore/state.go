@@ -0,0 +1,62 @@
+package dbstore
+
+import (
+	"time"
+
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// GetScriptState get the state of script, return nil if not exist
+func (dr *DBStore) GetScriptState(name, exchange, symbol string) (state *ScriptState, err error) {
+	var st ScriptState
+	has, err := dr.engine.Where("name = ? and exchange = ? and symbol = ?", name, exchange, symbol).Get(&st)
+	if err != nil || !has {
+		return
+	}
+	state = &st
+	return
+}
+
+// SaveScriptState insert or update the state by name, exchange and symbol
+func (dr *DBStore) SaveScriptState(state *ScriptState) (err error) {
+	old, err := dr.GetScriptState(state.Name, state.Exchange, state.Symbol)
+	if err != nil {
+		return
+	}
+	state.UpdateTime = time.Now()
+	if old == nil {
+		state.ID = 0
+		_, err = dr.engine.Insert(state)
+		return
+	}
+	state.ID = old.ID
+	_, err = dr.engine.ID(state.ID).AllCols().Update(state)
+	return
+}
+
+// StateStore store the script states of exchange and symbol
+type StateStore struct {
+	db       *DBStore
+	exchange string
+	symbol   string
+}
+
+// StateStore return the state store of exchange and symbol
+func (dr *DBStore) StateStore(exchange, symbol string) *StateStore {
+	return &StateStore{db: dr, exchange: exchange, symbol: symbol}
+}
+
+// LoadState load the state of script, return nil if not exist
+func (s *StateStore) LoadState(name string) (data []byte, err error) {
+	state, err := s.db.GetScriptState(name, s.exchange, s.symbol)
+	if err != nil || state == nil {
+		return
+	}
+	data = state.Data
+	return
+}
+
+// SaveState save the state of script
+func (s *StateStore) SaveState(name string, data []byte) (err error) {
+	return s.db.SaveScriptState(&ScriptState{Name: name, Exchange: s.exchange, Symbol: s.symbol, Data: data})
+}
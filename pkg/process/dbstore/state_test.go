@@ -0,0 +1,40 @@
+package dbstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+func TestStateStore(t *testing.T) {
+	db, err := NewDBStore("sqlite", filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer db.Close()
+	st := db.StateStore("binance", "BTCUSDT")
+	data, err := st.LoadState("a")
+	if err != nil || data != nil {
+		t.Fatalf("load not exist state error: %s %v", data, err)
+	}
+	for _, v := range []string{"1", "2"} {
+		err = st.SaveState("a", []byte(v))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	// the same name of other symbol is another state
+	err = db.StateStore("binance", "ETHUSDT").SaveState("a", []byte("3"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	data, err = st.LoadState("a")
+	if err != nil || string(data) != "2" {
+		t.Fatalf("load state error: %s %v", data, err)
+	}
+	n, err := db.engine.Count(&ScriptState{})
+	if err != nil || n != 2 {
+		t.Fatalf("state not updated in place: %d %v", n, err)
+	}
+}
@@ -0,0 +1,38 @@
+package dbstore
+
+import (
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// GetSymbolInfo get symbol info, return nil if not exist
+func (dr *DBStore) GetSymbolInfo(exchange, symbol string) (si *SymbolInfo, err error) {
+	var info SymbolInfo
+	has, err := dr.engine.Where("exchange = ? and symbol = ?", exchange, symbol).Get(&info)
+	if err != nil || !has {
+		return
+	}
+	si = &info
+	return
+}
+
+// GetSymbolInfos get all symbol infos of exchange
+func (dr *DBStore) GetSymbolInfos(exchange string) (infos []SymbolInfo, err error) {
+	err = dr.engine.Where("exchange = ?", exchange).Asc("symbol").Find(&infos)
+	return
+}
+
+// SaveSymbolInfo insert or update symbol info by exchange and symbol
+func (dr *DBStore) SaveSymbolInfo(si *SymbolInfo) (err error) {
+	old, err := dr.GetSymbolInfo(si.Exchange, si.Symbol)
+	if err != nil {
+		return
+	}
+	if old == nil {
+		si.ID = 0
+		_, err = dr.engine.Insert(si)
+		return
+	}
+	si.ID = old.ID
+	_, err = dr.engine.ID(si.ID).AllCols().Update(si)
+	return
+}
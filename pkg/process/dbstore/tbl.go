@@ -151,7 +151,7 @@ func (t *TimeTbl) CacheData(start, end time.Time, bSize string) (err error) {
 		}
 	}
 	if err != nil {
-		err = fmt.Errorf("TimeTbl DataChan getDatas failed:", err.Error())
+		err = fmt.Errorf("TimeTbl DataChan getDatas failed: %w", err)
 	} else {
 		t.db.dataCache.Store(key, caches)
 	}
@@ -0,0 +1,235 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/ztrade/exchange"
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+func init() {
+	RegisterStateQuerier("binance", newBinanceQuerier)
+	RegisterSymbolLimitQuerier("binance", newBinanceLimitQuerier)
+}
+
+// binanceQuerier StateQuerier of binance usdt futures
+type binanceQuerier struct {
+	client  *futures.Client
+	timeout time.Duration
+}
+
+func newBinanceQuerier(cfg exchange.Config, cltName string) (q StateQuerier, err error) {
+	prefix := fmt.Sprintf("exchanges.%s.", cltName)
+	kind := cfg.GetString(prefix + "kind")
+	if kind == "spot" {
+		err = fmt.Errorf("binance %s not support query state", kind)
+		return
+	}
+	bq := &binanceQuerier{client: futures.NewClient(cfg.GetString(prefix+"key"), cfg.GetString(prefix+"secret"))}
+	bq.timeout = cfg.GetDuration(prefix + "timeout")
+	if bq.timeout == 0 {
+		bq.timeout = time.Second * 30
+	}
+	q = bq
+	return
+}
+
+func newBinanceLimitQuerier(cfg exchange.Config, cltName string) (q SymbolLimitQuerier, err error) {
+	if cfg.GetString(fmt.Sprintf("exchanges.%s.kind", cltName)) == "spot" {
+		return
+	}
+	bq, err := newBinanceQuerier(cfg, cltName)
+	if err != nil {
+		return
+	}
+	q = bq.(*binanceQuerier)
+	return
+}
+
+func (q *binanceQuerier) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), q.timeout)
+}
+
+func parseFloat(str string) float64 {
+	f, _ := strconv.ParseFloat(str, 64)
+	return f
+}
+
+func transBinanceOrder(o *futures.Order) *Order {
+	od := &Order{
+		OrderID: strconv.FormatInt(o.OrderID, 10),
+		Symbol:  o.Symbol,
+		Amount:  parseFloat(o.OrigQuantity),
+		Price:   parseFloat(o.Price),
+		Side:    strings.ToLower(string(o.Side)),
+		Time:    time.UnixMilli(o.Time),
+		Filled:  parseFloat(o.ExecutedQuantity),
+	}
+	switch o.Status {
+	case futures.OrderStatusTypeFilled:
+		od.Status = OrderStatusFilled
+		if avg := parseFloat(o.AvgPrice); avg > 0 {
+			od.Price = avg
+		}
+	case futures.OrderStatusTypeCanceled, futures.OrderStatusTypeExpired, futures.OrderStatusTypeRejected:
+		od.Status = OrderStatusCanceled
+	default:
+		od.Status = string(o.Status)
+	}
+	return od
+}
+
+func (q *binanceQuerier) GetOrder(order *Order) (ret *Order, err error) {
+	id, err := strconv.ParseInt(order.OrderID, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("binance order id %s invalid: %w", order.OrderID, err)
+		return
+	}
+	ctx, cancel := q.ctx()
+	defer cancel()
+	o, err := q.client.NewGetOrderService().Symbol(order.Symbol).OrderID(id).Do(ctx)
+	if err != nil {
+		return
+	}
+	ret = transBinanceOrder(o)
+	return
+}
+
+func (q *binanceQuerier) OpenOrders(symbol string) (orders []*Order, err error) {
+	ctx, cancel := q.ctx()
+	defer cancel()
+	ret, err := q.client.NewListOpenOrdersService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return
+	}
+	for _, v := range ret {
+		orders = append(orders, transBinanceOrder(v))
+	}
+	return
+}
+
+func (q *binanceQuerier) Positions(symbol string) (positions []Position, err error) {
+	ctx, cancel := q.ctx()
+	defer cancel()
+	ret, err := q.client.NewGetPositionRiskService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return
+	}
+	for _, v := range ret {
+		pos := Position{Symbol: v.Symbol, Hold: parseFloat(v.PositionAmt), Price: parseFloat(v.EntryPrice)}
+		if pos.Hold == 0 {
+			continue
+		}
+		if pos.Hold > 0 {
+			pos.Type = Long
+		} else {
+			pos.Type = Short
+		}
+		positions = append(positions, pos)
+	}
+	return
+}
+
+const (
+	// binanceFillWindow the max time range of one trades query
+	binanceFillWindow = time.Hour * 24 * 7
+	// binanceFillLimit the max trades of one page
+	binanceFillLimit = 1000
+)
+
+// fetchTradesFn query one page of trades, the trades from fromID if fromID > 0, or else the trades in [start, end]
+type fetchTradesFn func(start, end time.Time, fromID int64) ([]*futures.AccountTrade, error)
+
+func (q *binanceQuerier) Fills(symbol string, since time.Time) (fills []Fill, err error) {
+	ctx, cancel := q.ctx()
+	defer cancel()
+	fetch := func(start, end time.Time, fromID int64) ([]*futures.AccountTrade, error) {
+		svc := q.client.NewListAccountTradeService().Symbol(symbol).Limit(binanceFillLimit)
+		if fromID > 0 {
+			// fromId can't be sent with the time range
+			svc = svc.FromID(fromID)
+		} else {
+			svc = svc.StartTime(start.UnixMilli()).EndTime(end.UnixMilli())
+		}
+		return svc.Do(ctx)
+	}
+	trades, err := pageTrades(since, time.Now(), fetch)
+	if err != nil {
+		return
+	}
+	for _, v := range trades {
+		fills = append(fills, Fill{
+			OrderID: strconv.FormatInt(v.OrderID, 10),
+			Side:    strings.ToLower(string(v.Side)),
+			Price:   parseFloat(v.Price),
+			Amount:  parseFloat(v.Quantity),
+			Time:    time.UnixMilli(v.Time),
+		})
+	}
+	return
+}
+
+// pageTrades query all the trades in [since, now], binance limits one query to 7 days and one page
+// to binanceFillLimit trades, so query every window by time and the rest of a full window by id
+func pageTrades(since, now time.Time, fetch fetchTradesFn) (trades []*futures.AccountTrade, err error) {
+	for start := since; !start.After(now); start = start.Add(binanceFillWindow) {
+		end := start.Add(binanceFillWindow - time.Millisecond)
+		if end.After(now) {
+			end = now
+		}
+		page, err := fetch(start, end, 0)
+		if err != nil {
+			return nil, err
+		}
+		for len(page) > 0 {
+			full := len(page) >= binanceFillLimit
+			last := page[len(page)-1]
+			for _, v := range page {
+				if v.Time > end.UnixMilli() {
+					full = false
+					break
+				}
+				trades = append(trades, v)
+			}
+			if !full {
+				break
+			}
+			page, err = fetch(start, end, last.ID+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return
+}
+
+// SymbolLimits return the min qty and min notional of usdt futures, the multiplier is always 1
+func (q *binanceQuerier) SymbolLimits() (limits map[string]SymbolLimit, err error) {
+	ctx, cancel := q.ctx()
+	defer cancel()
+	info, err := q.client.NewExchangeInfoService().Do(ctx)
+	if err != nil {
+		return
+	}
+	limits = make(map[string]SymbolLimit, len(info.Symbols))
+	for i := range info.Symbols {
+		limits[info.Symbols[i].Symbol] = transBinanceLimit(&info.Symbols[i])
+	}
+	return
+}
+
+func transBinanceLimit(s *futures.Symbol) (limit SymbolLimit) {
+	if f := s.LotSizeFilter(); f != nil {
+		limit.MinQty = parseFloat(f.MinQuantity)
+	}
+	if f := s.MinNotionalFilter(); f != nil {
+		limit.MinNotional = parseFloat(f.Notional)
+	}
+	return
+}
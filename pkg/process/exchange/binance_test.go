@@ -0,0 +1,68 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+func TestPageTrades(t *testing.T) {
+	now := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	since := now.Add(-time.Hour * 24 * 10)
+	// a burst of trades in the first window and one trade in the second
+	var all []*futures.AccountTrade
+	for i := 0; i < binanceFillLimit*2+10; i++ {
+		all = append(all, &futures.AccountTrade{ID: int64(i + 1), Time: since.Add(time.Second * time.Duration(i)).UnixMilli()})
+	}
+	all = append(all, &futures.AccountTrade{ID: int64(len(all) + 1), Time: now.Add(-time.Hour).UnixMilli()})
+	var queries int
+	fetch := func(start, end time.Time, fromID int64) (page []*futures.AccountTrade, err error) {
+		queries++
+		if end.Sub(start) >= binanceFillWindow {
+			t.Fatalf("time range too long: %s %s", start, end)
+		}
+		for _, v := range all {
+			if len(page) == binanceFillLimit {
+				break
+			}
+			if fromID > 0 && v.ID >= fromID || fromID == 0 && v.Time >= start.UnixMilli() && v.Time <= end.UnixMilli() {
+				page = append(page, v)
+			}
+		}
+		return
+	}
+	trades, err := pageTrades(since, now, fetch)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(trades) != len(all) {
+		t.Fatalf("trades missed: %d, expect: %d", len(trades), len(all))
+	}
+	for i, v := range trades {
+		if v.ID != int64(i+1) {
+			t.Fatalf("trade %d error: %d", i, v.ID)
+		}
+	}
+	// 3 pages of first window and 1 of second
+	if queries != 4 {
+		t.Fatalf("queries error: %d", queries)
+	}
+}
+
+func TestTransBinanceLimit(t *testing.T) {
+	s := &futures.Symbol{Symbol: "BTCUSDT", Filters: []map[string]interface{}{
+		{"filterType": "PRICE_FILTER", "tickSize": "0.10"},
+		{"filterType": "LOT_SIZE", "minQty": "0.001", "stepSize": "0.001"},
+		{"filterType": "MIN_NOTIONAL", "notional": "100"},
+	}}
+	limit := transBinanceLimit(s)
+	if limit.MinQty != 0.001 || limit.MinNotional != 100 || limit.Multiplier != 0 {
+		t.Fatalf("limit error: %#v", limit)
+	}
+	limit = transBinanceLimit(&futures.Symbol{Symbol: "ETHUSDT"})
+	if limit != (SymbolLimit{}) {
+		t.Fatalf("limit without filters should be empty: %#v", limit)
+	}
+}
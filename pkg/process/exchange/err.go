@@ -1,31 +1,180 @@
 package exchange
 
 import (
+	"context"
 	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 var (
 	ErrCanRetry = errors.New("error but can retry")
+	// ErrRateLimit the request is rejected by rate limit of exchange, retry after backoff
+	ErrRateLimit = errors.New("rate limit")
+	// ErrRejected the request is rejected by exchange, never retry
+	ErrRejected = errors.New("rejected")
+)
+
+// ErrorClass how to deal with an error of exchange call
+type ErrorClass int
+
+const (
+	// ErrorPermanent never retry, eg: insufficient balance, invalid price
+	ErrorPermanent ErrorClass = iota
+	// ErrorRetryable network error or 5xx, retry with backoff
+	ErrorRetryable
+	// ErrorRateLimit rate limited by exchange, retry with a longer backoff
+	ErrorRateLimit
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorRetryable:
+		return "retryable"
+	case ErrorRateLimit:
+		return "ratelimit"
+	default:
+		return "permanent"
+	}
+}
+
+// ErrorClassifier exchange which knows its own error codes
+type ErrorClassifier interface {
+	ClassifyError(err error) ErrorClass
+}
+
+var (
+	rateLimitPattern = keywordRegexp([]string{"429", "418"}, []string{"too many requests", "rate limit", "ratelimit", "too many"})
+	permanentPattern = keywordRegexp(nil, []string{"insufficient", "invalid", "not enough", `reject\w*`, "precision", "min notional", "not found", "unknown order", "illegal"})
+	retryPattern     = keywordRegexp([]string{"500", "502", "503", "504"}, []string{"timeout", "time out", "timed out", "connection reset", "connection refused", "eof", "broken pipe", "service unavailable", "bad gateway", "internal server error", "busy"})
 )
 
+// keywordRegexp match the words as whole words, codes must not be a part of number, eg: 500 doesn't match 1500 or 500.5
+func keywordRegexp(codes, words []string) *regexp.Regexp {
+	var patterns []string
+	if len(codes) > 0 {
+		patterns = append(patterns, `(?:^|[^\w.])(?:`+strings.Join(codes, "|")+`)(?:[^\w.]|\.(?:\D|$)|$)`)
+	}
+	if len(words) > 0 {
+		patterns = append(patterns, `\b(?:`+strings.Join(words, "|")+`)\b`)
+	}
+	return regexp.MustCompile(strings.Join(patterns, "|"))
+}
+
+// ClassifyError classify error by sentinel errors, net errors and the keywords of error message
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ErrorPermanent
+	case errors.Is(err, ErrRejected):
+		return ErrorPermanent
+	case errors.Is(err, ErrRateLimit):
+		return ErrorRateLimit
+	case errors.Is(err, ErrCanRetry), errors.Is(err, context.DeadlineExceeded):
+		return ErrorRetryable
+	}
+	msg := strings.ToLower(err.Error())
+	if rateLimitPattern.MatchString(msg) {
+		return ErrorRateLimit
+	}
+	if permanentPattern.MatchString(msg) {
+		return ErrorPermanent
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorRetryable
+	}
+	if retryPattern.MatchString(msg) {
+		return ErrorRetryable
+	}
+	return ErrorPermanent
+}
+
+// RetryPolicy retry policy of exchange calls
+type RetryPolicy struct {
+	Max int
+	// first delay of retryable error, doubled every retry
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// first delay of rate limit error, doubled every retry
+	RateLimitDelay time.Duration
+}
+
+// DefaultRetryPolicy default retry policy
+var DefaultRetryPolicy = RetryPolicy{
+	Max:            10,
+	Delay:          time.Millisecond * 500,
+	MaxDelay:       time.Second * 10,
+	RateLimitDelay: time.Second * 2,
+}
+
+// backoff return the delay before nth(from 0) retry
+func (p RetryPolicy) backoff(n int, class ErrorClass) time.Duration {
+	delay := p.Delay
+	if class == ErrorRateLimit {
+		delay = p.RateLimitDelay
+	}
+	for i := 0; i < n && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// notSent return true if the request provably didn't reach the exchange or was refused by it,
+// the order is only resent in these cases, a timeout or 5xx may have opened the order already
+func notSent(err error) bool {
+	if errors.Is(err, ErrRateLimit) || errors.Is(err, ErrCanRetry) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "connection refused")
+}
+
 type dofn func() (interface{}, error)
 
-func doOrderWithRetry(nRetry int, fn dofn) (ret interface{}, err error) {
-	ret, err = fn()
-	if err == nil {
-		return
-	}
-	for n := 0; n != nRetry; n++ {
-		if errors.Is(err, ErrCanRetry) {
-			time.Sleep(time.Millisecond * 500)
-			ret, err = fn()
-			if err == nil {
-				break
-			}
-		} else {
-			break
+// doWithRetry call fn with rate limit of endpoint, retry the retryable errors with backoff
+// orders are not idempotent, they are retried only when the request was not sent or rate limited
+func (b *TradeExchange) doWithRetry(endpoint string, fn dofn) (ret interface{}, err error) {
+	classify := ClassifyError
+	if classifier, ok := b.impl.(ErrorClassifier); ok {
+		classify = classifier.ClassifyError
+	}
+	var class ErrorClass
+	for n := 0; ; n++ {
+		b.limiter.wait(endpoint, b.metrics)
+		b.metrics.add(endpoint, metricCalls)
+		ret, err = fn()
+		if err == nil {
+			return
+		}
+		class = classify(err)
+		switch class {
+		case ErrorRateLimit:
+			b.metrics.add(endpoint, metricRateLimited)
+		case ErrorPermanent:
+			b.metrics.add(endpoint, metricRejected)
+		}
+		if class == ErrorRetryable && endpoint == EndpointOrder && !notSent(err) {
+			log.Warnf("order may be sent already, not retry: %s", err.Error())
+			b.metrics.add(endpoint, metricFailed)
+			return
+		}
+		if class == ErrorPermanent || n >= b.retry.Max {
+			b.metrics.add(endpoint, metricFailed)
+			return
 		}
+		b.metrics.add(endpoint, metricRetries)
+		time.Sleep(b.retry.backoff(n, class))
 	}
-	return
 }
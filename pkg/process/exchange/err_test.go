@@ -0,0 +1,105 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err   error
+		class ErrorClass
+	}{
+		{nil, ErrorPermanent},
+		{fmt.Errorf("order: %w", ErrRejected), ErrorPermanent},
+		{fmt.Errorf("order: %w", ErrRateLimit), ErrorRateLimit},
+		{fmt.Errorf("order: %w", ErrCanRetry), ErrorRetryable},
+		{context.DeadlineExceeded, ErrorRetryable},
+		{&net.OpError{Op: "dial", Err: errors.New("no route to host")}, ErrorRetryable},
+		{errors.New("HTTP 429 Too Many Requests"), ErrorRateLimit},
+		{errors.New("status: 418"), ErrorRateLimit},
+		{errors.New("code=-1003, msg=Way too many requests"), ErrorRateLimit},
+		{errors.New("Account has insufficient balance"), ErrorPermanent},
+		{errors.New("order rejected by exchange"), ErrorPermanent},
+		{errors.New("502 Bad Gateway"), ErrorRetryable},
+		{errors.New("http status 503."), ErrorRetryable},
+		{errors.New("read tcp: connection reset by peer"), ErrorRetryable},
+		{errors.New("unexpected EOF"), ErrorRetryable},
+		{errors.New("i/o timeout"), ErrorRetryable},
+		// keywords in numbers or words are not matched
+		{errors.New("order 15001 failed"), ErrorPermanent},
+		{errors.New("order 4290 failed"), ErrorPermanent},
+		{errors.New("price 500.5 failed"), ErrorPermanent},
+		{errors.New("geofence blocked"), ErrorPermanent},
+		{errors.New("symbol is busybox"), ErrorPermanent},
+	}
+	for _, v := range cases {
+		class := ClassifyError(v.err)
+		if class != v.class {
+			t.Errorf("classify %v error: %s, expect: %s", v.err, class, v.class)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		policy RetryPolicy
+		n      int
+		class  ErrorClass
+		delay  time.Duration
+	}{
+		{DefaultRetryPolicy, 0, ErrorRetryable, time.Millisecond * 500},
+		{DefaultRetryPolicy, 1, ErrorRetryable, time.Second},
+		{DefaultRetryPolicy, 4, ErrorRetryable, time.Second * 8},
+		{DefaultRetryPolicy, 5, ErrorRetryable, time.Second * 10},
+		{DefaultRetryPolicy, 9, ErrorRetryable, time.Second * 10},
+		{DefaultRetryPolicy, 0, ErrorRateLimit, time.Second * 2},
+		{DefaultRetryPolicy, 2, ErrorRateLimit, time.Second * 8},
+		{DefaultRetryPolicy, 3, ErrorRateLimit, time.Second * 10},
+		// no max delay
+		{RetryPolicy{Delay: time.Second}, 3, ErrorRetryable, time.Second * 8},
+	}
+	for _, v := range cases {
+		delay := v.policy.backoff(v.n, v.class)
+		if delay != v.delay {
+			t.Errorf("backoff %d of %s error: %s, expect: %s", v.n, v.class, delay, v.delay)
+		}
+	}
+}
+
+func TestOrderRetry(t *testing.T) {
+	te := NewTradeExchange("fake", &fakeImpl{}, "BTCUSDT")
+	te.SetLimitConfig(LimitConfig{Retry: RetryPolicy{Max: 3, Delay: time.Millisecond, RateLimitDelay: time.Millisecond}})
+	cases := []struct {
+		endpoint string
+		err      error
+		calls    int
+	}{
+		// the order may be opened already, resending it may open the position twice
+		{EndpointOrder, context.DeadlineExceeded, 1},
+		{EndpointOrder, errors.New("read tcp: i/o timeout"), 1},
+		{EndpointOrder, errors.New("502 Bad Gateway"), 1},
+		{EndpointOrder, &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, 1},
+		// not sent or rejected, safe to resend
+		{EndpointOrder, &net.OpError{Op: "dial", Err: errors.New("no route to host")}, 4},
+		{EndpointOrder, errors.New("dial tcp: connection refused"), 4},
+		{EndpointOrder, fmt.Errorf("order: %w", ErrRateLimit), 4},
+		{EndpointOrder, fmt.Errorf("order: %w", ErrCanRetry), 4},
+		// queries are idempotent
+		{EndpointQuery, context.DeadlineExceeded, 4},
+	}
+	for _, v := range cases {
+		var calls int
+		_, err := te.doWithRetry(v.endpoint, func() (interface{}, error) {
+			calls++
+			return nil, v.err
+		})
+		if err == nil || calls != v.calls {
+			t.Errorf("%s %v calls: %d, expect: %d", v.endpoint, v.err, calls, v.calls)
+		}
+	}
+}
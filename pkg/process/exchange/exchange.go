@@ -1,6 +1,7 @@
 package exchange
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -17,8 +18,9 @@ import (
 type OrderInfo struct {
 	LocalID string
 	Order
-	Action TradeType
-	Filled bool
+	Action   TradeType
+	Filled   bool
+	Canceled bool
 }
 
 type TradeExchange struct {
@@ -31,24 +33,45 @@ type TradeExchange struct {
 
 	orders          map[string]*OrderInfo
 	localOrderIndex map[string]*OrderInfo
+	// protect orders,localOrderIndex and pos
+	stateMutex sync.Mutex
 
 	closeCh chan bool
 
 	pos            Position
 	positionUpdate int64
 	exchangeName   string
+	account        string
+	isDefault      bool
 	symbol         string
 
 	candleParam CandleParam
 
 	localStopOrder bool
 	stopOrders     sync.Map
+
+	reconcileInterval time.Duration
+	querier           StateQuerier
+	cancelUnknown     bool
+	lastReconcile     time.Time
+
+	limiter *rateLimiter
+	retry   RetryPolicy
+	metrics *Metrics
+
+	symbolInfo   *SymbolInfo
+	limitQuerier SymbolLimitQuerier
+
+	serveMarket bool
+	serveOrder  bool
 }
 
 func NewTradeExchange(exName string, impl exchange.Exchange, symbol string) *TradeExchange {
 	te := new(TradeExchange)
 	te.Name = fmt.Sprintf("exchange-%s", exName)
 	te.exchangeName = exName
+	te.account = exName
+	te.isDefault = true
 	te.impl = impl
 	te.actChan = make(chan TradeAction, 10)
 	te.orders = make(map[string]*OrderInfo)
@@ -56,9 +79,41 @@ func NewTradeExchange(exName string, impl exchange.Exchange, symbol string) *Tra
 	te.closeCh = make(chan bool)
 	te.symbol = symbol
 	te.datas = make(chan interface{}, 1024)
+	te.limiter = newRateLimiter()
+	te.retry = DefaultRetryPolicy
+	te.metrics = newMetrics()
+	te.serveMarket = true
+	te.serveOrder = true
 	return te
 }
 
+// SetAccount set the account name(config name), events are tagged with it
+func (b *TradeExchange) SetAccount(account string) {
+	b.account = account
+	b.Name = fmt.Sprintf("exchange-%s", account)
+}
+
+// SetDefault set if the exchange process the events not tagged with account
+func (b *TradeExchange) SetDefault(isDefault bool) {
+	b.isDefault = isDefault
+}
+
+// accept check if the event is sent to this exchange
+func (b *TradeExchange) accept(e *Event) bool {
+	if e.GetAccount() == "" {
+		return b.isDefault
+	}
+	return e.GetAccount() == b.account
+}
+
+// send send event tagged with exchange and account
+func (b *TradeExchange) send(name, strType string, data, extra interface{}) {
+	e := NewEvent(name, strType, b.Name, data, extra)
+	e.Exchange = b.exchangeName
+	e.Account = b.account
+	b.Bus.Send(e)
+}
+
 func (b *TradeExchange) UseLocalStopOrder(enable bool) {
 	b.localStopOrder = enable
 	if enable {
@@ -66,46 +121,97 @@ func (b *TradeExchange) UseLocalStopOrder(enable bool) {
 	}
 }
 
+// SetRole set what the exchange serves, both are served by default
+// market serves the watches of market data, order serves the orders and the balance, position and trades of account
+func (b *TradeExchange) SetRole(market, order bool) {
+	b.serveMarket = market
+	b.serveOrder = order
+}
+
 func (b *TradeExchange) Init(bus *Bus) (err error) {
 	b.BaseProcesser.Init(bus)
-	b.Subscribe(EventOrder, b.onEventOrder)
-	b.Subscribe(EventWatch, b.onEventWatch)
-	return
+	var errs []error
+	if b.serveOrder {
+		errs = append(errs, SubscribeData(b, EventOrder, b.onEventOrder))
+	}
+	if b.serveMarket {
+		errs = append(errs, SubscribeData(b, EventWatch, b.onEventWatch))
+	}
+	return errors.Join(errs...)
 }
 
 func (b *TradeExchange) Start() (err error) {
-	b.impl.Watch(exchange.WatchParam{Type: exchange.WatchTypeBalance}, func(data interface{}) {
-		b.datas <- data
-	})
-	b.impl.Watch(exchange.WatchParam{Type: exchange.WatchTypePosition}, func(data interface{}) {
-		b.datas <- data
-	})
-	b.impl.Watch(exchange.WatchParam{Type: exchange.WatchTypeTrade}, func(data interface{}) {
-		b.datas <- data
-	})
+	if b.serveOrder {
+		b.impl.Watch(exchange.WatchParam{Type: exchange.WatchTypeBalance}, func(data interface{}) {
+			b.datas <- data
+		})
+		b.impl.Watch(exchange.WatchParam{Type: exchange.WatchTypePosition}, func(data interface{}) {
+			b.datas <- data
+		})
+		b.impl.Watch(exchange.WatchParam{Type: exchange.WatchTypeTrade}, func(data interface{}) {
+			b.datas <- data
+		})
+	}
 	err = b.impl.Start()
 	if err != nil {
 		return err
 	}
+	b.loadSymbolInfo()
 	go b.recvDatas()
 	go b.orderRoutine()
+	if b.serveOrder && b.reconcileInterval > 0 {
+		querier := b.querier
+		if querier == nil {
+			querier, _ = b.impl.(StateQuerier)
+		}
+		if querier != nil {
+			go b.reconcileRoutine(querier)
+		} else {
+			log.Warnf("%s TradeExchange not support query state, reconcile disabled", b.impl.Info().Name)
+		}
+	}
 	return
 }
 
+// SetSymbolInfo set symbol info, orders are rounded and checked by it
+// if not set, it is fetched from exchange when start
+func (b *TradeExchange) SetSymbolInfo(si *SymbolInfo) {
+	b.symbolInfo = si
+}
+
+func (b *TradeExchange) loadSymbolInfo() {
+	if b.symbolInfo == nil {
+		infos, err := newSymbolInfos(b.exchangeName, b.impl, b.limitQuerier)
+		if err != nil {
+			log.Warnf("%s get symbols failed: %s, orders are not rounded", b.Name, err.Error())
+			return
+		}
+		for _, v := range infos {
+			if v.Symbol == b.symbol {
+				b.symbolInfo = v
+				break
+			}
+		}
+		if b.symbolInfo == nil {
+			log.Warnf("%s symbol %s not found, orders are not rounded", b.Name, b.symbol)
+			return
+		}
+	}
+	b.send(b.symbol, EventSymbolInfo, b.symbolInfo, nil)
+}
+
 func (b *TradeExchange) Stop() (err error) {
 	err = b.impl.Stop()
 	close(b.actChan)
+	close(b.closeCh)
 	return
 }
 
 func (b *TradeExchange) recvDatas() {
-	var ok bool
 	var posTime int64
-	var o *OrderInfo
 	bFirst := true
 	var err error
 	var tFirstLastStart int64
-Out:
 	for data := range b.datas {
 		switch value := data.(type) {
 		case *Candle:
@@ -115,64 +221,85 @@ Out:
 				param.End = value.Time().Add(-1 * time.Second)
 				tFirstLastStart, err = b.emitRecentCandles(param)
 				if err != nil {
-					log.Errorf("TradeExchange recv data:", err.Error())
+					log.Errorf("TradeExchange recv data: %s", err.Error())
 					panic(err.Error())
 				}
 				if value.Start <= tFirstLastStart {
 					continue
 				}
 			}
-			b.SendWithExtra("candle", EventCandle, value, b.candleParam.BinSize)
+			b.send("candle", EventCandle, value, b.candleParam.BinSize)
 		case *Balance:
-			b.Send(b.exchangeName, EventBalance, value)
+			b.send(b.exchangeName, EventBalance, value, nil)
 		case *Position:
 			if value.Symbol != b.symbol {
 				log.Infof("TradeExchange ignore event: %#v, exchange symbol: %s, data symbol: %s", value, b.symbol, value.Symbol)
 				continue
 			}
+			b.stateMutex.Lock()
 			b.pos = *value
+			b.stateMutex.Unlock()
 			posTime = time.Now().Unix()
 			atomic.StoreInt64(&b.positionUpdate, posTime)
-			b.Send(value.Symbol, EventPosition, value)
+			b.send(value.Symbol, EventPosition, value, nil)
 		case *Order:
 			if value.Symbol != b.symbol {
 				log.Infof("TradeExchange ignore event: %#v, exchange symbol: %s, data symbol: %s", value, b.symbol, value.Symbol)
 				continue
 			}
-			o, ok = b.orders[value.OrderID]
-			if !ok || o.Filled {
-				continue Out
-			}
-			o.Order = *value
-			if value.Status != OrderStatusFilled {
-				continue Out
-			}
-			o.Filled = true
-			tr := Trade{ID: o.LocalID,
-				Action: o.Action,
-				Time:   o.Time,
-				Price:  o.Price,
-				Amount: o.Amount,
-				Side:   o.Side,
-				Remark: o.OrderID}
-			b.Send(o.OrderID, EventTrade, &tr)
+			b.onOrder(value)
 		case *Depth:
-			b.Send(b.exchangeName, EventDepth, value)
+			b.send(b.exchangeName, EventDepth, value, nil)
 		case *Trade:
 			b.onEventTradeMarket(value)
-			b.Send(b.exchangeName, EventTradeMarket, value)
+			b.send(b.exchangeName, EventTradeMarket, value, nil)
 		default:
 			log.Errorf("unsupport exchange data: %##v", value)
 		}
 	}
 }
 
-func (b *TradeExchange) onEventCandleParam(e *Event) (err error) {
-	wParam, ok := e.GetData().(*WatchParam)
-	if !ok {
-		err = fmt.Errorf("event not watch %s %#v", e.Name, e.Data)
+// onOrder update local order, send trade if filled or order_canceled if canceled
+func (b *TradeExchange) onOrder(value *Order) {
+	b.stateMutex.Lock()
+	o, ok := b.orders[value.OrderID]
+	if !ok || o.Filled || o.Canceled {
+		b.stateMutex.Unlock()
+		return
+	}
+	o.Order = *value
+	switch value.Status {
+	case OrderStatusFilled:
+		o.Filled = true
+	case OrderStatusCanceled:
+		o.Canceled = true
+	default:
+		b.stateMutex.Unlock()
+		return
+	}
+	oi := *o
+	b.stateMutex.Unlock()
+	if oi.Canceled {
+		act := TradeAction{ID: oi.LocalID,
+			Action: oi.Action,
+			Amount: oi.Amount,
+			Price:  oi.Price,
+			Time:   oi.Time,
+			Symbol: oi.Symbol}
+		b.send(oi.OrderID, EventOrderCanceled, &act, nil)
 		return
 	}
+	tr := Trade{ID: oi.LocalID,
+		Action: oi.Action,
+		Time:   oi.Time,
+		Price:  oi.Price,
+		Amount: oi.Amount,
+		Side:   oi.Side,
+		Remark: oi.OrderID}
+	b.send(oi.OrderID, EventTrade, &tr, nil)
+}
+
+func (b *TradeExchange) onEventCandleParam(e *Event, wParam *WatchParam) (err error) {
 	cParam, _ := wParam.Data.(*CandleParam)
 	if cParam == nil {
 		err = fmt.Errorf("event not CandleParam %s %#v", e.Name, e.Data)
@@ -182,9 +309,15 @@ func (b *TradeExchange) onEventCandleParam(e *Event) (err error) {
 	return
 }
 
-func (b *TradeExchange) onEventOrder(e *Event) (err error) {
-	act := e.GetData().(*TradeAction)
-	b.actChan <- *act
+func (b *TradeExchange) onEventOrder(e *Event, order *TradeAction) (err error) {
+	if !b.accept(e) {
+		return
+	}
+	act := *order
+	if act.Symbol == "" {
+		act.Symbol = b.symbol
+	}
+	b.actChan <- act
 	return
 }
 
@@ -233,12 +366,14 @@ func (b *TradeExchange) onEventTradeMarket(trade *Trade) {
 	}
 }
 
-func (b *TradeExchange) onEventWatch(e *Event) (err error) {
+func (b *TradeExchange) onEventWatch(e *Event, param *WatchParam) (err error) {
+	if !b.accept(e) {
+		return
+	}
 	if e.Name == "candle" {
-		return b.onEventCandleParam(e)
+		return b.onEventCandleParam(e, param)
 	}
 
-	param := e.GetData().(*WatchParam)
 	switch param.Type {
 	case EventTradeMarket:
 		b.impl.Watch(exchange.WatchParam{Type: exchange.WatchTypeTradeMarket, Param: map[string]string{"symbol": param.Extra.(string)}}, func(data interface{}) {
@@ -273,12 +408,14 @@ func (b *TradeExchange) orderRoutine() {
 			if exist {
 				continue
 			}
+			b.stateMutex.Lock()
 			oi, ok := b.localOrderIndex[v.ID]
+			b.stateMutex.Unlock()
 			if !ok {
 				log.Errorf("local order: %s not found", v.ID)
 				continue
 			}
-			_, err = doOrderWithRetry(10, func() (interface{}, error) {
+			_, err = b.doWithRetry(EndpointCancel, func() (interface{}, error) {
 				return b.impl.CancelOrder(&oi.Order)
 			})
 			if err != nil {
@@ -286,15 +423,23 @@ func (b *TradeExchange) orderRoutine() {
 			}
 			continue
 		}
-		ret, err = doOrderWithRetry(10, func() (interface{}, error) {
-			order, e := b.impl.ProcessOrder(v)
-			return order, e
-		})
+		err = nil
+		if b.symbolInfo != nil {
+			err = b.symbolInfo.FixOrder(&v)
+		}
+		if err == nil {
+			ret, err = b.doWithRetry(EndpointOrder, func() (interface{}, error) {
+				order, e := b.impl.ProcessOrder(v)
+				return order, e
+			})
+		}
 		if err == nil {
 			od := ret.(*Order)
 			oi := &OrderInfo{Order: *od, Action: v.Action, LocalID: v.ID}
+			b.stateMutex.Lock()
 			b.orders[od.OrderID] = oi
 			b.localOrderIndex[v.ID] = oi
+			b.stateMutex.Unlock()
 		} else {
 			tr := Trade{ID: v.ID,
 				Action: v.Action,
@@ -303,14 +448,14 @@ func (b *TradeExchange) orderRoutine() {
 				Amount: v.Amount,
 				// Side:   v.Action,
 				Remark: "failed:" + err.Error()}
-			b.Send(v.ID, EventTrade, &tr)
+			b.send(v.ID, EventTrade, &tr, nil)
 		}
 
 	}
 }
 
 func (b *TradeExchange) cancelAllOrder() {
-	ret, err := doOrderWithRetry(10, func() (interface{}, error) {
+	ret, err := b.doWithRetry(EndpointCancel, func() (interface{}, error) {
 		orders, err := b.impl.CancelAllOrders()
 		return orders, err
 	})
@@ -333,7 +478,7 @@ func (b *TradeExchange) emitCandles(param CandleParam) {
 		b.datas <- candle
 	})
 	if err != nil {
-		log.Errorf("emitCandles wathKline failed:", err.Error())
+		log.Errorf("emitCandles wathKline failed: %s", err.Error())
 		return
 	}
 }
@@ -342,7 +487,7 @@ func (b *TradeExchange) emitRecentCandles(param CandleParam) (tLast int64, err e
 	klines, errCh := exchange.KlineChan(b.impl, param.Symbol, param.BinSize, param.Start, param.End)
 	for v := range klines {
 		tLast = v.Start
-		b.SendWithExtra("recent", EventCandle, v, param.BinSize)
+		b.send("recent", EventCandle, v, param.BinSize)
 	}
 	err = <-errCh
 	return
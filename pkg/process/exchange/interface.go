@@ -2,7 +2,9 @@ package exchange
 
 import (
 	"fmt"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/ztrade/exchange"
 )
 
@@ -12,7 +14,39 @@ func GetTradeExchange(name string, cfg exchange.Config, cltName, symbol string)
 		return
 	}
 	t = NewTradeExchange(name, ex, symbol)
+	t.SetAccount(cltName)
 	localStop := cfg.GetBool(fmt.Sprintf("exchanges.%s.localstop", cltName))
 	t.UseLocalStopOrder(localStop)
+	limitQuerier, err := newSymbolLimitQuerier(name, ex, cfg, cltName)
+	if err != nil {
+		log.Warnf("exchanges.%s create symbol limit querier failed: %s", cltName, err.Error())
+		err = nil
+	}
+	t.SetSymbolLimitQuerier(limitQuerier)
+	reconcile := cfg.GetString(fmt.Sprintf("exchanges.%s.reconcile", cltName))
+	if reconcile != "" {
+		var interval time.Duration
+		interval, err = time.ParseDuration(reconcile)
+		if err != nil {
+			err = fmt.Errorf("exchanges.%s.reconcile invalid: %w", cltName, err)
+			return
+		}
+		t.SetReconcileInterval(interval)
+		var querier StateQuerier
+		querier, err = newStateQuerier(name, ex, cfg, cltName)
+		if err != nil {
+			log.Warnf("exchanges.%s create state querier failed: %s, reconcile disabled", cltName, err.Error())
+			err = nil
+		}
+		t.SetStateQuerier(querier)
+		t.SetCancelUnknown(cfg.GetBool(fmt.Sprintf("exchanges.%s.cancelunknown", cltName)))
+	}
+	limitCfg := LimitConfig{Retry: DefaultRetryPolicy}
+	err = cfg.UnmarshalKey(fmt.Sprintf("exchanges.%s", cltName), &limitCfg)
+	if err != nil {
+		err = fmt.Errorf("exchanges.%s limit config invalid: %w", cltName, err)
+		return
+	}
+	t.SetLimitConfig(limitCfg)
 	return
 }
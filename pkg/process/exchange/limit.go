@@ -0,0 +1,169 @@
+package exchange
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// endpoint classes of exchange calls, every class has its own rate limit
+const (
+	EndpointOrder  = "order"
+	EndpointCancel = "cancel"
+	EndpointQuery  = "query"
+)
+
+// RateLimit token bucket config, Rate is requests per second, 0 means no limit
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// LimitConfig rate limit and retry config of an exchange
+type LimitConfig struct {
+	RateLimit map[string]RateLimit
+	Retry     RetryPolicy
+}
+
+type rateLimiter struct {
+	limiters map[string]*rate.Limiter
+	mutex    sync.RWMutex
+}
+
+func newRateLimiter() *rateLimiter {
+	l := new(rateLimiter)
+	l.limiters = make(map[string]*rate.Limiter)
+	return l
+}
+
+func (l *rateLimiter) set(endpoint string, limit RateLimit) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if limit.Rate <= 0 {
+		delete(l.limiters, endpoint)
+		return
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	l.limiters[endpoint] = rate.NewLimiter(rate.Limit(limit.Rate), burst)
+}
+
+// wait block until the endpoint is allowed
+func (l *rateLimiter) wait(endpoint string, m *Metrics) {
+	l.mutex.RLock()
+	limiter, ok := l.limiters[endpoint]
+	l.mutex.RUnlock()
+	if !ok {
+		return
+	}
+	start := time.Now()
+	err := limiter.Wait(context.Background())
+	if err != nil {
+		return
+	}
+	waited := time.Since(start)
+	if waited > time.Millisecond {
+		m.add(endpoint, metricLimitWaits)
+		m.addWait(endpoint, waited)
+	}
+}
+
+const (
+	metricCalls = iota
+	metricRetries
+	metricRateLimited
+	metricRejected
+	metricFailed
+	metricLimitWaits
+)
+
+// CallMetrics metrics of exchange calls of one endpoint class
+type CallMetrics struct {
+	Calls int64
+	// retried calls
+	Retries int64
+	// rate limited by exchange
+	RateLimited int64
+	// permanent errors
+	Rejected int64
+	// calls failed after retry
+	Failed int64
+	// calls blocked by local rate limiter
+	LimitWaits int64
+	LimitWait  time.Duration
+}
+
+// Metrics metrics of exchange calls
+type Metrics struct {
+	datas map[string]*CallMetrics
+	mutex sync.Mutex
+}
+
+func newMetrics() *Metrics {
+	m := new(Metrics)
+	m.datas = make(map[string]*CallMetrics)
+	return m
+}
+
+func (m *Metrics) get(endpoint string) *CallMetrics {
+	cm, ok := m.datas[endpoint]
+	if !ok {
+		cm = new(CallMetrics)
+		m.datas[endpoint] = cm
+	}
+	return cm
+}
+
+func (m *Metrics) add(endpoint string, typ int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	cm := m.get(endpoint)
+	switch typ {
+	case metricCalls:
+		cm.Calls++
+	case metricRetries:
+		cm.Retries++
+	case metricRateLimited:
+		cm.RateLimited++
+	case metricRejected:
+		cm.Rejected++
+	case metricFailed:
+		cm.Failed++
+	case metricLimitWaits:
+		cm.LimitWaits++
+	}
+}
+
+func (m *Metrics) addWait(endpoint string, wait time.Duration) {
+	m.mutex.Lock()
+	m.get(endpoint).LimitWait += wait
+	m.mutex.Unlock()
+}
+
+// Snapshot return a copy of metrics of all endpoints
+func (m *Metrics) Snapshot() (ret map[string]CallMetrics) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ret = make(map[string]CallMetrics, len(m.datas))
+	for k, v := range m.datas {
+		ret[k] = *v
+	}
+	return
+}
+
+// SetLimitConfig set the rate limit and retry policy
+func (b *TradeExchange) SetLimitConfig(cfg LimitConfig) {
+	for k, v := range cfg.RateLimit {
+		b.limiter.set(k, v)
+	}
+	b.retry = cfg.Retry
+}
+
+// Metrics return the call metrics of exchange
+func (b *TradeExchange) Metrics() *Metrics {
+	return b.metrics
+}
@@ -0,0 +1,244 @@
+package exchange
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ztrade/exchange"
+	. "github.com/ztrade/ztrade/pkg/core"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/ztrade/trademodel"
+)
+
+const holdEpsilon = 1e-9
+
+// Fill a fill of order in exchange
+type Fill struct {
+	OrderID string
+	Side    string
+	Price   float64
+	Amount  float64
+	Time    time.Time
+}
+
+// StateQuerier exchange which can query the order and position state actively
+// reconcile is only enabled when the exchange implements it or a querier is registered for the exchange type
+type StateQuerier interface {
+	GetOrder(order *Order) (*Order, error)
+	OpenOrders(symbol string) ([]*Order, error)
+	Positions(symbol string) ([]Position, error)
+	// Fills return the fills of symbol since the time
+	Fills(symbol string, since time.Time) ([]Fill, error)
+}
+
+// NewStateQuerierFn create the StateQuerier of exchange account
+type NewStateQuerierFn func(cfg exchange.Config, cltName string) (StateQuerier, error)
+
+var queriers = map[string]NewStateQuerierFn{}
+
+// RegisterStateQuerier register the StateQuerier of exchange type, used when the exchange doesn't implement it
+func RegisterStateQuerier(typ string, fn NewStateQuerierFn) {
+	queriers[typ] = fn
+}
+
+// newStateQuerier create the StateQuerier of exchange account, nil if not supported
+func newStateQuerier(typ string, impl exchange.Exchange, cfg exchange.Config, cltName string) (querier StateQuerier, err error) {
+	querier, ok := impl.(StateQuerier)
+	if ok {
+		return
+	}
+	fn, ok := queriers[typ]
+	if !ok {
+		return
+	}
+	return fn(cfg, cltName)
+}
+
+// SetReconcileInterval set the interval of reconcile, 0 means disabled
+func (b *TradeExchange) SetReconcileInterval(interval time.Duration) {
+	b.reconcileInterval = interval
+}
+
+// SetStateQuerier set the querier used by reconcile
+func (b *TradeExchange) SetStateQuerier(querier StateQuerier) {
+	b.querier = querier
+}
+
+// SetCancelUnknown cancel the open orders in exchange which are not sent by this process when reconcile
+func (b *TradeExchange) SetCancelUnknown(enable bool) {
+	b.cancelUnknown = enable
+}
+
+func (b *TradeExchange) reconcileRoutine(querier StateQuerier) {
+	ticker := time.NewTicker(b.reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.closeCh:
+			return
+		case <-ticker.C:
+			b.reconcile(querier)
+		}
+	}
+}
+
+// waitQuery wait the rate limiter of query, reconcile never retry
+func (b *TradeExchange) waitQuery() {
+	b.limiter.wait(EndpointQuery, b.metrics)
+	b.metrics.add(EndpointQuery, metricCalls)
+}
+
+// reconcile compare the local state with exchange, the missed updates are
+// pushed to datas so that fills and cancels are synthesized by recvDatas
+func (b *TradeExchange) reconcile(querier StateQuerier) {
+	var drifts []string
+	var opens []Order
+	known := make(map[string]bool)
+	start := time.Now()
+	b.stateMutex.Lock()
+	for k, v := range b.orders {
+		known[k] = true
+		if !v.Filled && !v.Canceled {
+			opens = append(opens, v.Order)
+		}
+	}
+	hold := b.pos.Hold
+	b.stateMutex.Unlock()
+
+	opens, drifts = b.reconcileFills(querier, opens, known, start)
+
+	for i := range opens {
+		od := &opens[i]
+		b.waitQuery()
+		ret, err := querier.GetOrder(od)
+		if err != nil {
+			log.Errorf("reconcile get order %s failed: %s", od.OrderID, err.Error())
+			continue
+		}
+		if ret.Status != OrderStatusFilled && ret.Status != OrderStatusCanceled {
+			continue
+		}
+		if ret.OrderID == "" {
+			ret.OrderID = od.OrderID
+		}
+		if ret.Symbol == "" {
+			ret.Symbol = b.symbol
+		}
+		drifts = append(drifts, fmt.Sprintf("order %s missed status: %s", ret.OrderID, ret.Status))
+		b.datas <- ret
+	}
+
+	b.waitQuery()
+	orders, err := querier.OpenOrders(b.symbol)
+	if err != nil {
+		log.Errorf("reconcile get open orders failed: %s", err.Error())
+	} else {
+		for _, v := range b.unknownOrders(orders, known, start) {
+			drift := fmt.Sprintf("unknown open order %s: %s %f@%f", v.OrderID, v.Side, v.Amount, v.Price)
+			if b.cancelUnknown {
+				_, err = b.doWithRetry(EndpointCancel, func() (interface{}, error) {
+					return b.impl.CancelOrder(v)
+				})
+				if err != nil {
+					drift += ", cancel failed: " + err.Error()
+				} else {
+					drift += ", canceled"
+				}
+			}
+			drifts = append(drifts, drift)
+		}
+	}
+
+	b.waitQuery()
+	positions, err := querier.Positions(b.symbol)
+	if err != nil {
+		log.Errorf("reconcile get positions failed: %s", err.Error())
+	} else {
+		pos := Position{Symbol: b.symbol}
+		for _, v := range positions {
+			if v.Symbol == b.symbol {
+				pos = v
+				break
+			}
+		}
+		if math.Abs(pos.Hold-hold) > holdEpsilon {
+			drifts = append(drifts, fmt.Sprintf("position drift: local %f, exchange %f", hold, pos.Hold))
+			b.datas <- &pos
+		}
+	}
+
+	if len(drifts) == 0 {
+		return
+	}
+	content := strings.Join(drifts, "\n")
+	log.Warnf("%s reconcile found drift:\n%s", b.Name, content)
+	b.send(b.Name, EventNotify, &NotifyEvent{Title: fmt.Sprintf("%s reconcile", b.Name), Content: content}, nil)
+}
+
+// reconcileFills poll the fills since the oldest open order, the fully filled orders are synthesized
+// return the orders still open, and the drifts of missed fills and the fills of unknown orders
+func (b *TradeExchange) reconcileFills(querier StateQuerier, opens []Order, known map[string]bool, start time.Time) (left []Order, drifts []string) {
+	since := b.lastReconcile
+	if since.IsZero() {
+		since = start.Add(-b.reconcileInterval)
+	}
+	for _, v := range opens {
+		if v.Time.Before(since) {
+			since = v.Time
+		}
+	}
+	b.waitQuery()
+	fills, err := querier.Fills(b.symbol, since)
+	if err != nil {
+		log.Errorf("reconcile get fills failed: %s", err.Error())
+		return opens, nil
+	}
+	amounts := make(map[string]float64)
+	costs := make(map[string]float64)
+	for _, v := range fills {
+		amounts[v.OrderID] += v.Amount
+		costs[v.OrderID] += v.Amount * v.Price
+		// fills before last reconcile have been reported
+		if !known[v.OrderID] && !v.Time.Before(b.lastReconcile) {
+			drifts = append(drifts, fmt.Sprintf("unknown fill of order %s: %s %f@%f", v.OrderID, v.Side, v.Amount, v.Price))
+		}
+	}
+	b.lastReconcile = start
+	for _, v := range opens {
+		filled := amounts[v.OrderID]
+		if filled < v.Amount-holdEpsilon {
+			left = append(left, v)
+			continue
+		}
+		od := v
+		od.Status = OrderStatusFilled
+		od.Filled = filled
+		od.Price = costs[v.OrderID] / filled
+		if od.Symbol == "" {
+			od.Symbol = b.symbol
+		}
+		drifts = append(drifts, fmt.Sprintf("order %s missed fill: %f@%f", od.OrderID, od.Filled, od.Price))
+		b.datas <- &od
+	}
+	return
+}
+
+// unknownOrders return the open orders not sent by this process
+// orders created after reconcile start may be sent but not recorded yet, they are checked next time
+func (b *TradeExchange) unknownOrders(orders []*Order, known map[string]bool, start time.Time) (unknown []*Order) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	for _, v := range orders {
+		if known[v.OrderID] || !v.Time.Before(start) {
+			continue
+		}
+		if _, ok := b.orders[v.OrderID]; ok {
+			continue
+		}
+		unknown = append(unknown, v)
+	}
+	return
+}
@@ -0,0 +1,137 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ztrade/exchange"
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+// fakeImpl exchange which only supports cancel order
+type fakeImpl struct {
+	exchange.Exchange
+	canceled []string
+}
+
+func (f *fakeImpl) Info() exchange.ExchangeInfo {
+	return exchange.ExchangeInfo{Name: "fake"}
+}
+
+func (f *fakeImpl) CancelOrder(old *Order) (*Order, error) {
+	f.canceled = append(f.canceled, old.OrderID)
+	return old, nil
+}
+
+type fakeQuerier struct {
+	orders    map[string]*Order
+	opens     []*Order
+	positions []Position
+	fills     []Fill
+}
+
+func (q *fakeQuerier) GetOrder(order *Order) (*Order, error) {
+	return q.orders[order.OrderID], nil
+}
+
+func (q *fakeQuerier) OpenOrders(symbol string) ([]*Order, error) {
+	return q.opens, nil
+}
+
+func (q *fakeQuerier) Positions(symbol string) ([]Position, error) {
+	return q.positions, nil
+}
+
+func (q *fakeQuerier) Fills(symbol string, since time.Time) (fills []Fill, err error) {
+	for _, v := range q.fills {
+		if !v.Time.Before(since) {
+			fills = append(fills, v)
+		}
+	}
+	return
+}
+
+func TestReconcile(t *testing.T) {
+	impl := &fakeImpl{}
+	te := NewTradeExchange("fake", impl, "BTCUSDT")
+	te.SetReconcileInterval(time.Minute)
+	te.SetCancelUnknown(true)
+	bus := NewSyncBus()
+	err := te.Init(bus)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var notifies []string
+	recv := NewBaseProcesser("recv")
+	recv.Init(bus)
+	SubscribeData(recv, EventNotify, func(e *Event, n *NotifyEvent) error {
+		notifies = append(notifies, n.Content)
+		return nil
+	})
+
+	now := time.Now()
+	for _, v := range []string{"filled", "canceled", "open"} {
+		te.orders[v] = &OrderInfo{LocalID: "local-" + v, Order: Order{OrderID: v, Symbol: "BTCUSDT", Amount: 2, Price: 100, Time: now.Add(-time.Hour)}}
+	}
+	te.pos.Hold = 1
+	q := &fakeQuerier{
+		orders: map[string]*Order{
+			"canceled": {OrderID: "canceled", Status: OrderStatusCanceled},
+			"open":     {OrderID: "open", Status: "NEW"},
+		},
+		opens: []*Order{
+			{OrderID: "open", Time: now.Add(-time.Hour)},
+			{OrderID: "manual", Side: "buy", Amount: 1, Price: 90, Time: now.Add(-time.Hour)},
+			// may be sent just now and not recorded yet
+			{OrderID: "new", Time: now.Add(time.Hour)},
+		},
+		positions: []Position{{Symbol: "BTCUSDT", Hold: 3}},
+		fills: []Fill{
+			{OrderID: "filled", Price: 100, Amount: 1, Time: now.Add(-time.Minute * 50)},
+			{OrderID: "filled", Price: 102, Amount: 1, Time: now.Add(-time.Minute * 40)},
+			{OrderID: "open", Price: 100, Amount: 1, Time: now.Add(-time.Minute * 40)},
+			{OrderID: "manual-fill", Price: 100, Amount: 1, Time: now.Add(-time.Second)},
+		},
+	}
+	te.reconcile(q)
+
+	if len(te.datas) != 3 {
+		t.Fatalf("synthesized datas error: %d", len(te.datas))
+	}
+	od := (<-te.datas).(*Order)
+	if od.OrderID != "filled" || od.Status != OrderStatusFilled || od.Price != 101 || od.Filled != 2 {
+		t.Fatalf("synthesized fill error: %#v", od)
+	}
+	od = (<-te.datas).(*Order)
+	if od.OrderID != "canceled" || od.Status != OrderStatusCanceled || od.Symbol != "BTCUSDT" {
+		t.Fatalf("synthesized cancel error: %#v", od)
+	}
+	pos := (<-te.datas).(*Position)
+	if pos.Hold != 3 {
+		t.Fatalf("synthesized position error: %#v", pos)
+	}
+	if len(impl.canceled) != 1 || impl.canceled[0] != "manual" {
+		t.Fatalf("unknown orders not canceled: %#v", impl.canceled)
+	}
+	if len(notifies) != 1 {
+		t.Fatalf("notify error: %#v", notifies)
+	}
+	for _, v := range []string{"order filled missed fill", "order canceled missed status", "unknown open order manual", "unknown fill of order manual-fill", "position drift"} {
+		if !strings.Contains(notifies[0], v) {
+			t.Fatalf("drift %s not notified: %s", v, notifies[0])
+		}
+	}
+
+	// the fills reported are not reported again
+	q.opens = nil
+	te.pos.Hold = 3
+	te.orders["filled"].Filled = true
+	te.orders["canceled"].Canceled = true
+	te.reconcile(q)
+	if len(te.datas) != 0 || len(notifies) != 1 {
+		t.Fatalf("drifts reported again: %d %#v", len(te.datas), notifies)
+	}
+}
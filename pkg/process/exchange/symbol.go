@@ -0,0 +1,74 @@
+package exchange
+
+import (
+	"github.com/ztrade/exchange"
+	. "github.com/ztrade/ztrade/pkg/core"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SymbolLimitQuerier exchange which can query the limits of symbols, such as min qty and min notional,
+// which are not in the symbols of exchange
+type SymbolLimitQuerier interface {
+	SymbolLimits() (map[string]SymbolLimit, error)
+}
+
+// NewSymbolLimitQuerierFn create the SymbolLimitQuerier of exchange account
+type NewSymbolLimitQuerierFn func(cfg exchange.Config, cltName string) (SymbolLimitQuerier, error)
+
+var limitQueriers = map[string]NewSymbolLimitQuerierFn{}
+
+// RegisterSymbolLimitQuerier register the SymbolLimitQuerier of exchange type, used when the exchange doesn't implement it
+func RegisterSymbolLimitQuerier(typ string, fn NewSymbolLimitQuerierFn) {
+	limitQueriers[typ] = fn
+}
+
+// newSymbolLimitQuerier create the SymbolLimitQuerier of exchange account, nil if not supported
+func newSymbolLimitQuerier(typ string, impl exchange.Exchange, cfg exchange.Config, cltName string) (querier SymbolLimitQuerier, err error) {
+	querier, ok := impl.(SymbolLimitQuerier)
+	if ok {
+		return
+	}
+	fn, ok := limitQueriers[typ]
+	if !ok {
+		return
+	}
+	return fn(cfg, cltName)
+}
+
+// SetSymbolLimitQuerier set the querier used to fill the limits of symbol info
+func (b *TradeExchange) SetSymbolLimitQuerier(querier SymbolLimitQuerier) {
+	b.limitQuerier = querier
+}
+
+// GetSymbolInfos fetch the symbol infos of exchange, the limits are filled if the exchange type supports
+func GetSymbolInfos(typ string, impl exchange.Exchange, cfg exchange.Config, cltName string) (infos []*SymbolInfo, err error) {
+	querier, err := newSymbolLimitQuerier(typ, impl, cfg, cltName)
+	if err != nil {
+		log.Warnf("exchanges.%s create symbol limit querier failed: %s", cltName, err.Error())
+		err = nil
+	}
+	return newSymbolInfos(cltName, impl, querier)
+}
+
+// newSymbolInfos create symbol infos from the symbols of exchange and the limits of querier
+func newSymbolInfos(exchangeName string, impl exchange.Exchange, querier SymbolLimitQuerier) (infos []*SymbolInfo, err error) {
+	symbols, err := impl.Symbols()
+	if err != nil {
+		return
+	}
+	var limits map[string]SymbolLimit
+	if querier != nil {
+		limits, err = querier.SymbolLimits()
+		if err != nil {
+			log.Warnf("%s get symbol limits failed: %s, min qty and min notional are not checked", exchangeName, err.Error())
+			limits, err = nil, nil
+		}
+	}
+	for _, v := range symbols {
+		si := NewSymbolInfo(exchangeName, v)
+		si.SetLimit(limits[v.Symbol])
+		infos = append(infos, si)
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package exchange
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ztrade/exchange"
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// symbolImpl exchange which only supports symbols
+type symbolImpl struct {
+	exchange.Exchange
+	symbols []Symbol
+}
+
+func (f *symbolImpl) Symbols() ([]Symbol, error) {
+	return f.symbols, nil
+}
+
+type limitQuerier struct {
+	limits map[string]SymbolLimit
+	err    error
+}
+
+func (q *limitQuerier) SymbolLimits() (map[string]SymbolLimit, error) {
+	return q.limits, q.err
+}
+
+func TestSymbolInfos(t *testing.T) {
+	impl := &symbolImpl{symbols: []Symbol{
+		{Symbol: "BTCUSDT", Precision: 1, AmountPrecision: 3},
+		{Symbol: "ETHUSDT", Precision: 2, AmountPrecision: 3},
+	}}
+	q := &limitQuerier{limits: map[string]SymbolLimit{"BTCUSDT": {MinQty: 0.001, MinNotional: 100}}}
+	infos, err := newSymbolInfos("binance", impl, q)
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("symbol infos error: %v %d", err, len(infos))
+	}
+	if infos[0].MinQty != 0.001 || infos[0].MinNotional != 100 || infos[0].TickSize != 0.1 {
+		t.Fatalf("limits not filled: %#v", infos[0])
+	}
+	if infos[1].MinQty != 0 || infos[1].MinNotional != 0 || infos[1].LotSize != 0.001 {
+		t.Fatalf("limits of other symbol error: %#v", infos[1])
+	}
+	// the symbols are still usable if the limits failed
+	q.err = errors.New("network error")
+	infos, err = newSymbolInfos("binance", impl, q)
+	if err != nil || len(infos) != 2 || infos[0].MinQty != 0 {
+		t.Fatalf("symbol infos should ignore limit error: %v %d", err, len(infos))
+	}
+	infos, err = newSymbolInfos("binance", impl, nil)
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("symbol infos without querier error: %v %d", err, len(infos))
+	}
+}
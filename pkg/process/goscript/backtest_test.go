@@ -0,0 +1,225 @@
+package goscript
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ztrade/base/common"
+	bengine "github.com/ztrade/base/engine"
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+	"github.com/ztrade/ztrade/pkg/process/rpt"
+	"github.com/ztrade/ztrade/pkg/process/vex"
+	"github.com/ztrade/ztrade/pkg/report"
+)
+
+func init() {
+	engine.Register(".det", newDetRunner)
+}
+
+// detRunner strategy of test, it opens or closes long every period candles
+type detRunner struct {
+	name   string
+	period int
+	count  int
+	engine bengine.Engine
+}
+
+func newDetRunner(file string) (r engine.Runner, err error) {
+	period := 3
+	if strings.HasPrefix(filepath.Base(file), "b") {
+		period = 5
+	}
+	r = &detRunner{name: file, period: period}
+	return
+}
+
+func (r *detRunner) Param() (paramInfo []common.Param, err error) {
+	return
+}
+
+func (r *detRunner) Init(e bengine.Engine, params common.ParamData) (err error) {
+	r.engine = e
+	return
+}
+
+func (r *detRunner) OnCandle(candle *Candle) (err error) {
+	r.count++
+	if r.count%r.period != 0 {
+		return
+	}
+	pos, _ := r.engine.Position()
+	if pos > 0 {
+		r.engine.CloseLong(candle.Close, pos)
+	} else {
+		r.engine.OpenLong(candle.Close, 1)
+	}
+	return
+}
+
+func (r *detRunner) OnPosition(pos, price float64) (err error) { return }
+func (r *detRunner) OnTrade(trade *Trade) (err error)          { return }
+func (r *detRunner) OnTradeMarket(trade *Trade) (err error)    { return }
+func (r *detRunner) OnDepth(depth *Depth) (err error)          { return }
+func (r *detRunner) OnEvent(e *Event) (err error)              { return }
+func (r *detRunner) GetName() string                           { return r.name }
+
+// feeder send the candles and record the trades
+type feeder struct {
+	BaseProcesser
+	trades []string
+}
+
+func (f *feeder) Init(bus *Bus) (err error) {
+	f.BaseProcesser.Init(bus)
+	return SubscribeData(f, EventTrade, func(e *Event, tr *Trade) error {
+		f.trades = append(f.trades, tr.ID+"@"+tr.Time.UTC().Format(time.RFC3339))
+		return nil
+	})
+}
+
+func testCandles(n int) (candles []*Candle) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := 100.0
+	for i := 0; i < n; i++ {
+		price := 100 + 5*math.Sin(float64(i)/7) + 3*math.Sin(float64(i)/2.3)
+		candles = append(candles, &Candle{
+			Start:  start.Add(time.Minute * time.Duration(i)).Unix(),
+			Open:   last,
+			High:   math.Max(last, price) + 0.5,
+			Low:    math.Min(last, price) - 0.5,
+			Close:  price,
+			Volume: 1,
+		})
+		last = price
+	}
+	return
+}
+
+// runBacktest run the scripts with the candles, return the html report and trades
+func runBacktest(t *testing.T, candles []*Candle, seed int64) (html []byte, trades []string) {
+	f := &feeder{BaseProcesser: BaseProcesser{Name: "feeder"}}
+	ex := vex.NewVExchange("BTCUSDT")
+	err := ex.SetSpot(false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s, err := NewDefaultGoEngine()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s.SetSeed(seed)
+	// added in reverse order, the scripts are still called in the order of names
+	for _, v := range []string{"b.det", "a.det"} {
+		err = s.AddScript(v, v, "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	r := report.NewReportSimple()
+	r.SetSpot(false)
+	start, end := time.Unix(candles[0].Start, 0), time.Unix(candles[len(candles)-1].Start, 0)
+	r.SetTimeRange(start, end)
+	r.SetCandles(candles)
+
+	procs := NewSyncProcessers()
+	procs.SetClock(NewSimClock())
+	procs.Adds(f, ex, s, rpt.NewRpt(r))
+	err = procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	f.Send("balance_init", EventBalanceInit, &BalanceInfo{Balance: 100000})
+	for _, v := range candles {
+		c := *v
+		f.SendWithExtra("candle", EventCandle, &c, "1m")
+	}
+	procs.Stop()
+
+	fPath := filepath.Join(t.TempDir(), "report.html")
+	err = r.GenRPT(fPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	html, err = os.ReadFile(fPath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return html, f.trades
+}
+
+func TestReproducibleBacktest(t *testing.T) {
+	candles := testCandles(600)
+	html1, trades1 := runBacktest(t, candles, 42)
+	html2, trades2 := runBacktest(t, candles, 42)
+	if len(trades1) == 0 {
+		t.Fatal("no trades in backtest")
+	}
+	if len(trades1) != len(trades2) {
+		t.Fatalf("trades are different: %d, %d", len(trades1), len(trades2))
+	}
+	for i := range trades1 {
+		if trades1[i] != trades2[i] {
+			t.Fatalf("trade %d is different: %s, %s", i, trades1[i], trades2[i])
+		}
+	}
+	if !bytes.Equal(html1, html2) {
+		t.Fatal("reports of the same backtest are different")
+	}
+	_, trades3 := runBacktest(t, candles, 43)
+	if strings.Join(trades1, ",") == strings.Join(trades3, ",") {
+		t.Fatal("order ids don't change with seed")
+	}
+}
+
+func TestRemoveInCallback(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDefaultGoEngine()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, v := range []string{"a", "b", "c"} {
+		version := "v1"
+		if v == "a" {
+			version = "remove"
+		}
+		src := filepath.Join(dir, v+".rld")
+		err = os.WriteFile(src, []byte(version), 0644)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		err = s.AddScript(v, src, "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	runners := make(map[string]*reloadRunner)
+	for k, v := range s.vms {
+		runners[k] = v.Runner.(*reloadRunner)
+	}
+	f := NewBaseProcesser("feeder")
+	procs := NewSyncProcessers()
+	procs.Adds(f, s)
+	err = procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer procs.Stop()
+	for _, v := range testCandles(2) {
+		f.SendWithExtra("candle", EventCandle, v, "1m")
+	}
+	if _, ok := s.vms["a"]; ok || runners["a"].count != 1 {
+		t.Fatalf("script not removed: %d", runners["a"].count)
+	}
+	// the scripts after the removed one are not skipped
+	if runners["b"].count != 2 || runners["c"].count != 2 {
+		t.Fatalf("scripts skipped: %d %d", runners["b"].count, runners["c"].count)
+	}
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// AccountEngine virtual sub-account api, scripts get it by engine.(AccountEngine)
+// if sub-account is enabled, Position return the position opened by the script only
+type AccountEngine interface {
+	// SubAccount check if the sub-account is enabled
+	SubAccount() bool
+	// Realized realized pnl of the script, fee is not included
+	Realized() float64
+	// Fills number of the trades filled for the script
+	Fills() int
+}
+
+var _ AccountEngine = (*EngineWrapper)(nil)
+
+// SetSubAccount track the position of every script by the id of orders, must be called before start
+// trades are only sent to the script which sent the order
+func (e *EngineImpl) SetSubAccount(enable bool) {
+	e.subAccount = enable
+}
+
+func (e *EngineImpl) SubAccount() bool {
+	return e.subAccount
+}
+
+// UpdateAccount add the trade to the sub-account of script, return the position of it
+func (e *EngineImpl) UpdateAccount(vmID string, tr *Trade) (pos, price float64) {
+	e.accountsMutex.Lock()
+	defer e.accountsMutex.Unlock()
+	a, ok := e.accounts[vmID]
+	if !ok {
+		a = &PnL{Script: vmID}
+		e.accounts[vmID] = a
+	}
+	a.AddTrade(*tr, 0)
+	return a.Position, a.AvgPrice
+}
+
+// Account return the sub-account of script
+func (e *EngineImpl) Account(vmID string) (a PnL) {
+	e.accountsMutex.Lock()
+	defer e.accountsMutex.Unlock()
+	if v, ok := e.accounts[vmID]; ok {
+		a = *v
+	}
+	a.Script = vmID
+	return
+}
+
+// SetAccount replace the sub-account of script, used to restore it after restart
+func (e *EngineImpl) SetAccount(vmID string, a PnL) {
+	a.Script = vmID
+	e.accountsMutex.Lock()
+	e.accounts[vmID] = &a
+	e.accountsMutex.Unlock()
+}
+
+// Position return the position of script if sub-account enabled, else the position of account
+func (e *EngineWrapper) Position() (float64, float64) {
+	if !e.subAccount {
+		return e.EngineImpl.Position()
+	}
+	a := e.Account(e.VmID)
+	return a.Position, a.AvgPrice
+}
+
+func (e *EngineWrapper) Realized() float64 {
+	return e.Account(e.VmID).Realized
+}
+
+func (e *EngineWrapper) Fills() int {
+	return e.Account(e.VmID).Trades
+}
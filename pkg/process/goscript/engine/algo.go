@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// AlgoEngine execution algo api, scripts get it by engine.(AlgoEngine)
+type AlgoEngine interface {
+	// TWAP split order into slices evenly in duration
+	TWAP(typ TradeType, price, amount float64, duration time.Duration, slices int) string
+	// VWAP split order into slices following the history volume profile
+	VWAP(typ TradeType, price, amount float64, duration time.Duration, slices int) string
+	// Iceberg only show visible amount in orderbook
+	Iceberg(typ TradeType, price, amount, visible float64) string
+	// Peg keep order at best bid/ask, offset is the distance to the best price
+	Peg(typ TradeType, amount, offset float64) string
+}
+
+var _ AlgoEngine = (*EngineWrapper)(nil)
+
+func (e *EngineWrapper) TWAP(typ TradeType, price, amount float64, duration time.Duration, slices int) string {
+	return e.addAlgoOrder(AlgoOrder{Algo: AlgoTWAP, Duration: duration, Slices: slices}, typ, price, amount)
+}
+
+func (e *EngineWrapper) VWAP(typ TradeType, price, amount float64, duration time.Duration, slices int) string {
+	return e.addAlgoOrder(AlgoOrder{Algo: AlgoVWAP, Duration: duration, Slices: slices}, typ, price, amount)
+}
+
+func (e *EngineWrapper) Iceberg(typ TradeType, price, amount, visible float64) string {
+	return e.addAlgoOrder(AlgoOrder{Algo: AlgoIceberg, Visible: visible}, typ, price, amount)
+}
+
+func (e *EngineWrapper) Peg(typ TradeType, amount, offset float64) string {
+	return e.addAlgoOrder(AlgoOrder{Algo: AlgoPeg, Offset: offset}, typ, 0, amount)
+}
+
+func (e *EngineWrapper) addAlgoOrder(o AlgoOrder, typ TradeType, price, amount float64) (id string) {
+	if e.Disabled() {
+		return
+	}
+	id = fmt.Sprintf("%s-%s", e.VmID, e.actionID())
+	o.TradeAction = TradeAction{ID: id, Action: typ, Symbol: e.symbol, Amount: amount, Price: price, Time: e.Now()}
+	e.proc.Send(EventAlgoOrder, EventAlgoOrder, &o)
+	return
+}
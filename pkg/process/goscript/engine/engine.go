@@ -3,6 +3,7 @@ package engine
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -17,10 +18,10 @@ import (
 
 const letterBytes = "abcdefghijklmnopqrstuvwxyz123456789"
 
-func randStringBytes(n int) string {
+func randStringBytes(n int, intn func(n int) int) string {
 	b := make([]byte, n)
 	for i := range b {
-		b[i] = letterBytes[rand.Intn(len(letterBytes))]
+		b[i] = letterBytes[intn(len(letterBytes))]
 	}
 	return string(b)
 }
@@ -29,18 +30,33 @@ func init() {
 	rand.Seed(time.Now().Unix())
 }
 
-func getActionID() string {
-	return randStringBytes(8)
-}
-
 type EngineImpl struct {
 	proc        *BaseProcesser
 	pos         float64
 	posPrice    float64
 	balance     float64
 	merges      map[string][]*KlinePlugin
+	feeds       map[string][]*indicatorFeed
 	mergesMutex sync.Mutex
+	history     HistoryFn
+	curTime     time.Time
 	symbol      string
+	venues      map[string]*venueInfo
+	venuesMutex sync.Mutex
+	symbolInfo  SymbolInfo
+	// lever of risk limit, protected by symbolMutex
+	lever       float64
+	symbolMutex sync.RWMutex
+	// virtual sub-accounts of scripts
+	subAccount    bool
+	accounts      map[string]*PnL
+	accountsMutex sync.Mutex
+	// timers of scripts, the key is vmID
+	timers      map[string]map[string]*scriptTimer
+	timersMutex sync.Mutex
+	// rand of order ids, the global rand is used if no seed set
+	rand      *rand.Rand
+	randMutex sync.Mutex
 }
 
 type UpdateStatusFn func(vm string, status int, msg string)
@@ -48,13 +64,21 @@ type EngineWrapper struct {
 	*EngineImpl
 	VmID string
 	Cb   UpdateStatusFn
+	// set when the script is stopped by sandbox
+	disabled int32
 }
 
 func (e *EngineWrapper) UpdateStatus(status int, msg string) {
+	if e.Disabled() {
+		return
+	}
 	e.Cb(e.VmID, status, msg)
 }
 
 func (e *EngineWrapper) Merge(src, dst string, fn common.CandleFn) {
+	if e.Disabled() {
+		return
+	}
 	e.EngineImpl.Merge(e.VmID, src, dst, fn)
 }
 
@@ -69,11 +93,32 @@ func NewEngineWrapper(proc *BaseProcesser, cb UpdateStatusFn, symbol string, id
 func NewEngineImpl(proc *BaseProcesser, symbol string) *EngineImpl {
 	e := new(EngineImpl)
 	e.merges = make(map[string][]*KlinePlugin)
+	e.feeds = make(map[string][]*indicatorFeed)
+	e.venues = make(map[string]*venueInfo)
+	e.accounts = make(map[string]*PnL)
+	e.timers = make(map[string]map[string]*scriptTimer)
 	e.symbol = symbol
 	e.proc = proc
 	return e
 }
 
+// SetSeed generate the order ids by seed, the ids are the same in every run with the same seed
+func (e *EngineImpl) SetSeed(seed int64) {
+	e.randMutex.Lock()
+	e.rand = rand.New(rand.NewSource(seed))
+	e.randMutex.Unlock()
+}
+
+// actionID return the random part of order id
+func (e *EngineImpl) actionID() string {
+	e.randMutex.Lock()
+	defer e.randMutex.Unlock()
+	if e.rand == nil {
+		return randStringBytes(8, rand.Intn)
+	}
+	return randStringBytes(8, e.rand.Intn)
+}
+
 func (e *EngineWrapper) OpenLong(price, amount float64) string {
 	return e.addOrder(price, amount, OpenLong)
 }
@@ -101,10 +146,25 @@ func (e *EngineImpl) CancelAllOrder() {
 	e.proc.Send(EventOrder, EventOrder, &TradeAction{Action: CancelAll})
 }
 
+// CancelAllOrder cancel all the orders of symbol, not only the orders of the script
+func (e *EngineWrapper) CancelAllOrder() {
+	if e.Disabled() {
+		return
+	}
+	e.EngineImpl.CancelAllOrder()
+}
+
 func (e *EngineImpl) CancelOrder(id string) {
 	e.proc.Send(EventOrder, EventOrder, &TradeAction{Action: CancelOne, ID: id})
 }
 
+func (e *EngineWrapper) CancelOrder(id string) {
+	if e.Disabled() {
+		return
+	}
+	e.EngineImpl.CancelOrder(id)
+}
+
 func (e *EngineImpl) AddIndicator(name string, params ...int) (ind indicator.CommonIndicator) {
 	var err error
 	ind, err = indicator.NewCommonIndicator(name, params...)
@@ -128,9 +188,11 @@ func (e *EngineImpl) Log(v ...interface{}) {
 }
 
 func (e *EngineWrapper) addOrder(price, amount float64, orderType TradeType) (id string) {
-	// FixMe: in backtest, time may be the time of candle
-	id = fmt.Sprintf("%s-%s", e.VmID, getActionID())
-	act := TradeAction{ID: id, Action: orderType, Symbol: e.symbol, Amount: amount, Price: price, Time: time.Now()}
+	if e.Disabled() {
+		return
+	}
+	id = fmt.Sprintf("%s-%s", e.VmID, e.actionID())
+	act := TradeAction{ID: id, Action: orderType, Symbol: e.symbol, Amount: amount, Price: price, Time: e.Now()}
 	e.proc.Send(EventOrder, EventOrder, &act)
 	return
 }
@@ -140,6 +202,13 @@ func (e *EngineImpl) Watch(watchType string) {
 	e.proc.Send(EventWatch, EventWatch, &param)
 }
 
+func (e *EngineWrapper) Watch(watchType string) {
+	if e.Disabled() {
+		return
+	}
+	e.EngineImpl.Watch(watchType)
+}
+
 func (e *EngineImpl) SendNotify(title, content, contentType string) {
 	if contentType == "" {
 		contentType = "text"
@@ -148,8 +217,22 @@ func (e *EngineImpl) SendNotify(title, content, contentType string) {
 	e.proc.Send("notify", EventNotify, &data)
 }
 
+func (e *EngineWrapper) SendNotify(title, content, contentType string) {
+	if e.Disabled() {
+		return
+	}
+	e.EngineImpl.SendNotify(title, content, contentType)
+}
+
 func (e *EngineImpl) SetBalance(balance float64) {
-	e.proc.Send("balance", "init_balance", map[string]interface{}{"balance": balance})
+	e.proc.Send("balance", EventScriptBalance, &BalanceInfo{Balance: balance})
+}
+
+func (e *EngineWrapper) SetBalance(balance float64) {
+	if e.Disabled() {
+		return
+	}
+	e.EngineImpl.SetBalance(balance)
 }
 
 func (e *EngineImpl) Balance() (balance float64) {
@@ -170,13 +253,65 @@ func (e *EngineImpl) Merge(vmID, src, dst string, fn common.CandleFn) {
 
 func (e *EngineImpl) RemoveMerge(vmID string) {
 	e.mergesMutex.Lock()
-	defer e.mergesMutex.Unlock()
 	delete(e.merges, vmID)
+	delete(e.feeds, vmID)
+	e.mergesMutex.Unlock()
+	e.timersMutex.Lock()
+	delete(e.timers, vmID)
+	e.timersMutex.Unlock()
+}
+
+// ScriptMerges the merges, indicators and timers of one vm
+type ScriptMerges struct {
+	merges []*KlinePlugin
+	feeds  []*indicatorFeed
+	timers map[string]*scriptTimer
+}
+
+// TakeMerges remove and return the merges, indicators and timers of vm
+func (e *EngineImpl) TakeMerges(vmID string) (ms ScriptMerges) {
+	e.mergesMutex.Lock()
+	ms.merges = e.merges[vmID]
+	ms.feeds = e.feeds[vmID]
+	delete(e.merges, vmID)
+	delete(e.feeds, vmID)
+	e.mergesMutex.Unlock()
+	e.timersMutex.Lock()
+	ms.timers = e.timers[vmID]
+	delete(e.timers, vmID)
+	e.timersMutex.Unlock()
+	return
+}
+
+// SetMerges replace the merges, indicators and timers of vm
+func (e *EngineImpl) SetMerges(vmID string, ms ScriptMerges) {
+	e.mergesMutex.Lock()
+	delete(e.merges, vmID)
+	delete(e.feeds, vmID)
+	if len(ms.merges) != 0 {
+		e.merges[vmID] = ms.merges
+	}
+	if len(ms.feeds) != 0 {
+		e.feeds[vmID] = ms.feeds
+	}
+	e.mergesMutex.Unlock()
+	e.timersMutex.Lock()
+	delete(e.timers, vmID)
+	if len(ms.timers) != 0 {
+		e.timers[vmID] = ms.timers
+	}
+	e.timersMutex.Unlock()
 }
 
 func (e *EngineImpl) OnCandle(candle *Candle) {
-	for _, kls := range e.merges {
-		for _, v := range kls {
+	// update in the order of scripts, so the merged candles are sent in the same order every time
+	ids := make([]string, 0, len(e.merges))
+	for k := range e.merges {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, v := range e.merges[id] {
 			v.Update(candle)
 		}
 	}
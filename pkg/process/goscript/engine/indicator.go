@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ztrade/base/common"
+	"github.com/ztrade/indicator"
+	. "github.com/ztrade/ztrade/pkg/core"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/ztrade/trademodel"
+)
+
+const (
+	// binSize of the candles sent to scripts
+	baseBinSize = "1m"
+
+	minWarmupBars = 50
+	maxWarmupBars = 500
+)
+
+// IndicatorEngine indicator api, get it by engine.(IndicatorEngine)
+type IndicatorEngine interface {
+	// Indicator create indicator updated by the candles of binSize before OnCandle, "" means the candles of script
+	// it's warmed up with the history candles before the first candle
+	Indicator(binSize, name string, params ...int) indicator.CommonIndicator
+	// PlotIndicator draw all the values of indicator created by Indicator in report
+	PlotIndicator(label string, ind indicator.CommonIndicator)
+	// Plot draw value at the time of current candle in report
+	Plot(label string, value float64)
+}
+
+var _ IndicatorEngine = (*EngineWrapper)(nil)
+
+// HistoryFn load the history candles of script binSize in [start, end)
+type HistoryFn func(start, end time.Time) (candles []*Candle, err error)
+
+// indicatorFeed update one indicator with the candles of binSize
+type indicatorFeed struct {
+	vmID    string
+	binSize string
+	bars    int
+	kl      *common.KlineMerge
+	ind     indicator.CommonIndicator
+	updater CandleUpdater
+	label   string
+	// binSize, name and params of indicator
+	key    string
+	warmed bool
+	// start of the last base candle
+	last int64
+}
+
+func (f *indicatorFeed) update(e *EngineImpl, candle *Candle, plot bool) {
+	if candle.Start <= f.last {
+		return
+	}
+	f.last = candle.Start
+	if f.kl != nil {
+		ret := f.kl.Update(candle)
+		if ret == nil {
+			return
+		}
+		candle = ret.(*Candle)
+	}
+	if f.updater != nil {
+		f.updater.UpdateCandle(candle)
+	} else {
+		f.ind.Update(candle.Close)
+	}
+	if plot && f.label != "" {
+		t := time.Unix(candle.Start, 0)
+		values := f.ind.Indicator()
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			e.plot(f.vmID, fmt.Sprintf("%s.%s", f.label, k), t, values[k])
+		}
+	}
+}
+
+// warmUp feed the history candles before the first candle
+func (f *indicatorFeed) warmUp(e *EngineImpl, first *Candle) {
+	f.warmed = true
+	if e.history == nil {
+		return
+	}
+	dur, err := common.GetBinSizeDuration(f.binSize)
+	if err != nil {
+		return
+	}
+	end := time.Unix(first.Start, 0)
+	candles, err := e.history(end.Add(-dur*time.Duration(f.bars)), end)
+	if err != nil {
+		log.Warnf("indicator %s warm up failed: %s", f.vmID, err.Error())
+		return
+	}
+	for _, v := range candles {
+		f.update(e, v, false)
+	}
+}
+
+// SetHistory set the loader of history candles, indicators are not warmed up if not set
+func (e *EngineImpl) SetHistory(fn HistoryFn) {
+	e.history = fn
+}
+
+// UpdateIndicators feed the candle to indicators, must be called before OnCandle of scripts
+func (e *EngineImpl) UpdateIndicators(candle *Candle) {
+	e.mergesMutex.Lock()
+	// update in the order of scripts, the plots are sent in the same order every time
+	vmIDs := make([]string, 0, len(e.feeds))
+	for k := range e.feeds {
+		vmIDs = append(vmIDs, k)
+	}
+	sort.Strings(vmIDs)
+	var feeds []*indicatorFeed
+	for _, k := range vmIDs {
+		feeds = append(feeds, e.feeds[k]...)
+	}
+	e.curTime = time.Unix(candle.Start, 0)
+	e.mergesMutex.Unlock()
+	for _, v := range feeds {
+		if !v.warmed {
+			v.warmUp(e, candle)
+		}
+		v.update(e, candle, candle.ID != -1)
+	}
+}
+
+func (e *EngineImpl) plot(vmID, label string, t time.Time, value float64) {
+	e.proc.Send(EventPlot, EventPlot, &PlotData{Script: vmID, Name: label, Time: t, Value: value})
+}
+
+func (e *EngineWrapper) Indicator(binSize, name string, params ...int) indicator.CommonIndicator {
+	ind, err := indicator.NewCommonIndicator(name, params...)
+	if err != nil {
+		log.Errorf("script %s create indicator %s %v failed: %s", e.VmID, name, params, err.Error())
+		return nil
+	}
+	// the indicator of disabled script is not updated
+	if e.Disabled() {
+		return ind
+	}
+	f := &indicatorFeed{vmID: e.VmID, binSize: binSize, ind: ind}
+	if f.binSize == "" {
+		f.binSize = baseBinSize
+	}
+	f.key = feedKey(f.binSize, name, params)
+	if f.binSize != baseBinSize {
+		f.kl = common.NewKlineMergeStr(baseBinSize, f.binSize)
+	}
+	f.updater, _ = unwrapIndicator(ind).(CandleUpdater)
+	f.bars = minWarmupBars
+	for _, v := range params {
+		f.bars = max(f.bars, v*4)
+	}
+	f.bars = min(f.bars, maxWarmupBars)
+	e.mergesMutex.Lock()
+	e.feeds[e.VmID] = append(e.feeds[e.VmID], f)
+	e.mergesMutex.Unlock()
+	return ind
+}
+
+func feedKey(binSize, name string, params []int) string {
+	return fmt.Sprintf("%s/%s/%v", binSize, strings.ToUpper(name), params)
+}
+
+// findIndicator return the indicator created by Indicator with the same args
+func (e *EngineWrapper) findIndicator(binSize, name string, params ...int) indicator.CommonIndicator {
+	if binSize == "" {
+		binSize = baseBinSize
+	}
+	key := feedKey(binSize, name, params)
+	e.mergesMutex.Lock()
+	defer e.mergesMutex.Unlock()
+	for _, v := range e.feeds[e.VmID] {
+		if v.key == key {
+			return v.ind
+		}
+	}
+	return nil
+}
+
+func (e *EngineWrapper) PlotIndicator(label string, ind indicator.CommonIndicator) {
+	if e.Disabled() {
+		return
+	}
+	e.mergesMutex.Lock()
+	defer e.mergesMutex.Unlock()
+	for _, v := range e.feeds[e.VmID] {
+		if v.ind == ind {
+			v.label = label
+			return
+		}
+	}
+	log.Errorf("script %s PlotIndicator %s: indicator not created by Indicator", e.VmID, label)
+}
+
+func (e *EngineWrapper) Plot(label string, value float64) {
+	if e.Disabled() {
+		return
+	}
+	e.mergesMutex.Lock()
+	t := e.curTime
+	e.mergesMutex.Unlock()
+	e.plot(e.VmID, label, t, value)
+}
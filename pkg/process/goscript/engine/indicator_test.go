@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ztrade/indicator"
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+func TestIndicatorPlotOrder(t *testing.T) {
+	bus := NewSyncBus()
+	proc := NewBaseProcesser("test")
+	proc.Init(bus)
+	var plots []PlotData
+	SubscribeData(proc, EventPlot, func(e *Event, data *PlotData) error {
+		plots = append(plots, *data)
+		return nil
+	})
+	e := NewEngineImpl(proc, "BTCUSDT")
+	for _, v := range []string{"c", "a", "b"} {
+		w := &EngineWrapper{EngineImpl: e, VmID: v}
+		w.PlotIndicator("st", w.Indicator("", "SUPERTREND", 2, 1))
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		price := float64(100 + i)
+		e.UpdateIndicators(&Candle{Start: start.Add(time.Minute * time.Duration(i)).Unix(), Open: price, High: price + 1, Low: price - 1, Close: price})
+	}
+	if len(plots) == 0 || len(plots)%3 != 0 {
+		t.Fatalf("plots error: %d", len(plots))
+	}
+	// the same order of scripts and values for every candle
+	n := len(plots) / 3
+	for i, v := range plots {
+		expect := plots[i%n]
+		if v.Script != expect.Script || v.Name != expect.Name {
+			t.Fatalf("plot %d order error: %s.%s, expect: %s.%s", i, v.Script, v.Name, expect.Script, expect.Name)
+		}
+	}
+	if plots[0].Script != "a" || plots[n-1].Script != "c" {
+		t.Fatalf("plots not sorted by script: %#v", plots[:n])
+	}
+}
+
+func TestIndicatorParams(t *testing.T) {
+	for _, v := range []string{"ATR", "SUPERTREND", "ICHIMOKU", "STDDEV"} {
+		_, err := indicator.NewCommonIndicator(v)
+		if err == nil {
+			t.Errorf("%s without params should fail", v)
+		}
+	}
+}
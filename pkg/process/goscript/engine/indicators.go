@@ -0,0 +1,304 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ztrade/indicator"
+	. "github.com/ztrade/trademodel"
+)
+
+// CandleUpdater indicator which need the high and low of candle
+// it's updated by UpdateCandle instead of Update when fed by engine
+type CandleUpdater interface {
+	UpdateCandle(candle *Candle)
+}
+
+func init() {
+	indicator.RegisterIndicator("ATR", newATRIndicator)
+	indicator.RegisterIndicator("SUPERTREND", newSupertrendIndicator)
+	indicator.RegisterIndicator("ICHIMOKU", newIchimokuIndicator)
+	indicator.RegisterIndicator("STDDEV", newStdDevIndicator)
+}
+
+func newATRIndicator(params ...int) (ind indicator.CommonIndicator, err error) {
+	if len(params) < 1 {
+		err = fmt.Errorf("ATR params not enough")
+		return
+	}
+	if params[0] <= 0 {
+		err = fmt.Errorf("ATR period must be positive")
+		return
+	}
+	ind = NewATR(params[0])
+	return
+}
+
+// newSupertrendIndicator params: period, multiplier and the optional divisor of multiplier,
+// the params are int, so the multiplier 2.5 is 25, 10
+func newSupertrendIndicator(params ...int) (ind indicator.CommonIndicator, err error) {
+	if len(params) < 2 {
+		err = fmt.Errorf("SUPERTREND params not enough")
+		return
+	}
+	if params[0] <= 0 {
+		err = fmt.Errorf("SUPERTREND period must be positive")
+		return
+	}
+	mult := float64(params[1])
+	if len(params) > 2 {
+		if params[2] <= 0 {
+			err = fmt.Errorf("SUPERTREND divisor of multiplier must be positive")
+			return
+		}
+		mult /= float64(params[2])
+	}
+	ind = NewSupertrend(params[0], mult)
+	return
+}
+
+func newIchimokuIndicator(params ...int) (ind indicator.CommonIndicator, err error) {
+	if len(params) < 3 {
+		err = fmt.Errorf("ICHIMOKU params not enough")
+		return
+	}
+	for _, v := range params[:3] {
+		if v <= 0 {
+			err = fmt.Errorf("ICHIMOKU periods must be positive")
+			return
+		}
+	}
+	ind = NewIchimoku(params[0], params[1], params[2])
+	return
+}
+
+func newStdDevIndicator(params ...int) (ind indicator.CommonIndicator, err error) {
+	if len(params) < 1 {
+		err = fmt.Errorf("STDDEV params not enough")
+		return
+	}
+	if params[0] <= 1 {
+		err = fmt.Errorf("STDDEV period must be greater than 1")
+		return
+	}
+	ind = NewStdDev(params[0])
+	return
+}
+
+// unwrapIndicator return the indicator wrapped by NewCommonIndicator
+func unwrapIndicator(ind indicator.CommonIndicator) indicator.CommonIndicator {
+	if j, ok := ind.(*indicator.JsonIndicator); ok {
+		return j.CommonIndicator
+	}
+	return ind
+}
+
+// ATR average true range with wilder's smoothing
+type ATR struct {
+	winLen    int
+	n         int
+	prevClose float64
+	sum       float64
+	result    float64
+}
+
+func NewATR(winLen int) *ATR {
+	return &ATR{winLen: winLen}
+}
+
+func (a *ATR) Update(price float64) {
+	a.UpdateCandle(&Candle{High: price, Low: price, Close: price})
+}
+
+func (a *ATR) UpdateCandle(candle *Candle) {
+	tr := candle.High - candle.Low
+	if a.n > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(candle.High-a.prevClose), math.Abs(candle.Low-a.prevClose)))
+	}
+	a.prevClose = candle.Close
+	a.n++
+	if a.n <= a.winLen {
+		a.sum += tr
+		a.result = a.sum / float64(a.n)
+		return
+	}
+	a.result = (a.result*float64(a.winLen-1) + tr) / float64(a.winLen)
+}
+
+func (a *ATR) Result() float64 {
+	return a.result
+}
+
+func (a *ATR) Indicator() map[string]float64 {
+	return map[string]float64{"result": a.result}
+}
+
+// Supertrend trend following line, below the price in up trend and above the price in down trend
+type Supertrend struct {
+	atr       *ATR
+	mult      float64
+	n         int
+	prevClose float64
+	upper     float64
+	lower     float64
+	trend     float64
+}
+
+func NewSupertrend(winLen int, mult float64) *Supertrend {
+	return &Supertrend{atr: NewATR(winLen), mult: mult, trend: 1}
+}
+
+func (s *Supertrend) Update(price float64) {
+	s.UpdateCandle(&Candle{High: price, Low: price, Close: price})
+}
+
+func (s *Supertrend) UpdateCandle(candle *Candle) {
+	s.atr.UpdateCandle(candle)
+	mid := (candle.High + candle.Low) / 2
+	upper := mid + s.mult*s.atr.Result()
+	lower := mid - s.mult*s.atr.Result()
+	if s.n > 0 {
+		if upper > s.upper && s.prevClose <= s.upper {
+			upper = s.upper
+		}
+		if lower < s.lower && s.prevClose >= s.lower {
+			lower = s.lower
+		}
+		if s.trend > 0 && candle.Close < lower {
+			s.trend = -1
+		} else if s.trend < 0 && candle.Close > upper {
+			s.trend = 1
+		}
+	}
+	s.upper = upper
+	s.lower = lower
+	s.prevClose = candle.Close
+	s.n++
+}
+
+// Result the supertrend line
+func (s *Supertrend) Result() float64 {
+	if s.trend > 0 {
+		return s.lower
+	}
+	return s.upper
+}
+
+// Trend 1 means up trend, -1 means down trend
+func (s *Supertrend) Trend() float64 {
+	return s.trend
+}
+
+func (s *Supertrend) Indicator() map[string]float64 {
+	return map[string]float64{"result": s.Result(), "trend": s.trend, "upper": s.upper, "lower": s.lower}
+}
+
+// Ichimoku ichimoku kinko hyo, cloudA and cloudB are the spans of the cloud at current candle
+type Ichimoku struct {
+	tenkanLen int
+	kijunLen  int
+	senkouLen int
+	highs     []float64
+	lows      []float64
+	spanAs    []float64
+	spanBs    []float64
+	tenkan    float64
+	kijun     float64
+	spanA     float64
+	spanB     float64
+	cloudA    float64
+	cloudB    float64
+	close     float64
+}
+
+func NewIchimoku(tenkan, kijun, senkou int) *Ichimoku {
+	return &Ichimoku{tenkanLen: tenkan, kijunLen: kijun, senkouLen: senkou}
+}
+
+func (ic *Ichimoku) Update(price float64) {
+	ic.UpdateCandle(&Candle{High: price, Low: price, Close: price})
+}
+
+func (ic *Ichimoku) UpdateCandle(candle *Candle) {
+	maxLen := max(ic.tenkanLen, ic.kijunLen, ic.senkouLen)
+	ic.highs = appendWindow(ic.highs, candle.High, maxLen)
+	ic.lows = appendWindow(ic.lows, candle.Low, maxLen)
+	ic.close = candle.Close
+	ic.tenkan = ic.middle(ic.tenkanLen)
+	ic.kijun = ic.middle(ic.kijunLen)
+	ic.spanA = (ic.tenkan + ic.kijun) / 2
+	ic.spanB = ic.middle(ic.senkouLen)
+	// the spans are shifted forward kijun periods
+	ic.spanAs = appendWindow(ic.spanAs, ic.spanA, ic.kijunLen+1)
+	ic.spanBs = appendWindow(ic.spanBs, ic.spanB, ic.kijunLen+1)
+	ic.cloudA = ic.spanAs[0]
+	ic.cloudB = ic.spanBs[0]
+}
+
+func (ic *Ichimoku) middle(n int) float64 {
+	start := max(len(ic.highs)-n, 0)
+	high, low := ic.highs[start], ic.lows[start]
+	for i := start + 1; i < len(ic.highs); i++ {
+		high = math.Max(high, ic.highs[i])
+		low = math.Min(low, ic.lows[i])
+	}
+	return (high + low) / 2
+}
+
+// Result the kijun line
+func (ic *Ichimoku) Result() float64 {
+	return ic.kijun
+}
+
+func (ic *Ichimoku) Indicator() map[string]float64 {
+	return map[string]float64{
+		"result": ic.kijun,
+		"tenkan": ic.tenkan,
+		"kijun":  ic.kijun,
+		"spanA":  ic.spanA,
+		"spanB":  ic.spanB,
+		"cloudA": ic.cloudA,
+		"cloudB": ic.cloudB,
+		"chikou": ic.close,
+	}
+}
+
+// StdDev standard deviation of the close prices
+type StdDev struct {
+	winLen int
+	values []float64
+	result float64
+}
+
+func NewStdDev(winLen int) *StdDev {
+	return &StdDev{winLen: winLen}
+}
+
+func (s *StdDev) Update(price float64) {
+	s.values = appendWindow(s.values, price, s.winLen)
+	var mean, sum float64
+	for _, v := range s.values {
+		mean += v
+	}
+	mean /= float64(len(s.values))
+	for _, v := range s.values {
+		sum += (v - mean) * (v - mean)
+	}
+	s.result = math.Sqrt(sum / float64(len(s.values)))
+}
+
+func (s *StdDev) Result() float64 {
+	return s.result
+}
+
+func (s *StdDev) Indicator() map[string]float64 {
+	return map[string]float64{"result": s.result}
+}
+
+func appendWindow(values []float64, v float64, n int) []float64 {
+	values = append(values, v)
+	if len(values) > n {
+		values = values[len(values)-n:]
+	}
+	return values
+}
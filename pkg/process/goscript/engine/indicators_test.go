@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ztrade/indicator"
+	. "github.com/ztrade/trademodel"
+)
+
+func TestATR(t *testing.T) {
+	atr := NewATR(2)
+	atr.UpdateCandle(&Candle{High: 11, Low: 9, Close: 10})
+	atr.UpdateCandle(&Candle{High: 14, Low: 12, Close: 13})
+	// tr: 2, 4
+	if atr.Result() != 3 {
+		t.Fatalf("atr error: %f", atr.Result())
+	}
+	atr.UpdateCandle(&Candle{High: 13, Low: 13, Close: 13})
+	// tr: 0, smoothed (3*1+0)/2
+	if atr.Result() != 1.5 {
+		t.Fatalf("atr error: %f", atr.Result())
+	}
+}
+
+func TestSupertrend(t *testing.T) {
+	ind, err := indicator.NewCommonIndicator("supertrend", 3, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	st, ok := unwrapIndicator(ind).(*Supertrend)
+	if !ok {
+		t.Fatalf("unwrap error: %T", unwrapIndicator(ind))
+	}
+	price := 100.0
+	for i := 0; i < 10; i++ {
+		price += 1
+		st.UpdateCandle(&Candle{High: price + 1, Low: price - 1, Close: price})
+	}
+	if st.Trend() != 1 || st.Result() >= price {
+		t.Fatalf("up trend error: %v", st.Indicator())
+	}
+	for i := 0; i < 10; i++ {
+		price -= 3
+		st.UpdateCandle(&Candle{High: price + 1, Low: price - 1, Close: price})
+	}
+	if st.Trend() != -1 || st.Result() <= price {
+		t.Fatalf("down trend error: %v", st.Indicator())
+	}
+}
+
+func TestSupertrendMultiplier(t *testing.T) {
+	cases := []struct {
+		params []int
+		mult   float64
+	}{
+		{[]int{10, 3}, 3},
+		{[]int{10, 25, 10}, 2.5},
+		{[]int{10, 3, 2}, 1.5},
+	}
+	for _, v := range cases {
+		ind, err := newSupertrendIndicator(v.params...)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if st := ind.(*Supertrend); st.mult != v.mult {
+			t.Errorf("multiplier of %v error: %f", v.params, st.mult)
+		}
+	}
+	for _, v := range [][]int{{10}, {0, 3}, {10, 25, 0}} {
+		if _, err := newSupertrendIndicator(v...); err == nil {
+			t.Errorf("params %v should fail", v)
+		}
+	}
+}
+
+func TestIchimoku(t *testing.T) {
+	ic := NewIchimoku(2, 3, 4)
+	for i := 1; i <= 10; i++ {
+		v := float64(i)
+		ic.UpdateCandle(&Candle{High: v, Low: v, Close: v})
+	}
+	ret := ic.Indicator()
+	// tenkan: (10+9)/2, kijun: (10+8)/2, spanB: (10+7)/2
+	if ret["tenkan"] != 9.5 || ret["kijun"] != 9 || ret["spanB"] != 8.5 {
+		t.Fatalf("ichimoku error: %v", ret)
+	}
+	// spans of 3 candles before: tenkan 6.5, kijun 6
+	if math.Abs(ret["cloudA"]-6.25) > 1e-9 || ret["cloudB"] != 5.5 {
+		t.Fatalf("ichimoku cloud error: %v", ret)
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	sd := NewStdDev(4)
+	for _, v := range []float64{100, 2, 4, 4, 6} {
+		sd.Update(v)
+	}
+	// window 2,4,4,6: mean 4, variance 2
+	if math.Abs(sd.Result()-math.Sqrt(2)) > 1e-9 {
+		t.Fatalf("stddev error: %f", sd.Result())
+	}
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -14,6 +15,9 @@ type NewRunnerFn func(file string) (r Runner, err error)
 
 var (
 	factory = map[string]NewRunnerFn{}
+
+	// ErrNoSnapshot returned by Snapshoter when the strategy doesn't implement Snapshot/Restore
+	ErrNoSnapshot = errors.New("strategy not support snapshot")
 )
 
 func Register(ext string, fn NewRunnerFn) {
@@ -32,6 +36,19 @@ type Runner interface {
 	GetName() string
 }
 
+// Snapshoter runner which can save the state of strategy and restore it after reloaded
+// nil snapshot means the strategy has no state to hand over,
+// ErrNoSnapshot means the strategy doesn't support it, such as the wrappers of plugin and ixgo script
+type Snapshoter interface {
+	Snapshot() (data []byte, err error)
+	Restore(data []byte) (err error)
+}
+
+// TimerRunner runner which receive the timers of TimerEngine
+type TimerRunner interface {
+	OnTimer(id string) (err error)
+}
+
 func NewRunner(file string) (r Runner, err error) {
 	ext := filepath.Ext(file)
 	f, ok := factory[ext]
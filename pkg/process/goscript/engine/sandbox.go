@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	importsMutex   sync.RWMutex
+	allowedImports []string
+)
+
+// SetAllowedImports set the packages which interpreted scripts can import, empty means no limit
+// "net/..." allow net and all the packages under it
+func SetAllowedImports(pkgs []string) {
+	importsMutex.Lock()
+	allowedImports = pkgs
+	importsMutex.Unlock()
+}
+
+// ImportAllowed check if the script can import the package
+func ImportAllowed(path string) bool {
+	importsMutex.RLock()
+	defer importsMutex.RUnlock()
+	if len(allowedImports) == 0 {
+		return true
+	}
+	for _, v := range allowedImports {
+		if v == path {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(v, "/..."); ok && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Disable drop all the orders, events, merges, indicators and timers of the script later,
+// used when the script is stopped by sandbox but its callback may still be running
+func (e *EngineWrapper) Disable() {
+	atomic.StoreInt32(&e.disabled, 1)
+}
+
+// Disabled return if the script is disabled
+func (e *EngineWrapper) Disabled() bool {
+	return atomic.LoadInt32(&e.disabled) == 1
+}
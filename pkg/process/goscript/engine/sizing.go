@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"math"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// volatility kinds of SizingEngine.Volatility
+const (
+	VolATR    = "ATR"
+	VolStdDev = "STDDEV"
+)
+
+// SizingEngine position sizing api, scripts get it by engine.(SizingEngine)
+// the amounts are capped by MaxAmount and rounded down to the lot size, 0 means too small to order
+type SizingEngine interface {
+	// RiskAmount fixed fractional: lose risk ratio of balance if price moves to stop
+	RiskAmount(price, stop, risk float64) float64
+	// VolTargetAmount volatility targeting: the volatility of position value is target ratio of balance,
+	// vol is the volatility of price, such as the ATR
+	VolTargetAmount(price, vol, target float64) float64
+	// Volatility ATR or standard deviation of close of the n candles of binSize,
+	// it's updated by engine like Indicator, call it in Init to warm up
+	Volatility(kind, binSize string, n int) float64
+	// KellyAmount kelly criterion: winLoss is the ratio of average win to average loss,
+	// the kelly fraction is scaled by fraction (eg: 0.5 is half kelly) and capped by maxRatio of balance
+	KellyAmount(price, winRate, winLoss, fraction, maxRatio float64) float64
+	// MaxAmount max amount allowed by balance and the lever of risk limit, the lever is 1 if unknown
+	MaxAmount(price float64) float64
+}
+
+var _ SizingEngine = (*EngineWrapper)(nil)
+
+// UpdateRiskLimit update the lever used by MaxAmount, limits of other symbols are ignored
+func (e *EngineImpl) UpdateRiskLimit(rl *RiskLimit) {
+	if rl.Code != "" && rl.Code != e.symbol {
+		return
+	}
+	e.symbolMutex.Lock()
+	e.lever = rl.Lever
+	e.symbolMutex.Unlock()
+}
+
+func (e *EngineImpl) getLever() float64 {
+	e.symbolMutex.RLock()
+	defer e.symbolMutex.RUnlock()
+	if e.lever <= 0 {
+		return 1
+	}
+	return e.lever
+}
+
+// notional value of one amount at price
+func (e *EngineImpl) unitValue(price float64) float64 {
+	return price * e.Multiplier()
+}
+
+// sizeAmount cap the amount by MaxAmount and round it to the lot size
+func (e *EngineImpl) sizeAmount(price, amount float64) float64 {
+	if price <= 0 || amount <= 0 || math.IsNaN(amount) {
+		return 0
+	}
+	amount = math.Min(amount, e.Balance()*e.getLever()/e.unitValue(price))
+	// remove the float error before rounding down, eg: 1.9999999999 to 2
+	amount = math.Round(amount*1e9) / 1e9
+	si := e.getSymbolInfo()
+	amount = si.RoundAmount(amount)
+	if si.Check(price, amount) != nil {
+		return 0
+	}
+	return amount
+}
+
+func (e *EngineImpl) RiskAmount(price, stop, risk float64) float64 {
+	dist := math.Abs(price - stop)
+	if dist == 0 || price <= 0 {
+		return 0
+	}
+	return e.sizeAmount(price, e.Balance()*risk/e.unitValue(dist))
+}
+
+func (e *EngineImpl) VolTargetAmount(price, vol, target float64) float64 {
+	if vol <= 0 || price <= 0 {
+		return 0
+	}
+	return e.sizeAmount(price, e.Balance()*target/e.unitValue(vol))
+}
+
+func (e *EngineImpl) KellyAmount(price, winRate, winLoss, fraction, maxRatio float64) float64 {
+	if winLoss <= 0 || price <= 0 {
+		return 0
+	}
+	f := (winRate - (1-winRate)/winLoss) * fraction
+	if maxRatio > 0 {
+		f = math.Min(f, maxRatio)
+	}
+	if f <= 0 {
+		return 0
+	}
+	return e.sizeAmount(price, e.Balance()*f/e.unitValue(price))
+}
+
+func (e *EngineImpl) MaxAmount(price float64) float64 {
+	return e.sizeAmount(price, math.Inf(1))
+}
+
+func (e *EngineWrapper) Volatility(kind, binSize string, n int) float64 {
+	if kind != VolATR && kind != VolStdDev {
+		log.Errorf("script %s Volatility: unsupported kind %s", e.VmID, kind)
+		return 0
+	}
+	ind := e.findIndicator(binSize, kind, n)
+	if ind == nil {
+		ind = e.Indicator(binSize, kind, n)
+		if ind == nil {
+			return 0
+		}
+	}
+	return ind.Result()
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"testing"
+
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+func TestSizing(t *testing.T) {
+	e := NewEngineImpl(nil, "BTCUSDT")
+	e.UpdateBalance(10000)
+	e.UpdateSymbolInfo(&SymbolInfo{Symbol: "BTCUSDT", LotSize: 0.01, MinQty: 0.01})
+	// lose 1% of balance if price moves 50
+	if ret := e.RiskAmount(1000, 950, 0.01); ret != 2 {
+		t.Fatalf("risk amount error: %f", ret)
+	}
+	// capped by lever 1
+	if ret := e.RiskAmount(1000, 999, 0.01); ret != 10 {
+		t.Fatalf("risk amount cap error: %f", ret)
+	}
+	e.UpdateRiskLimit(&RiskLimit{Lever: 3})
+	if ret := e.MaxAmount(1000); ret != 30 {
+		t.Fatalf("max amount error: %f", ret)
+	}
+	e.UpdateRiskLimit(&RiskLimit{Code: "ETHUSDT", Lever: 10})
+	if ret := e.VolTargetAmount(1000, 30, 0.01); ret != 3.33 {
+		t.Fatalf("vol target amount error: %f", ret)
+	}
+	// kelly: 0.6 - 0.4/2 = 0.4, half is 0.2
+	if ret := e.KellyAmount(1000, 0.6, 2, 0.5, 0); ret != 2 {
+		t.Fatalf("kelly amount error: %f", ret)
+	}
+	if ret := e.KellyAmount(1000, 0.6, 2, 1, 0.25); ret != 2.5 {
+		t.Fatalf("kelly cap error: %f", ret)
+	}
+	if ret := e.KellyAmount(1000, 0.3, 1, 1, 0); ret != 0 {
+		t.Fatalf("negative kelly error: %f", ret)
+	}
+	if ret := e.RiskAmount(1000, 0, 0.00001); ret != 0 {
+		t.Fatalf("min qty error: %f", ret)
+	}
+}
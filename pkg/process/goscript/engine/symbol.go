@@ -0,0 +1,67 @@
+package engine
+
+import (
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// SymbolEngine symbol metadata api, all values are 0 if unknown
+// scripts get it by engine.(SymbolEngine)
+type SymbolEngine interface {
+	// TickSize min price change
+	TickSize() float64
+	// LotSize min amount change
+	LotSize() float64
+	MinQty() float64
+	MinNotional() float64
+	// Multiplier contract multiplier, 1 for spot
+	Multiplier() float64
+	// RoundPrice round price to the nearest tick
+	RoundPrice(price float64) float64
+	// RoundAmount round amount down to the lot size
+	RoundAmount(amount float64) float64
+}
+
+var _ SymbolEngine = (*EngineWrapper)(nil)
+
+func (e *EngineImpl) UpdateSymbolInfo(si *SymbolInfo) {
+	e.symbolMutex.Lock()
+	e.symbolInfo = *si
+	e.symbolMutex.Unlock()
+}
+
+func (e *EngineImpl) getSymbolInfo() SymbolInfo {
+	e.symbolMutex.RLock()
+	defer e.symbolMutex.RUnlock()
+	return e.symbolInfo
+}
+
+func (e *EngineImpl) TickSize() float64 {
+	return e.getSymbolInfo().TickSize
+}
+
+func (e *EngineImpl) LotSize() float64 {
+	return e.getSymbolInfo().LotSize
+}
+
+func (e *EngineImpl) MinQty() float64 {
+	return e.getSymbolInfo().MinQty
+}
+
+func (e *EngineImpl) MinNotional() float64 {
+	return e.getSymbolInfo().MinNotional
+}
+
+func (e *EngineImpl) Multiplier() float64 {
+	si := e.getSymbolInfo()
+	return si.GetMultiplier()
+}
+
+func (e *EngineImpl) RoundPrice(price float64) float64 {
+	si := e.getSymbolInfo()
+	return si.RoundPrice(price)
+}
+
+func (e *EngineImpl) RoundAmount(amount float64) float64 {
+	si := e.getSymbolInfo()
+	return si.RoundAmount(amount)
+}
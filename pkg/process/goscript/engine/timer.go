@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// TimerEngine timer api, scripts get it by engine.(TimerEngine)
+// the timers call OnTimer(id string) of script, they use the clock of processers, which is the time of candles in backtest
+type TimerEngine interface {
+	// After call OnTimer once after d, the timer of the same id is replaced
+	After(id string, d time.Duration)
+	// Cron call OnTimer at the times of the standard cron spec in UTC, eg: "0 0 * * 1" is 00:00 of every monday
+	// the timer of the same id is replaced
+	Cron(id, spec string) (err error)
+	// CancelTimer cancel the timer of id
+	CancelTimer(id string)
+	// Now current time, it's the end of current candle in backtest
+	Now() time.Time
+}
+
+var _ TimerEngine = (*EngineWrapper)(nil)
+
+// scriptTimer one timer of script, sched is nil if it only fires once
+// next is zero if the timer is added before the first candle in backtest, it's scheduled by the first candle
+type scriptTimer struct {
+	id    string
+	next  time.Time
+	delay time.Duration
+	sched cron.Schedule
+}
+
+// schedule set the next time of timer after now
+func (t *scriptTimer) schedule(now time.Time) {
+	if now.IsZero() {
+		return
+	}
+	if t.sched == nil {
+		t.next = now.Add(t.delay)
+		return
+	}
+	t.next = t.sched.Next(now.UTC())
+}
+
+// TimerCall the timer to fire
+type TimerCall struct {
+	VmID string
+	ID   string
+	Time time.Time
+}
+
+// Now current time of the clock of processers
+func (e *EngineImpl) Now() time.Time {
+	return e.proc.Now()
+}
+
+func (e *EngineImpl) addTimer(vmID string, t *scriptTimer) {
+	e.timersMutex.Lock()
+	defer e.timersMutex.Unlock()
+	timers, ok := e.timers[vmID]
+	if !ok {
+		timers = make(map[string]*scriptTimer)
+		e.timers[vmID] = timers
+	}
+	timers[t.id] = t
+}
+
+// DueTimers return the timers due at now in time order, the cron timers are scheduled to the next time after now
+// and the others are removed, so the missed times are skipped
+func (e *EngineImpl) DueTimers(now time.Time) (calls []TimerCall) {
+	e.timersMutex.Lock()
+	defer e.timersMutex.Unlock()
+	for vmID, timers := range e.timers {
+		for id, t := range timers {
+			if t.next.IsZero() {
+				t.schedule(now)
+				continue
+			}
+			if t.next.After(now) {
+				continue
+			}
+			calls = append(calls, TimerCall{VmID: vmID, ID: id, Time: t.next})
+			if t.sched == nil {
+				delete(timers, id)
+				continue
+			}
+			t.schedule(now)
+		}
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if !calls[i].Time.Equal(calls[j].Time) {
+			return calls[i].Time.Before(calls[j].Time)
+		}
+		if calls[i].VmID != calls[j].VmID {
+			return calls[i].VmID < calls[j].VmID
+		}
+		return calls[i].ID < calls[j].ID
+	})
+	return
+}
+
+func (e *EngineWrapper) After(id string, d time.Duration) {
+	if e.Disabled() {
+		return
+	}
+	t := &scriptTimer{id: id, delay: d}
+	t.schedule(e.Now())
+	e.addTimer(e.VmID, t)
+}
+
+func (e *EngineWrapper) Cron(id, spec string) (err error) {
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		err = fmt.Errorf("parse cron %s failed: %w", spec, err)
+		return
+	}
+	if e.Disabled() {
+		return
+	}
+	t := &scriptTimer{id: id, sched: sched}
+	t.schedule(e.Now())
+	e.addTimer(e.VmID, t)
+	return
+}
+
+func (e *EngineWrapper) CancelTimer(id string) {
+	if e.Disabled() {
+		return
+	}
+	e.timersMutex.Lock()
+	defer e.timersMutex.Unlock()
+	delete(e.timers[e.VmID], id)
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+func TestTimer(t *testing.T) {
+	clock := NewSimClock()
+	bus := NewSyncBus()
+	bus.SetClock(clock)
+	proc := NewBaseProcesser("test")
+	proc.Init(bus)
+	e := NewEngineImpl(proc, "BTCUSDT")
+	a := &EngineWrapper{EngineImpl: e, VmID: "a"}
+	b := &EngineWrapper{EngineImpl: e, VmID: "b"}
+	// added before the first candle, scheduled by the first candle
+	a.After("once", time.Minute*5)
+	err := b.Cron("hourly", "0 * * * *")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = b.Cron("bad", "* *"); err == nil {
+		t.Fatal("invalid cron spec should fail")
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var fired []TimerCall
+	for i := 1; i <= 130; i++ {
+		now := start.Add(time.Minute * time.Duration(i))
+		clock.Set(now)
+		fired = append(fired, e.DueTimers(now)...)
+	}
+	expect := []TimerCall{
+		{VmID: "a", ID: "once", Time: start.Add(time.Minute * 6)},
+		{VmID: "b", ID: "hourly", Time: start.Add(time.Hour)},
+		{VmID: "b", ID: "hourly", Time: start.Add(time.Hour * 2)},
+	}
+	if len(fired) != len(expect) {
+		t.Fatalf("fired timers error: %v", fired)
+	}
+	for i, v := range expect {
+		if fired[i].VmID != v.VmID || fired[i].ID != v.ID || !fired[i].Time.Equal(v.Time) {
+			t.Fatalf("timer %d error: %v, expect %v", i, fired[i], v)
+		}
+	}
+	b.CancelTimer("hourly")
+	if ret := e.DueTimers(start.Add(time.Hour * 5)); len(ret) != 0 {
+		t.Fatalf("canceled timer fired: %v", ret)
+	}
+	if !a.Now().Equal(start.Add(time.Minute * 130)) {
+		t.Fatalf("simulated time error: %s", a.Now())
+	}
+}
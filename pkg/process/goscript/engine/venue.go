@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+)
+
+// VenueEngine multi exchange api, venue is the exchange account name in config
+// scripts get it by engine.(VenueEngine)
+type VenueEngine interface {
+	// Venues return all the extra venues
+	Venues() []string
+	// DoOrderOn send order to the venue
+	DoOrderOn(venue string, typ TradeType, price, amount float64) string
+	// CancelOrderOn cancel order of the venue
+	CancelOrderOn(venue, id string)
+	// PositionOn return position of the venue, empty venue is the default one, zero if the venue is unknown
+	PositionOn(venue string) (pos, price float64)
+	// BalanceOn return balance of the venue, empty venue is the default one, zero if the venue is unknown
+	BalanceOn(venue string) float64
+}
+
+var _ VenueEngine = (*EngineWrapper)(nil)
+
+type venueInfo struct {
+	pos      float64
+	posPrice float64
+	balance  float64
+}
+
+// AddVenue add an extra exchange venue
+func (e *EngineImpl) AddVenue(venue string) {
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	if _, ok := e.venues[venue]; !ok {
+		e.venues[venue] = &venueInfo{}
+	}
+}
+
+// HasVenue check if venue is an extra venue
+func (e *EngineImpl) HasVenue(venue string) bool {
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	_, ok := e.venues[venue]
+	return ok
+}
+
+func (e *EngineImpl) Venues() (venues []string) {
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	for k := range e.venues {
+		venues = append(venues, k)
+	}
+	sort.Strings(venues)
+	return
+}
+
+func (e *EngineImpl) UpdateVenuePosition(venue string, pos, price float64) {
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	v, ok := e.venues[venue]
+	if !ok {
+		v = &venueInfo{}
+		e.venues[venue] = v
+	}
+	v.pos = pos
+	v.posPrice = price
+}
+
+func (e *EngineImpl) UpdateVenueBalance(venue string, balance float64) {
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	v, ok := e.venues[venue]
+	if !ok {
+		v = &venueInfo{}
+		e.venues[venue] = v
+	}
+	v.balance = balance
+}
+
+func (e *EngineImpl) PositionOn(venue string) (pos, price float64) {
+	if venue == "" {
+		return e.Position()
+	}
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	v, ok := e.venues[venue]
+	if !ok {
+		log.Warnf("PositionOn unknown venue: %s", venue)
+		return
+	}
+	return v.pos, v.posPrice
+}
+
+func (e *EngineImpl) BalanceOn(venue string) float64 {
+	if venue == "" {
+		return e.Balance()
+	}
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	v, ok := e.venues[venue]
+	if !ok {
+		log.Warnf("BalanceOn unknown venue: %s", venue)
+		return 0
+	}
+	return v.balance
+}
+
+func (e *EngineImpl) CancelOrderOn(venue, id string) {
+	e.proc.SendToAccount(venue, EventOrder, EventOrder, &TradeAction{Action: CancelOne, ID: id})
+}
+
+func (e *EngineWrapper) CancelOrderOn(venue, id string) {
+	if e.Disabled() {
+		return
+	}
+	e.EngineImpl.CancelOrderOn(venue, id)
+}
+
+func (e *EngineWrapper) DoOrderOn(venue string, typ TradeType, price, amount float64) (id string) {
+	if e.Disabled() {
+		return
+	}
+	id = fmt.Sprintf("%s-%s", e.VmID, e.actionID())
+	act := TradeAction{ID: id, Action: typ, Symbol: e.venueSymbol(venue), Amount: amount, Price: price, Time: e.Now()}
+	e.proc.SendToAccount(venue, EventOrder, EventOrder, &act)
+	return
+}
+
+// venueSymbol symbol is decided by the exchange of venue, keep empty for extra venues
+func (e *EngineImpl) venueSymbol(venue string) string {
+	e.venuesMutex.Lock()
+	defer e.venuesMutex.Unlock()
+	if _, ok := e.venues[venue]; ok {
+		return ""
+	}
+	return e.symbol
+}
@@ -1,9 +1,14 @@
 package goscript
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ztrade/base/common"
 	bengine "github.com/ztrade/base/engine"
@@ -19,6 +24,10 @@ type scriptInfo struct {
 	engine.Runner
 	params common.ParamData
 	wrap   *engine.EngineWrapper
+	src    string
+	param  string
+	// mod time of src when loaded
+	modTime time.Time
 }
 
 type Status struct {
@@ -29,11 +38,22 @@ type Status struct {
 
 type GoEngine struct {
 	BaseProcesser
-	engine   *engine.EngineWrapper
-	vms      map[string]*scriptInfo
+	engine *engine.EngineWrapper
+	vms    map[string]*scriptInfo
+	// names of scripts in order, the scripts are always called in this order
+	names    []string
 	mutex    sync.Mutex
 	started  int32
 	statusCh chan *Status
+
+	watchInterval time.Duration
+	closeCh       chan bool
+
+	stateStore    StateStore
+	stateInterval time.Duration
+
+	budget time.Duration
+	stats  map[string]*ScriptStats
 }
 
 func NewDefaultGoEngine() (s *GoEngine, err error) {
@@ -43,6 +63,7 @@ func NewGoEngine(symbol string) (s *GoEngine, err error) {
 	s = new(GoEngine)
 	s.Name = "multi_script"
 	s.vms = make(map[string]*scriptInfo)
+	s.stats = make(map[string]*ScriptStats)
 	s.engine = engine.NewEngineWrapper(&s.BaseProcesser, nil, symbol, "")
 	return
 }
@@ -53,34 +74,85 @@ func (s *GoEngine) SetStatusCh(ch chan *Status) {
 
 func (s *GoEngine) Init(bus *Bus) (err error) {
 	s.BaseProcesser.Init(bus)
-	s.Subscribe(EventCandle, s.onEventCandle)
-	s.Subscribe(EventTrade, s.onEventTrade)
-	s.Subscribe(EventPosition, s.onEventPosition)
-	s.Subscribe(EventTradeMarket, s.onEventTradeMarket)
-	s.Subscribe(EventDepth, s.onEventDepth)
-	s.Subscribe(EventBalance, s.onEventBalance)
-	return
+	return errors.Join(
+		SubscribeData(s, EventCandle, s.onEventCandle),
+		SubscribeData(s, EventTrade, s.onEventTrade),
+		SubscribeData(s, EventPosition, s.onEventPosition),
+		SubscribeData(s, EventTradeMarket, s.onEventTradeMarket),
+		SubscribeData(s, EventDepth, s.onEventDepth),
+		SubscribeData(s, EventBalance, s.onEventBalance),
+		SubscribeData(s, EventSymbolInfo, s.onEventSymbolInfo),
+		SubscribeData(s, EventRiskLimit, s.onEventRiskLimit),
+	)
 }
 
 func (s *GoEngine) Start() (err error) {
 	atomic.StoreInt32(&s.started, 1)
-	for k, v := range s.vms {
-		tempEng := engine.EngineWrapper{EngineImpl: s.engine.EngineImpl, VmID: k}
-		tempEng.Cb = s.updateScriptStatus
-		v.wrap = &tempEng
-		err = v.Init(&tempEng, v.params)
+	// the script stopped by sandbox is removed from names
+	for _, k := range slices.Clone(s.names) {
+		v := s.vms[k]
+		v.wrap = s.newWrapper(k)
+		if s.restoreState(k, v) != nil {
+			continue
+		}
+		err = safeInit(v.Runner, v.wrap, v.params)
 		if err != nil {
 			return err
 		}
 	}
+	s.closeCh = make(chan bool)
+	if !s.simulated() {
+		go s.timerRoutine()
+	}
+	if s.watchInterval > 0 {
+		go s.watchRoutine()
+	}
+	if s.stateStore != nil && s.stateInterval > 0 {
+		go s.stateRoutine()
+	}
 	return
 }
 
+func (s *GoEngine) newWrapper(name string) *engine.EngineWrapper {
+	tempEng := engine.EngineWrapper{EngineImpl: s.engine.EngineImpl, VmID: name}
+	tempEng.Cb = s.updateScriptStatus
+	return &tempEng
+}
+
+// AddVenue add an extra exchange venue, events of it are not sent to scripts as the default ones
+func (s *GoEngine) AddVenue(venue string) {
+	s.engine.AddVenue(venue)
+}
+
+// SetSubAccount track the position of every script separately by the id of orders
+func (s *GoEngine) SetSubAccount(enable bool) {
+	s.engine.SetSubAccount(enable)
+}
+
+// SetSeed generate the order ids by seed, used by the reproducible backtest
+func (s *GoEngine) SetSeed(seed int64) {
+	s.engine.SetSeed(seed)
+}
+
+// SetHistory set the loader of history candles to warm up indicators
+func (s *GoEngine) SetHistory(fn engine.HistoryFn) {
+	s.engine.SetHistory(fn)
+}
+
+func (s *GoEngine) isExtraVenue(e *Event) bool {
+	return e.GetAccount() != "" && s.engine.HasVenue(e.GetAccount())
+}
+
 func (s *GoEngine) ScriptCount() int {
 	return len(s.vms)
 }
 
 func (s *GoEngine) Stop() (err error) {
+	s.saveStates()
+	if s.closeCh != nil {
+		close(s.closeCh)
+		s.closeCh = nil
+	}
 	return
 }
 
@@ -98,6 +170,12 @@ func (s *GoEngine) doRemoveScript(name string) (err error) {
 	}
 	vm.wrap.CleanMerges()
 	delete(s.vms, name)
+	for i, v := range s.names {
+		if v == name {
+			s.names = append(s.names[:i], s.names[i+1:]...)
+			break
+		}
+	}
 	return
 }
 
@@ -114,62 +192,120 @@ func (s *GoEngine) doAddScript(name, src, param string) (err error) {
 		err = fmt.Errorf("%s script aleady exist", name)
 		return
 	}
+	si, err := loadScript(src, param)
+	if err != nil {
+		err = fmt.Errorf("AddScript %s %w", name, err)
+		return
+	}
+	s.vms[name] = si
+	s.names = append(s.names, name)
+	sort.Strings(s.names)
+	isStart := atomic.LoadInt32(&s.started)
+	if isStart == 1 {
+		si.wrap = s.newWrapper(name)
+		err = s.restoreState(name, si)
+		if err != nil {
+			return
+		}
+		err = safeInit(si.Runner, si.wrap, si.params)
+		if err != nil {
+			log.Error("GoEngine doAddScript Init failed:", err.Error())
+			return err
+		}
+	}
+	return
+}
+
+// loadScript load the script and parse the params
+func loadScript(src, param string) (si *scriptInfo, err error) {
+	var modTime time.Time
+	if fi, err := os.Stat(src); err == nil {
+		modTime = fi.ModTime()
+	}
 	r, err := engine.NewRunner(src)
 	if err != nil {
-		err = fmt.Errorf("AddScript %s %s error: %w", name, src, err)
+		err = fmt.Errorf("%s error: %w", src, err)
 		return
 	}
 	paramInfo, err := r.Param()
 	if err != nil {
-		err = fmt.Errorf("AddScript %s %s get Params error: %w", name, src, err)
+		err = fmt.Errorf("%s get Params error: %w", src, err)
 		return
 	}
 	paramData := make(common.ParamData)
 	if param != "" {
 		paramData, err = common.ParseParams(param, paramInfo)
 		if err != nil {
-			err = fmt.Errorf("AddScript %s %s ParseParams error: %w", name, src, err)
+			err = fmt.Errorf("%s ParseParams error: %w", src, err)
 			return
 		}
 	}
-	// var fnName string
-	si := scriptInfo{Runner: r, params: paramData}
-	s.vms[name] = &si
-	isStart := atomic.LoadInt32(&s.started)
-	if isStart == 1 {
-		tempEng := engine.EngineWrapper{EngineImpl: s.engine.EngineImpl, VmID: name}
-		tempEng.Cb = s.updateScriptStatus
-		si.wrap = &tempEng
-		err = si.Runner.Init(&tempEng, paramData)
-		if err != nil {
-			log.Error("GoEngine doAddScript Init failed:", err.Error())
-			return err
-		}
-	}
+	si = &scriptInfo{Runner: r, params: paramData, src: src, param: param, modTime: modTime}
 	return
 }
 
-func (s *GoEngine) onTrade(trade *Trade) {
+// onTrade send trade to scripts, only to the owner of order if sub-account enabled
+// venue trades don't change the sub-account
+func (s *GoEngine) onTrade(trade *Trade, isVenue bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	for _, vm := range s.vms {
-		vm.OnTrade(trade)
+	if s.engine.SubAccount() {
+		owner := ScriptOfOrder(trade.ID)
+		if vm, ok := s.vms[owner]; ok {
+			s.onAccountTrade(owner, vm, trade, isVenue)
+			return
+		}
 	}
+	s.callAll("OnTrade", func(r engine.Runner) {
+		r.OnTrade(trade)
+	})
 
 }
 
+// callAll call every script in the order of names, so the backtest is reproducible
+// the scripts may be removed in callbacks, so iterate a copy and skip the removed ones
+func (s *GoEngine) callAll(callback string, fn func(r engine.Runner)) {
+	for _, name := range slices.Clone(s.names) {
+		vm, ok := s.vms[name]
+		if !ok {
+			continue
+		}
+		s.call(name, vm, callback, fn)
+	}
+}
+
+func (s *GoEngine) onAccountTrade(name string, vm *scriptInfo, trade *Trade, isVenue bool) {
+	if isVenue || TradeFailed(trade) {
+		s.call(name, vm, "OnTrade", func(r engine.Runner) {
+			r.OnTrade(trade)
+		})
+		return
+	}
+	pos, price := s.engine.UpdateAccount(name, trade)
+	s.call(name, vm, "OnTrade", func(r engine.Runner) {
+		r.OnTrade(trade)
+	})
+	s.call(name, vm, "OnPosition", func(r engine.Runner) {
+		r.OnPosition(pos, price)
+	})
+}
+
 func (s *GoEngine) onPosition(pos *Position) {
 	log.Debug("on position:", pos.Hold)
-	posHold, _ := s.engine.Position()
+	posHold, _ := s.engine.EngineImpl.Position()
 	if posHold == pos.Hold {
 		return
 	}
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.engine.UpdatePosition(pos.Hold, pos.Price)
-	for _, vm := range s.vms {
-		vm.OnPosition(pos.Hold, pos.Price)
+	// scripts get the position of sub-account after their trades
+	if s.engine.SubAccount() {
+		return
 	}
+	s.callAll("OnPosition", func(r engine.Runner) {
+		r.OnPosition(pos.Hold, pos.Price)
+	})
 }
 
 func (s *GoEngine) onBalance(balance float64) {
@@ -184,37 +320,40 @@ func (s *GoEngine) onBalance(balance float64) {
 func (s *GoEngine) onCandle(name, binSize string, candle *Candle) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	for _, vm := range s.vms {
-		vm.OnCandle(candle)
+	s.engine.UpdateIndicators(candle)
+	if candle.ID != -1 && s.simulated() {
+		// the clock is at the end of candle, the timers due before it are fired before OnCandle
+		s.fireTimers(s.Now())
 	}
+	s.callAll("OnCandle", func(r engine.Runner) {
+		r.OnCandle(candle)
+	})
 	s.engine.OnCandle(candle)
 }
 
 func (s *GoEngine) onTradeMarket(th *Trade) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	for _, vm := range s.vms {
-		vm.OnTradeMarket(th)
-	}
+	s.callAll("OnTradeMarket", func(r engine.Runner) {
+		r.OnTradeMarket(th)
+	})
 }
 
 func (s *GoEngine) onDepth(depth *Depth) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	for _, vm := range s.vms {
-		vm.OnDepth(depth)
-	}
+	s.callAll("OnDepth", func(r engine.Runner) {
+		r.OnDepth(depth)
+	})
 }
 
-func (s *GoEngine) onEventCandle(e *Event) (err error) {
-	ret, ok := e.GetData().(*Candle)
-	if !ok {
-		log.Errorf("onEventCandle type error: %##v", e.GetData())
+func (s *GoEngine) onEventCandle(e *Event, ret *Candle) (err error) {
+
+	if s.isExtraVenue(e) {
 		return
 	}
-
 	name := e.GetName()
-	binSize := e.GetExtra().(string)
+	binSize, _ := ExtraAs[string](e)
 	if name == "recent" {
 		ret.ID = -1
 	}
@@ -222,55 +361,64 @@ func (s *GoEngine) onEventCandle(e *Event) (err error) {
 	return
 }
 
-func (s *GoEngine) onEventTrade(e *Event) (err error) {
-	tr, ok := e.GetData().(*Trade)
-	if !ok {
-		log.Errorf("onEventTrade type error: %##v", e.GetData())
-		return
-	}
-	s.onTrade(tr)
+func (s *GoEngine) onEventTrade(e *Event, tr *Trade) (err error) {
+	s.onTrade(tr, s.isExtraVenue(e))
 	return
 }
 
-func (s *GoEngine) onEventPosition(e *Event) (err error) {
-	pos, ok := e.GetData().(*Position)
-	if !ok {
-		log.Errorf("onEventPosition type error: %##v", e.GetData())
+func (s *GoEngine) onEventPosition(e *Event, pos *Position) (err error) {
+	if s.isExtraVenue(e) {
+		s.engine.UpdateVenuePosition(e.GetAccount(), pos.Hold, pos.Price)
 		return
 	}
 	s.onPosition(pos)
 	return
 }
-func (s *GoEngine) onEventTradeMarket(e *Event) (err error) {
-	th, ok := e.GetData().(*Trade)
-	if !ok {
-		log.Errorf("onEventTradeMarket type error: %##v", e.GetData())
+func (s *GoEngine) onEventTradeMarket(e *Event, th *Trade) (err error) {
+	if s.isExtraVenue(e) {
 		return
 	}
 	s.onTradeMarket(th)
 	return
 }
 
-func (s *GoEngine) onEventDepth(e *Event) (err error) {
-	depth, ok := e.GetData().(*Depth)
-	if !ok {
-		log.Errorf("onEventDepth type error: %##v", e.GetData())
+func (s *GoEngine) onEventDepth(e *Event, depth *Depth) (err error) {
+	if s.isExtraVenue(e) {
 		return
 	}
 	s.onDepth(depth)
 	return
 }
 
-func (s *GoEngine) onEventBalance(e *Event) (err error) {
-	balance, ok := e.GetData().(*Balance)
-	if !ok {
-		log.Errorf("onEventBalance type error: %##v", e.GetData())
+func (s *GoEngine) onEventBalance(e *Event, balance *Balance) (err error) {
+	if s.isExtraVenue(e) {
+		s.engine.UpdateVenueBalance(e.GetAccount(), balance.Balance)
+		return
+	}
+	// base currency of spot is the position, not balance
+	if e.GetExtra() == BalanceAsset {
 		return
 	}
 	s.onBalance(balance.Balance)
 	return
 }
 
+func (s *GoEngine) onEventSymbolInfo(e *Event, si *SymbolInfo) (err error) {
+	if s.isExtraVenue(e) {
+		return
+	}
+	s.engine.UpdateSymbolInfo(si)
+	return
+}
+
+func (s *GoEngine) onEventRiskLimit(e *Event, rl *RiskLimit) (err error) {
+	if s.isExtraVenue(e) {
+		return
+	}
+	s.engine.UpdateRiskLimit(rl)
+	return
+}
+
 func (s *GoEngine) updateScriptStatus(name string, status int, msg string) {
 	// call in script, no need lock
 	switch status {
@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 
 	"github.com/goplus/ixgo"
 	_ "github.com/goplus/ixgo/pkg/encoding/json"
@@ -97,7 +100,27 @@ func fixSource(file string) (ret string, err error) {
 	return
 }
 
+// checkImports check the imports of script with the allowed list
+func checkImports(file string) (err error) {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+	if err != nil {
+		return
+	}
+	for _, v := range f.Imports {
+		path, _ := strconv.Unquote(v.Path.Value)
+		if !engine.ImportAllowed(path) {
+			err = fmt.Errorf("import %s not allowed", path)
+			return
+		}
+	}
+	return
+}
+
 func NewRunner(file string) (r engine.Runner, err error) {
+	err = checkImports(file)
+	if err != nil {
+		return
+	}
 	source, err := fixSource(file)
 	if err != nil {
 		return
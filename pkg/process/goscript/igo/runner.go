@@ -5,6 +5,7 @@ import (
 	"github.com/ztrade/base/engine"
 	. "github.com/ztrade/trademodel"
 	. "github.com/ztrade/ztrade/pkg/event"
+	zengine "github.com/ztrade/ztrade/pkg/process/goscript/engine"
 )
 
 type igoImpl interface {
@@ -19,6 +20,17 @@ type igoImpl interface {
 	// GetName() string
 }
 
+// snapshoter optional hook of script to hand over state when reloaded
+type snapshoter interface {
+	Snapshot() ([]byte, error)
+	Restore([]byte) error
+}
+
+// timer optional hook of script to receive the timers
+type timer interface {
+	OnTimer(id string)
+}
+
 type igoRunner struct {
 	name string
 	impl igoImpl
@@ -62,6 +74,31 @@ func (r *igoRunner) OnEvent(e *Event) (err error) {
 	return
 }
 
+func (r *igoRunner) Snapshot() (data []byte, err error) {
+	sn, ok := r.impl.(snapshoter)
+	if !ok {
+		return nil, zengine.ErrNoSnapshot
+	}
+	return sn.Snapshot()
+}
+
+func (r *igoRunner) Restore(data []byte) (err error) {
+	sn, ok := r.impl.(snapshoter)
+	if !ok {
+		return zengine.ErrNoSnapshot
+	}
+	return sn.Restore(data)
+}
+
+func (r *igoRunner) OnTimer(id string) (err error) {
+	t, ok := r.impl.(timer)
+	if !ok {
+		return
+	}
+	t.OnTimer(id)
+	return
+}
+
 func (r *igoRunner) GetName() string {
 	return r.name
 }
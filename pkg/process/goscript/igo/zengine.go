@@ -0,0 +1,42 @@
+package igo
+
+import (
+	q "github.com/ztrade/ztrade/pkg/process/goscript/engine"
+
+	"go/constant"
+	"reflect"
+
+	"github.com/goplus/ixgo"
+)
+
+// export the optional engine apis, scripts get them by engine.(zengine.AlgoEngine)
+func init() {
+	ixgo.RegisterPackage(&ixgo.Package{
+		Name: "engine",
+		Path: "github.com/ztrade/ztrade/pkg/process/goscript/engine",
+		Deps: map[string]string{
+			"github.com/ztrade/indicator":  "indicator",
+			"github.com/ztrade/trademodel": "trademodel",
+			"time":                         "time",
+		},
+		Interfaces: map[string]reflect.Type{
+			"AlgoEngine":      reflect.TypeOf((*q.AlgoEngine)(nil)).Elem(),
+			"VenueEngine":     reflect.TypeOf((*q.VenueEngine)(nil)).Elem(),
+			"SymbolEngine":    reflect.TypeOf((*q.SymbolEngine)(nil)).Elem(),
+			"IndicatorEngine": reflect.TypeOf((*q.IndicatorEngine)(nil)).Elem(),
+			"CandleUpdater":   reflect.TypeOf((*q.CandleUpdater)(nil)).Elem(),
+			"AccountEngine":   reflect.TypeOf((*q.AccountEngine)(nil)).Elem(),
+			"SizingEngine":    reflect.TypeOf((*q.SizingEngine)(nil)).Elem(),
+			"TimerEngine":     reflect.TypeOf((*q.TimerEngine)(nil)).Elem(),
+		},
+		NamedTypes:  map[string]reflect.Type{},
+		AliasTypes:  map[string]reflect.Type{},
+		Vars:        map[string]reflect.Value{},
+		Funcs:       map[string]reflect.Value{},
+		TypedConsts: map[string]ixgo.TypedConst{},
+		UntypedConsts: map[string]ixgo.UntypedConst{
+			"VolATR":    {Typ: "untyped string", Value: constant.MakeString(q.VolATR)},
+			"VolStdDev": {Typ: "untyped string", Value: constant.MakeString(q.VolStdDev)},
+		},
+	})
+}
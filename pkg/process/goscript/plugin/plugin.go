@@ -2,9 +2,13 @@ package plugin
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"plugin"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/ztrade/base/common"
 	bengine "github.com/ztrade/base/engine"
@@ -21,13 +25,30 @@ func init() {
 
 type newFn func() interface{}
 
+var (
+	_ engine.Snapshoter  = (*StrategyPlugin)(nil)
+	_ engine.TimerRunner = (*StrategyPlugin)(nil)
+
+	// plugins opened, go can't load the same plugin file twice
+	opened      = map[string]bool{}
+	openedMutex sync.Mutex
+)
+
 type StrategyPlugin struct {
 	name string
 	Runner
 }
 
 func NewPlugin(file string) (r engine.Runner, err error) {
-	pl, err := plugin.Open(file)
+	src, copied, err := openFile(file)
+	if err != nil {
+		return
+	}
+	if copied {
+		// the plugin is mapped in memory after opened, the temp copy is not needed any more
+		defer os.Remove(src)
+	}
+	pl, err := plugin.Open(src)
 	if err != nil {
 		return
 	}
@@ -54,6 +75,34 @@ func NewPlugin(file string) (r engine.Runner, err error) {
 	r = sp
 	return
 }
+
+// openFile return the file to open, the plugin file reloaded is copied to a new temp file
+func openFile(file string) (src string, copied bool, err error) {
+	src, err = filepath.Abs(file)
+	if err != nil {
+		return
+	}
+	openedMutex.Lock()
+	defer openedMutex.Unlock()
+	if !opened[src] {
+		opened[src] = true
+		return
+	}
+	buf, err := os.ReadFile(src)
+	if err != nil {
+		return
+	}
+	ext := filepath.Ext(src)
+	dst := filepath.Join(os.TempDir(), fmt.Sprintf("%s.%d%s", strings.TrimSuffix(filepath.Base(src), ext), time.Now().UnixNano(), ext))
+	err = os.WriteFile(dst, buf, 0755)
+	if err != nil {
+		return
+	}
+	src = dst
+	copied = true
+	return
+}
+
 func (sp *StrategyPlugin) GetName() string {
 	return sp.name
 }
@@ -88,3 +137,25 @@ func (sp *StrategyPlugin) OnDepth(depth *Depth) (err error) {
 func (sp *StrategyPlugin) OnEvent(e *Event) (err error) {
 	return
 }
+func (sp *StrategyPlugin) Snapshot() (data []byte, err error) {
+	sn, ok := sp.Runner.(snapshoter)
+	if !ok {
+		return nil, engine.ErrNoSnapshot
+	}
+	return sn.Snapshot()
+}
+func (sp *StrategyPlugin) Restore(data []byte) (err error) {
+	sn, ok := sp.Runner.(snapshoter)
+	if !ok {
+		return engine.ErrNoSnapshot
+	}
+	return sn.Restore(data)
+}
+func (sp *StrategyPlugin) OnTimer(id string) (err error) {
+	t, ok := sp.Runner.(timer)
+	if !ok {
+		return
+	}
+	t.OnTimer(id)
+	return
+}
@@ -0,0 +1,29 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.so")
+	err := os.WriteFile(file, []byte("v1"), 0755)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	src, copied, err := openFile(file)
+	if err != nil || copied || src != file {
+		t.Fatalf("first open error: %s %t %v", src, copied, err)
+	}
+	// go can't open the same plugin twice, the reloaded one is copied
+	src, copied, err = openFile(file)
+	if err != nil || !copied || src == file {
+		t.Fatalf("reload open error: %s %t %v", src, copied, err)
+	}
+	defer os.Remove(src)
+	buf, err := os.ReadFile(src)
+	if err != nil || string(buf) != "v1" {
+		t.Fatalf("temp copy error: %s %v", buf, err)
+	}
+}
@@ -16,3 +16,14 @@ type Runner interface {
 	OnDepth(depth *Depth)
 	// OnEvent(e Event)
 }
+
+// snapshoter optional hook of strategy to hand over state when reloaded
+type snapshoter interface {
+	Snapshot() ([]byte, error)
+	Restore([]byte) error
+}
+
+// timer optional hook of strategy to receive the timers
+type timer interface {
+	OnTimer(id string)
+}
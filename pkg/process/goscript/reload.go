@@ -0,0 +1,165 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ztrade/base/common"
+	bengine "github.com/ztrade/base/engine"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetWatchInterval check the script files every interval, and reload the script when modified, 0 means disable
+// must be called before start
+func (s *GoEngine) SetWatchInterval(interval time.Duration) {
+	s.watchInterval = interval
+}
+
+// ReloadScript load the new version of script and replace the old one between events
+// the state is handed over if the script implement Snapshot/Restore,
+// the old one keep running if the new one failed.
+// Only the merges added by the new version are rolled back, the orders, timers or
+// other side effects of its Init stay, so Init should not send orders
+func (s *GoEngine) ReloadScript(name string) (err error) {
+	s.mutex.Lock()
+	old, ok := s.vms[name]
+	s.mutex.Unlock()
+	if !ok {
+		err = fmt.Errorf("%s script not exist", name)
+		return
+	}
+	// load outside the lock, compile may take long time
+	si, err := loadScript(old.src, old.param)
+	if err != nil {
+		err = fmt.Errorf("ReloadScript %s %w", name, err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cur, ok := s.vms[name]
+	if !ok || cur != old {
+		err = fmt.Errorf("%s script changed while reloading", name)
+		return
+	}
+	if old.wrap == nil {
+		// not started yet
+		s.vms[name] = si
+		return
+	}
+	var state []byte
+	if sn, ok := old.Runner.(engine.Snapshoter); ok {
+		var sErr error
+		err = s.call(name, old, "Snapshot", func(r engine.Runner) {
+			state, sErr = sn.Snapshot()
+		})
+		if err == nil && !errors.Is(sErr, engine.ErrNoSnapshot) {
+			err = sErr
+		}
+		if err != nil {
+			err = fmt.Errorf("ReloadScript %s snapshot failed: %w", name, err)
+			return
+		}
+	}
+	// restore before Init as restart
+	if state != nil {
+		if sn, ok := si.Runner.(engine.Snapshoter); ok {
+			// the new version is not running yet, a panic only fails the reload
+			var rErr error
+			err = protect(si.Runner, func(r engine.Runner) {
+				rErr = sn.Restore(state)
+			})
+			if err == nil && errors.Is(rErr, engine.ErrNoSnapshot) {
+				log.Warnf("ReloadScript %s: new version can't restore state", name)
+			} else if err == nil {
+				err = rErr
+			}
+			if err != nil {
+				err = fmt.Errorf("ReloadScript %s restore failed, keep the old version: %w", name, err)
+				return
+			}
+		} else {
+			log.Warnf("ReloadScript %s: new version can't restore state", name)
+		}
+	}
+	merges := s.engine.TakeMerges(name)
+	si.wrap = s.newWrapper(name)
+	err = safeInit(si.Runner, si.wrap, si.params)
+	if err != nil {
+		// the merges added by the new version are dropped
+		s.engine.SetMerges(name, merges)
+		err = fmt.Errorf("ReloadScript %s failed, keep the old version: %w", name, err)
+		return
+	}
+	s.vms[name] = si
+	log.Infof("script %s reloaded from %s", name, si.src)
+	return
+}
+
+// safeInit init runner, the panic of script is returned as error
+func safeInit(r engine.Runner, eng bengine.Engine, params common.ParamData) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("init panic: %v", p)
+		}
+	}()
+	return r.Init(eng, params)
+}
+
+func (s *GoEngine) watchRoutine() {
+	closeCh := s.closeCh
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+	// the mod time seen last time, reload after the file is stable for one interval
+	pending := make(map[string]time.Time)
+	for {
+		select {
+		case <-closeCh:
+			return
+		case <-ticker.C:
+		}
+		for name, modTime := range s.modifiedScripts() {
+			last, ok := pending[name]
+			pending[name] = modTime
+			if !ok || !last.Equal(modTime) {
+				continue
+			}
+			delete(pending, name)
+			err := s.ReloadScript(name)
+			if err != nil {
+				log.Error(err.Error())
+				s.markLoaded(name, modTime)
+			}
+		}
+	}
+}
+
+// modifiedScripts return the scripts which file modified after loaded
+func (s *GoEngine) modifiedScripts() (ret map[string]time.Time) {
+	ret = make(map[string]time.Time)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for k, v := range s.vms {
+		fi, err := os.Stat(v.src)
+		if err != nil {
+			continue
+		}
+		if fi.ModTime().After(v.modTime) {
+			ret[k] = fi.ModTime()
+		}
+	}
+	return
+}
+
+// markLoaded don't reload the failed version again
+func (s *GoEngine) markLoaded(name string, modTime time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if v, ok := s.vms[name]; ok {
+		v.modTime = modTime
+	}
+}
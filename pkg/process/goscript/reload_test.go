@@ -0,0 +1,171 @@
+package goscript
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ztrade/base/common"
+	bengine "github.com/ztrade/base/engine"
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+)
+
+func init() {
+	engine.Register(".rld", newReloadRunner)
+}
+
+// reloadRunner strategy of test, the version is the content of file, it counts the candles
+type reloadRunner struct {
+	version string
+	count   int
+	// count seen in Init
+	initCount int
+	eng       bengine.Engine
+}
+
+func newReloadRunner(file string) (r engine.Runner, err error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	r = &reloadRunner{version: strings.TrimSpace(string(buf))}
+	return
+}
+
+func (r *reloadRunner) Param() (paramInfo []common.Param, err error) {
+	return
+}
+
+func (r *reloadRunner) Init(e bengine.Engine, params common.ParamData) (err error) {
+	r.initCount = r.count
+	r.eng = e
+	switch r.version {
+	case "fail":
+		err = errors.New("init failed")
+	case "panic":
+		panic("init panic")
+	}
+	return
+}
+
+func (r *reloadRunner) OnCandle(candle *Candle) (err error) {
+	r.count++
+	if r.version == "remove" {
+		r.eng.UpdateStatus(bengine.StatusSuccess, "removed")
+	}
+	return
+}
+
+func (r *reloadRunner) Snapshot() (data []byte, err error) {
+	switch r.version {
+	case "snapshot panic":
+		panic("snapshot panic")
+	case "no snapshot":
+		return nil, engine.ErrNoSnapshot
+	}
+	return []byte(strconv.Itoa(r.count)), nil
+}
+
+func (r *reloadRunner) Restore(data []byte) (err error) {
+	if r.version == "no snapshot" {
+		return engine.ErrNoSnapshot
+	}
+	r.count, err = strconv.Atoi(string(data))
+	return
+}
+
+func (r *reloadRunner) OnPosition(pos, price float64) (err error) { return }
+func (r *reloadRunner) OnTrade(trade *Trade) (err error)          { return }
+func (r *reloadRunner) OnTradeMarket(trade *Trade) (err error)    { return }
+func (r *reloadRunner) OnDepth(depth *Depth) (err error)          { return }
+func (r *reloadRunner) OnEvent(e *Event) (err error)              { return }
+func (r *reloadRunner) GetName() string                           { return r.version }
+
+func TestReloadScript(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a.rld")
+	write := func(version string) {
+		err := os.WriteFile(src, []byte(version), 0644)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	write("v1")
+	s, err := NewDefaultGoEngine()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = s.AddScript("a", src, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	f := NewBaseProcesser("feeder")
+	procs := NewSyncProcessers()
+	procs.Adds(f, s)
+	err = procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer procs.Stop()
+	candles := testCandles(3)
+	running := func() *reloadRunner {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return s.vms["a"].Runner.(*reloadRunner)
+	}
+	f.SendWithExtra("candle", EventCandle, candles[0], "1m")
+
+	write("v2")
+	err = s.ReloadScript("a")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r := running()
+	if r.version != "v2" || r.count != 1 || r.initCount != 1 {
+		t.Fatalf("reload error, version: %s, count: %d", r.version, r.count)
+	}
+	f.SendWithExtra("candle", EventCandle, candles[1], "1m")
+
+	// the old version keep running if the new one failed
+	for _, v := range []string{"fail", "panic"} {
+		write(v)
+		err = s.ReloadScript("a")
+		if err == nil {
+			t.Fatalf("reload %s should fail", v)
+		}
+		r = running()
+		if r.version != "v2" || r.count != 2 {
+			t.Fatalf("old version not kept after %s, version: %s, count: %d", v, r.version, r.count)
+		}
+	}
+	f.SendWithExtra("candle", EventCandle, candles[2], "1m")
+	if r.count != 3 {
+		t.Fatalf("old version not running: %d", r.count)
+	}
+
+	// the state is dropped if the new version doesn't support snapshot
+	write("no snapshot")
+	err = s.ReloadScript("a")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r = running()
+	if r.version != "no snapshot" || r.count != 0 {
+		t.Fatalf("reload without snapshot error, version: %s, count: %d", r.version, r.count)
+	}
+	// and nothing is handed over if the old version doesn't support it
+	write("v3")
+	err = s.ReloadScript("a")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r = running()
+	if r.version != "v3" || r.count != 0 {
+		t.Fatalf("reload from no snapshot error, version: %s, count: %d", r.version, r.count)
+	}
+}
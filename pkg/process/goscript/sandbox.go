@@ -0,0 +1,123 @@
+package goscript
+
+import (
+	"fmt"
+	"runtime/metrics"
+	"time"
+
+	bengine "github.com/ztrade/base/engine"
+	zmetrics "github.com/ztrade/ztrade/pkg/metrics"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const allocMetric = "/gc/heap/allocs:bytes"
+
+// ScriptStats resource usage of script callbacks
+type ScriptStats struct {
+	Calls int64
+	// Busy wall time spent in callbacks, include the time waiting for the lock or IO
+	Busy time.Duration
+	// MaxBusy the longest callback
+	MaxBusy time.Duration
+	// Alloc bytes allocated in callbacks, it's read from the process-wide counter,
+	// so other goroutines allocated at the same time are counted too
+	Alloc uint64
+}
+
+// SetBudget set the max duration of one callback, the script is stopped if exceeded, 0 means no limit
+// the callbacks run in another goroutine when the budget is set. Go can't kill a goroutine,
+// the timed out callback keeps running in background, the orders, events and state changes of it are dropped
+func (s *GoEngine) SetBudget(budget time.Duration) {
+	s.budget = budget
+}
+
+// ScriptStats return the resource usage of scripts
+func (s *GoEngine) ScriptStats() (ret map[string]ScriptStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret = make(map[string]ScriptStats, len(s.stats))
+	for k, v := range s.stats {
+		ret[k] = *v
+	}
+	return
+}
+
+// call run the callback of script with panic recovery, time budget and accounting
+// the error of panic or timeout is returned after the script stopped, must be called with the mutex held
+func (s *GoEngine) call(name string, vm *scriptInfo, callback string, fn func(r engine.Runner)) (err error) {
+	start := time.Now()
+	alloc := readAlloc()
+	var reason string
+	if s.budget <= 0 {
+		err = protect(vm.Runner, fn)
+		reason = "panic"
+	} else {
+		done := make(chan error, 1)
+		go func() {
+			done <- protect(vm.Runner, fn)
+		}()
+		timer := time.NewTimer(s.budget)
+		select {
+		case err = <-done:
+			timer.Stop()
+			reason = "panic"
+		case <-timer.C:
+			err = fmt.Errorf("%s exceed time budget %s", callback, s.budget)
+			reason = "timeout"
+		}
+	}
+	cost := time.Since(start)
+	allocated := readAlloc() - alloc
+	st, ok := s.stats[name]
+	if !ok {
+		st = new(ScriptStats)
+		s.stats[name] = st
+	}
+	st.Calls++
+	st.Busy += cost
+	if cost > st.MaxBusy {
+		st.MaxBusy = cost
+	}
+	st.Alloc += allocated
+	zmetrics.ObserveScript(name, callback, start)
+	zmetrics.ObserveScriptAlloc(name, allocated)
+	if err != nil {
+		s.failScript(name, vm, reason, err)
+	}
+	return
+}
+
+// failScript stop the script and keep others running
+func (s *GoEngine) failScript(name string, vm *scriptInfo, reason string, err error) {
+	log.Errorf("script %s stopped by sandbox: %s", name, err.Error())
+	zmetrics.ScriptFailed(name, reason)
+	if vm.wrap != nil {
+		vm.wrap.Disable()
+	}
+	// the script may be removed in callback already
+	if cur, ok := s.vms[name]; !ok || cur != vm {
+		return
+	}
+	s.updateScriptStatus(name, bengine.StatusFail, err.Error())
+}
+
+func protect(r engine.Runner, fn func(r engine.Runner)) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("script panic: %v", p)
+		}
+	}()
+	fn(r)
+	return
+}
+
+func readAlloc() uint64 {
+	sample := []metrics.Sample{{Name: allocMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
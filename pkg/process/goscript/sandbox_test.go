@@ -0,0 +1,105 @@
+package goscript
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+)
+
+var (
+	slowRelease = make(chan struct{})
+	slowDone    = make(chan struct{})
+)
+
+func init() {
+	engine.Register(".slow", newSlowRunner)
+}
+
+// slowRunner strategy of test, OnCandle blocks until released and then calls the engine
+type slowRunner struct {
+	reloadRunner
+}
+
+func newSlowRunner(file string) (r engine.Runner, err error) {
+	r = &slowRunner{reloadRunner: reloadRunner{version: "slow"}}
+	return
+}
+
+func (r *slowRunner) OnCandle(candle *Candle) (err error) {
+	<-slowRelease
+	defer close(slowDone)
+	r.eng.CancelAllOrder()
+	r.eng.CancelOrder("a")
+	r.eng.OpenLong(candle.Close, 1)
+	r.eng.Watch(EventDepth)
+	r.eng.SendNotify("title", "content", "")
+	r.eng.SetBalance(100)
+	r.eng.Merge("1m", "5m", func(candle *Candle) {})
+	r.eng.(engine.TimerEngine).After("timer", time.Minute)
+	return
+}
+
+// eventRecorder record the events sent by scripts
+type eventRecorder struct {
+	BaseProcesser
+	events []string
+}
+
+func (r *eventRecorder) Init(bus *Bus) (err error) {
+	r.BaseProcesser.Init(bus)
+	for _, v := range []string{EventOrder, EventWatch, EventNotify, EventScriptBalance} {
+		r.Subscribe(v, func(e *Event) error {
+			r.events = append(r.events, e.GetType())
+			return nil
+		})
+	}
+	return
+}
+
+func TestTimeoutDisable(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a.slow")
+	err := os.WriteFile(src, []byte("slow"), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s, err := NewDefaultGoEngine()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s.SetBudget(time.Millisecond * 20)
+	err = s.AddScript("a", src, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r := &eventRecorder{BaseProcesser: BaseProcesser{Name: "recorder"}}
+	procs := NewSyncProcessers()
+	procs.Adds(r, s)
+	err = procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer procs.Stop()
+	r.SendWithExtra("candle", EventCandle, testCandles(1)[0], "1m")
+	if s.ScriptCount() != 0 {
+		t.Fatal("script not stopped after timeout")
+	}
+	// the timed out callback goes on after the script stopped
+	close(slowRelease)
+	select {
+	case <-slowDone:
+	case <-time.After(time.Second * 5):
+		t.Fatal("callback not finished")
+	}
+	if len(r.events) != 0 {
+		t.Fatalf("events of stopped script not dropped: %v", r.events)
+	}
+	if calls := s.engine.DueTimers(time.Now().Add(time.Hour * 24 * 365)); len(calls) != 0 {
+		t.Fatalf("timers of stopped script not dropped: %v", calls)
+	}
+}
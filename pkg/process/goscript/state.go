@@ -0,0 +1,166 @@
+package goscript
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/ztrade/ztrade/pkg/core"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StateStore persist the snapshots of scripts
+type StateStore interface {
+	// LoadState return nil if not exist
+	LoadState(name string) (data []byte, err error)
+	SaveState(name string, data []byte) (err error)
+}
+
+// SetStateStore save the state of scripts every interval and when stop, and restore them before Init,
+// so Init can see the last state and skip the warm up, only the scripts implement Snapshot/Restore are saved,
+// the sub-accounts of scripts are saved too if enabled, must be called before start
+func (s *GoEngine) SetStateStore(store StateStore, interval time.Duration) {
+	s.stateStore = store
+	s.stateInterval = interval
+}
+
+// restoreState restore the last state of script in sandbox before Init, must be called with mutex locked
+// the error is returned only when the script is stopped by sandbox, other errors are logged
+func (s *GoEngine) restoreState(name string, si *scriptInfo) (err error) {
+	if s.stateStore == nil {
+		return
+	}
+	if s.engine.SubAccount() {
+		s.restoreAccount(name)
+	}
+	sn, ok := si.Runner.(engine.Snapshoter)
+	if !ok {
+		return
+	}
+	data, lErr := s.stateStore.LoadState(name)
+	if lErr != nil {
+		log.Errorf("load state of script %s failed: %s", name, lErr.Error())
+		return
+	}
+	if data == nil {
+		return
+	}
+	var rErr error
+	err = s.call(name, si, "Restore", func(r engine.Runner) {
+		rErr = sn.Restore(data)
+	})
+	if err != nil {
+		return
+	}
+	if errors.Is(rErr, engine.ErrNoSnapshot) {
+		log.Warnf("script %s can't restore the saved state", name)
+		return
+	}
+	if rErr != nil {
+		log.Errorf("restore state of script %s failed: %s", name, rErr.Error())
+		return
+	}
+	log.Infof("script %s state restored", name)
+	return
+}
+
+// accountKey the state name of the sub-account of script
+func accountKey(name string) string {
+	return name + "#account"
+}
+
+// restoreAccount restore the sub-account of script, the errors are logged
+func (s *GoEngine) restoreAccount(name string) {
+	data, err := s.stateStore.LoadState(accountKey(name))
+	if err != nil {
+		log.Errorf("load account of script %s failed: %s", name, err.Error())
+		return
+	}
+	if data == nil {
+		return
+	}
+	var a PnL
+	err = json.Unmarshal(data, &a)
+	if err != nil {
+		log.Errorf("restore account of script %s failed: %s", name, err.Error())
+		return
+	}
+	s.engine.SetAccount(name, a)
+	log.Infof("script %s account restored, position: %f", name, a.Position)
+}
+
+// snapshotAccount add the sub-account of script to states if it has trades
+func (s *GoEngine) snapshotAccount(name string, states map[string][]byte) {
+	a := s.engine.Account(name)
+	if a.Trades == 0 {
+		return
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		log.Errorf("encode account of script %s failed: %s", name, err.Error())
+		return
+	}
+	states[accountKey(name)] = data
+}
+
+// saveStates snapshot the scripts between events in sandbox and save them
+func (s *GoEngine) saveStates() {
+	if s.stateStore == nil {
+		return
+	}
+	states := make(map[string][]byte)
+	s.mutex.Lock()
+	for k, v := range s.vms {
+		// the sub-account is kept by engine, it's saved even if the script is disabled
+		if s.engine.SubAccount() {
+			s.snapshotAccount(k, states)
+		}
+		sn, ok := v.Runner.(engine.Snapshoter)
+		// the state of script stopped by sandbox may be broken, keep the last saved one
+		if !ok || v.wrap == nil || v.wrap.Disabled() {
+			continue
+		}
+		var data []byte
+		var sErr error
+		err := s.call(k, v, "Snapshot", func(r engine.Runner) {
+			data, sErr = sn.Snapshot()
+		})
+		if err != nil {
+			continue
+		}
+		err = sErr
+		if errors.Is(err, engine.ErrNoSnapshot) {
+			continue
+		}
+		if err != nil {
+			log.Errorf("snapshot script %s failed: %s", k, err.Error())
+			continue
+		}
+		if data != nil {
+			states[k] = data
+		}
+	}
+	s.mutex.Unlock()
+	for k, v := range states {
+		err := s.stateStore.SaveState(k, v)
+		if err != nil {
+			log.Errorf("save state of script %s failed: %s", k, err.Error())
+		}
+	}
+}
+
+func (s *GoEngine) stateRoutine() {
+	closeCh := s.closeCh
+	ticker := time.NewTicker(s.stateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closeCh:
+			return
+		case <-ticker.C:
+			s.saveStates()
+		}
+	}
+}
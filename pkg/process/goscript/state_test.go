@@ -0,0 +1,135 @@
+package goscript
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+type memStore map[string][]byte
+
+func (m memStore) LoadState(name string) (data []byte, err error) {
+	return m[name], nil
+}
+
+func (m memStore) SaveState(name string, data []byte) (err error) {
+	m[name] = data
+	return
+}
+
+func TestStateStore(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.rld")
+	err := os.WriteFile(src, []byte("v1"), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	bad := filepath.Join(dir, "bad.rld")
+	err = os.WriteFile(bad, []byte("snapshot panic"), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	noSnap := filepath.Join(dir, "c.rld")
+	err = os.WriteFile(noSnap, []byte("no snapshot"), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s, err := NewDefaultGoEngine()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	store := memStore{"a": []byte("5"), "c": []byte("3")}
+	s.SetStateStore(store, 0)
+	for _, v := range []string{"a", "b"} {
+		err = s.AddScript(v, src, "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	err = s.AddScript("bad", bad, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = s.AddScript("c", noSnap, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	f := NewBaseProcesser("feeder")
+	procs := NewSyncProcessers()
+	procs.Adds(f, s)
+	err = procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// the state is restored before Init
+	a := s.vms["a"].Runner.(*reloadRunner)
+	b := s.vms["b"].Runner.(*reloadRunner)
+	c := s.vms["c"].Runner.(*reloadRunner)
+	if a.count != 5 || a.initCount != 5 || b.count != 0 || c.count != 0 {
+		t.Fatalf("restore error, a: %#v, b: %#v, c: %#v", a, b, c)
+	}
+	for _, v := range testCandles(2) {
+		f.SendWithExtra("candle", EventCandle, v, "1m")
+	}
+	procs.Stop()
+	if string(store["a"]) != "7" || string(store["b"]) != "2" {
+		t.Fatalf("save error: %s %s", store["a"], store["b"])
+	}
+	// the script without snapshot keeps running and its saved state is kept
+	if _, ok := s.vms["c"]; !ok || c.count != 2 || string(store["c"]) != "3" {
+		t.Fatalf("script without snapshot error: %d %s", c.count, store["c"])
+	}
+	// the panic of snapshot only stop the script
+	if _, ok := store["bad"]; ok || s.ScriptStats()["bad"].Calls == 0 {
+		t.Fatalf("snapshot panic not sandboxed: %s", store["bad"])
+	}
+	if _, ok := s.vms["bad"]; ok {
+		t.Fatal("script not stopped after snapshot panic")
+	}
+}
+
+func TestStateStoreAccount(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a.rld")
+	err := os.WriteFile(src, []byte("v1"), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s, err := NewDefaultGoEngine()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	s.SetSubAccount(true)
+	store := memStore{accountKey("a"): []byte(`{"Position":2,"AvgPrice":100,"Realized":5,"Trades":3}`)}
+	s.SetStateStore(store, 0)
+	err = s.AddScript("a", src, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	f := NewBaseProcesser("feeder")
+	procs := NewSyncProcessers()
+	procs.Adds(f, s)
+	err = procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// the sub-account is restored before Init
+	pos, price := s.vms["a"].wrap.Position()
+	if pos != 2 || price != 100 {
+		t.Fatalf("restore account error: %f %f", pos, price)
+	}
+	f.Send("a-abcd1234", EventTrade, &Trade{ID: "a-abcd1234", Action: CloseLong, Price: 110, Amount: 1})
+	procs.Stop()
+	var a PnL
+	err = json.Unmarshal(store[accountKey("a")], &a)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if a.Script != "a" || a.Position != 1 || a.AvgPrice != 100 || a.Realized != 15 || a.Trades != 4 {
+		t.Fatalf("save account error: %+v", a)
+	}
+}
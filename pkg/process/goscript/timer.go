@@ -0,0 +1,51 @@
+package goscript
+
+import (
+	"time"
+
+	. "github.com/ztrade/ztrade/pkg/event"
+	"github.com/ztrade/ztrade/pkg/process/goscript/engine"
+)
+
+// timerInterval the precision of timers in real trade
+const timerInterval = time.Second
+
+// simulated check if the clock is driven by the candles
+func (s *GoEngine) simulated() bool {
+	_, ok := s.Bus.Clock().(*SimClock)
+	return ok
+}
+
+// fireTimers call OnTimer of the scripts whose timers are due at now, must be called with s.mutex locked
+func (s *GoEngine) fireTimers(now time.Time) {
+	for _, v := range s.engine.DueTimers(now) {
+		vm, ok := s.vms[v.VmID]
+		if !ok {
+			continue
+		}
+		if _, ok = vm.Runner.(engine.TimerRunner); !ok {
+			continue
+		}
+		id := v.ID
+		s.call(v.VmID, vm, "OnTimer", func(r engine.Runner) {
+			r.(engine.TimerRunner).OnTimer(id)
+		})
+	}
+}
+
+// timerRoutine fire the timers by wall clock in real trade
+func (s *GoEngine) timerRoutine() {
+	closeCh := s.closeCh
+	ticker := time.NewTicker(timerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closeCh:
+			return
+		case now := <-ticker.C:
+			s.mutex.Lock()
+			s.fireTimers(now)
+			s.mutex.Unlock()
+		}
+	}
+}
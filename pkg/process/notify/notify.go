@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"text/template"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/ztrade/exchange"
 	. "github.com/ztrade/trademodel"
 	. "github.com/ztrade/ztrade/pkg/core"
@@ -57,11 +56,12 @@ func NewNotify(cfg exchange.Config) (n *Notify, err error) {
 
 func (n *Notify) Init(bus *Bus) (err error) {
 	n.BaseProcesser.Init(bus)
-	n.Subscribe(EventTrade, n.OnEventTrade)
-	n.Subscribe(EventOrder, n.OnEventOrder)
-	n.Subscribe(EventBalance, n.OnEventBalance)
-	n.Subscribe(EventNotify, n.OnEventNotify)
-	return
+	return errors.Join(
+		SubscribeData(n, EventTrade, n.OnEventTrade),
+		SubscribeData(n, EventOrder, n.OnEventOrder),
+		SubscribeData(n, EventBalance, n.OnEventBalance),
+		SubscribeData(n, EventNotify, n.OnEventNotify),
+	)
 }
 
 func (n *Notify) Start() (err error) {
@@ -72,52 +72,31 @@ func (n *Notify) Stop() (err error) {
 	return
 }
 
-func (n *Notify) OnEventTrade(e *Event) (err error) {
+func (n *Notify) OnEventTrade(e *Event, t *Trade) (err error) {
 	if !n.cfg.Notify.Trade {
 		return nil
 	}
-	t := e.GetData().(*Trade)
-	if t == nil {
-		err = fmt.Errorf("notify OnEventTrade type error:%#v", e.GetData())
-		log.Error(err.Error())
-		return
-	}
 	msg := fmt.Sprintf("%s price: %f, amount: %f", t.Action.String(), t.Price, t.Amount)
 	return n.SendNotify(&NotifyEvent{Title: "Trade", Content: msg})
 }
 
-func (n *Notify) OnEventOrder(e *Event) (err error) {
+func (n *Notify) OnEventOrder(e *Event, act *TradeAction) (err error) {
 	if !n.cfg.Notify.Order {
 		return nil
 	}
-	act := e.GetData().(*TradeAction)
-	if act == nil {
-		log.Errorf("Notify decode tradeaction error: %##v", e.GetData())
-		return
-	}
 	msg := fmt.Sprintf("%s %s price: %f, amount: %f", act.Symbol, act.Action.String(), act.Price, act.Amount)
 	return n.SendNotify(&NotifyEvent{Title: "Order", Content: msg})
 }
 
-func (n *Notify) OnEventBalance(e *Event) (err error) {
+func (n *Notify) OnEventBalance(e *Event, balance *Balance) (err error) {
 	if !n.cfg.Notify.Blance {
 		return nil
 	}
-	balance, ok := e.GetData().(*Balance)
-	if !ok {
-		log.Errorf("Notify OnEventBalance type error: %##v", e.GetData())
-		return
-	}
 	msg := fmt.Sprintf("%s: %f", balance.Currency, balance.Balance)
 	return n.SendNotify(&NotifyEvent{Title: "Blance", Content: msg})
 }
 
-func (n *Notify) OnEventNotify(e *Event) (err error) {
-	nEvent, ok := e.GetData().(*NotifyEvent)
-	if !ok {
-		log.Errorf("Notify OnEventNotify type error: %##v", e.GetData())
-		return
-	}
+func (n *Notify) OnEventNotify(e *Event, nEvent *NotifyEvent) (err error) {
 	return n.SendNotify(nEvent)
 }
 
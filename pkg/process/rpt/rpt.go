@@ -1,6 +1,7 @@
 package rpt
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -17,50 +18,133 @@ type Reporter interface {
 	OnTrade(Trade)
 	OnBalanceInit(balance, fee float64) (err error)
 	SetLever(float64)
+	// OnMark update the market price, used to calculate the mark-to-market equity
+	OnMark(t time.Time, price float64)
+}
+
+// VenueReporter reporter support trades of extra exchange venues
+type VenueReporter interface {
+	OnVenueTrade(venue string, t Trade)
+}
+
+// CandleReporter reporter need the whole candles instead of the mark prices
+type CandleReporter interface {
+	OnCandle(candle Candle)
+}
+
+// PlotReporter reporter support drawing the values plotted by scripts
+type PlotReporter interface {
+	OnPlot(data PlotData)
+}
+
+// SummaryReporter reporter support the daily summary sent by notify
+type SummaryReporter interface {
+	Summary() (title, content string)
 }
 
 type Rpt struct {
 	BaseProcesser
-	rpt Reporter
+	rpt    Reporter
+	venues map[string]bool
+	// send the summary every day at the offset of 00:00 UTC, <0 means disabled
+	summaryAt time.Duration
+	stop      chan struct{}
 }
 
 func NewRpt(rpt Reporter) *Rpt {
 	r := new(Rpt)
 	r.rpt = rpt
+	r.venues = make(map[string]bool)
+	r.summaryAt = -1
 	return r
 }
 
+// SetDailySummary send the summary of reporter to notify every day at the offset of 00:00 UTC
+// only works if the reporter is SummaryReporter
+func (rpt *Rpt) SetDailySummary(at time.Duration) {
+	rpt.summaryAt = at
+}
+
+// AddVenue add an extra exchange venue, its trades are reported separately
+func (rpt *Rpt) AddVenue(venue string) {
+	rpt.venues[venue] = true
+}
+
 func (rpt *Rpt) Init(bus *Bus) (err error) {
 	rpt.BaseProcesser.Init(bus)
-	rpt.Subscribe(EventTrade, rpt.OnEventTrade)
-	rpt.Subscribe(EventBalanceInit, rpt.OnEventBalanceInit)
-	rpt.Subscribe(EventRiskLimit, rpt.OnEventRiskLimit)
+	err = errors.Join(
+		SubscribeData(rpt, EventTrade, rpt.OnEventTrade),
+		SubscribeData(rpt, EventBalanceInit, rpt.OnEventBalanceInit),
+		SubscribeData(rpt, EventRiskLimit, rpt.OnEventRiskLimit),
+		SubscribeData(rpt, EventCandle, rpt.OnEventCandle),
+	)
+	if err != nil {
+		return
+	}
+	if _, ok := rpt.rpt.(PlotReporter); ok {
+		err = SubscribeData(rpt, EventPlot, rpt.OnEventPlot)
+	}
 	return
 }
 
 func (rpt *Rpt) Start() (err error) {
+	sr, ok := rpt.rpt.(SummaryReporter)
+	if !ok || rpt.summaryAt < 0 {
+		return
+	}
+	rpt.stop = make(chan struct{})
+	go rpt.runSummary(sr, rpt.stop)
 	return
 }
 
 func (rpt *Rpt) Stop() (err error) {
+	if rpt.stop != nil {
+		close(rpt.stop)
+		rpt.stop = nil
+	}
 	return
 }
 
-func (rpt *Rpt) OnEventTrade(e *Event) (err error) {
-	t := e.GetData().(*Trade)
+func (rpt *Rpt) runSummary(sr SummaryReporter, stop chan struct{}) {
+	for {
+		now := time.Now()
+		next := now.UTC().Truncate(time.Hour * 24).Add(rpt.summaryAt)
+		for !next.After(now) {
+			next = next.Add(time.Hour * 24)
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		title, content := sr.Summary()
+		rpt.Send("summary", EventNotify, &NotifyEvent{Title: title, Type: "text", Content: content})
+	}
+}
+
+func (rpt *Rpt) OnEventTrade(e *Event, t *Trade) (err error) {
 	if t == nil {
 		err = fmt.Errorf("rpt OnEventTrade type error:%#v", e.GetData())
 		log.Error(err.Error())
 		return
 	}
-	if rpt.rpt != nil {
-		rpt.rpt.OnTrade(*t)
+	if rpt.rpt == nil {
+		return
 	}
+	if rpt.venues[e.GetAccount()] {
+		vr, ok := rpt.rpt.(VenueReporter)
+		if ok {
+			vr.OnVenueTrade(e.GetAccount(), *t)
+		}
+		return
+	}
+	rpt.rpt.OnTrade(*t)
 	return
 }
 
-func (rpt *Rpt) OnEventBalanceInit(e *Event) (err error) {
-	balance := e.GetData().(*BalanceInfo)
+func (rpt *Rpt) OnEventBalanceInit(e *Event, balance *BalanceInfo) (err error) {
 	if balance == nil {
 		err = fmt.Errorf("Rpt onEventBalanceInit error %w", err)
 		log.Error(err.Error())
@@ -71,8 +155,7 @@ func (rpt *Rpt) OnEventBalanceInit(e *Event) (err error) {
 	}
 	return
 }
-func (rpt *Rpt) OnEventRiskLimit(e *Event) (err error) {
-	info := e.GetData().(*RiskLimit)
+func (rpt *Rpt) OnEventRiskLimit(e *Event, info *RiskLimit) (err error) {
 	if info == nil {
 		err = fmt.Errorf("Rpt OnEventRiskLimit error %w", err)
 		log.Error(err.Error())
@@ -83,3 +166,21 @@ func (rpt *Rpt) OnEventRiskLimit(e *Event) (err error) {
 	}
 	return
 }
+
+func (rpt *Rpt) OnEventCandle(e *Event, candle *Candle) (err error) {
+	// candles of extra venues and history candles are not marked
+	if rpt.rpt == nil || rpt.venues[e.GetAccount()] || candle.ID == -1 {
+		return
+	}
+	if cr, ok := rpt.rpt.(CandleReporter); ok {
+		cr.OnCandle(*candle)
+		return
+	}
+	rpt.rpt.OnMark(time.Unix(candle.Start, 0), candle.Close)
+	return
+}
+
+func (rpt *Rpt) OnEventPlot(e *Event, data *PlotData) (err error) {
+	rpt.rpt.(PlotReporter).OnPlot(*data)
+	return
+}
@@ -2,11 +2,10 @@ package vex
 
 import (
 	"container/list"
+	"errors"
 	"fmt"
 	"math"
-	"reflect"
 	"sync"
-	"time"
 
 	"github.com/ztrade/base/common"
 	"github.com/ztrade/trademodel"
@@ -17,6 +16,16 @@ import (
 	. "github.com/ztrade/trademodel"
 )
 
+// balancer balance of futures or spot
+type balancer interface {
+	Set(balance float64)
+	SetFee(fee float64)
+	SetLever(lever float64)
+	Get() float64
+	Pos() float64
+	AddTrade(tr Trade) (profit, profitRate, fee float64, err error)
+}
+
 // VExchange Virtual exchange impl FuturesBaseExchanger
 type VExchange struct {
 	BaseProcesser
@@ -25,10 +34,11 @@ type VExchange struct {
 	orders   *list.List
 	position float64
 	symbol   string
-	balance  *common.LeverBalance
-	// order index in same candle
-	orderIndex int
+	balance  balancer
+	// not nil in spot mode
+	spot       *SpotBalance
 	orderMutex sync.Mutex
+	symbolInfo *SymbolInfo
 }
 
 func NewVExchange(symbol string) *VExchange {
@@ -40,17 +50,48 @@ func NewVExchange(symbol string) *VExchange {
 	return ex
 }
 
+// SetSpot use spot balance, short is only allowed when margin enabled
+func (ex *VExchange) SetSpot(margin bool) (err error) {
+	base, quote, err := SplitSpotSymbol(ex.symbol)
+	if err != nil {
+		return
+	}
+	ex.spot = NewSpotBalance(base, quote)
+	ex.spot.SetMargin(margin)
+	ex.balance = ex.spot
+	return
+}
+
+// sendBalance send balance, every currency is sent in spot mode
+func (ex *VExchange) sendBalance() {
+	if ex.spot == nil {
+		ex.Send(ex.symbol, EventBalance, &Balance{Currency: ex.symbol, Balance: ex.balance.Get()})
+		return
+	}
+	ex.SendWithExtra(ex.spot.BaseCurrency, EventBalance, &Balance{Currency: ex.spot.BaseCurrency, Balance: ex.spot.Base, Available: ex.spot.Base}, BalanceAsset)
+	ex.Send(ex.spot.QuoteCurrency, EventBalance, &Balance{Currency: ex.spot.QuoteCurrency, Balance: ex.spot.Quote, Available: ex.spot.Quote})
+}
+
+// SetSymbolInfo set symbol info, orders are rounded and checked by it
+func (ex *VExchange) SetSymbolInfo(si *SymbolInfo) {
+	ex.symbolInfo = si
+}
+
 func (b *VExchange) Init(bus *Bus) (err error) {
 	b.BaseProcesser.Init(bus)
-	b.Subscribe(EventCandle, b.onEventCandle)
-	b.Subscribe(EventOrder, b.onEventOrder)
-	b.Subscribe(EventBalanceInit, b.onEventBalanceInit)
-	b.Subscribe(EventRiskLimit, b.onEventRiskLimit)
-	return
+	return errors.Join(
+		SubscribeData(b, EventCandle, b.onEventCandle),
+		SubscribeData(b, EventOrder, b.onEventOrder),
+		SubscribeData(b, EventBalanceInit, b.onEventBalanceInit),
+		SubscribeData(b, EventRiskLimit, b.onEventRiskLimit),
+	)
 }
 
 func (ex *VExchange) Start() (err error) {
-	ex.Send(ex.symbol, EventBalance, &Balance{Balance: ex.balance.Get()})
+	if ex.symbolInfo != nil {
+		ex.Send(ex.symbol, EventSymbolInfo, ex.symbolInfo)
+	}
+	ex.sendBalance()
 	return
 }
 func (ex *VExchange) processCandle(candle Candle) (err error) {
@@ -62,7 +103,10 @@ func (ex *VExchange) processCandle(candle Candle) (err error) {
 	defer ex.orderMutex.Unlock()
 	var posChange bool
 	var deleteElems []*list.Element
-	virtualTime := candle.Time()
+	// the first error of rejected orders, returned after the fills are sent
+	var failErr error
+	// the clock is at the end of candle, all trades of the candle have the same time
+	tradeTime := ex.Now()
 	var trades []*Event
 	var pos Position
 	var orderFilled bool
@@ -131,10 +175,9 @@ func (ex *VExchange) processCandle(candle Candle) (err error) {
 			continue
 		}
 
-		virtualTime = virtualTime.Add(time.Second)
 		tr := Trade{ID: fmt.Sprintf("%d", len(ex.trades)),
 			Action: v.Action,
-			Time:   virtualTime,
+			Time:   tradeTime,
 			Price:  price,
 			Amount: v.Amount,
 			Side:   side,
@@ -142,11 +185,24 @@ func (ex *VExchange) processCandle(candle Candle) (err error) {
 		if v.ID != "" {
 			tr.ID = v.ID
 		}
-		// fix size
-		_, _, _, err = ex.balance.AddTrade(tr)
+		// the sell amount of spot may be clipped to the hold
+		err = nil
+		if ex.spot != nil && !tr.Action.IsLong() {
+			tr.Amount, err = ex.spot.SellAmount(tr.Amount)
+		}
+		if err == nil {
+			_, _, _, err = ex.balance.AddTrade(tr)
+		}
 		if err != nil {
-			// log.Errorf("vexchange balance AddTrade error:%s %f %f", err.Error(), v.Price, v.Amount)
-			return
+			// reject this order only, the fills before it are still sent
+			log.Warnf("vexchange reject order %s: %s", v.ID, err.Error())
+			trades = append(trades, ex.CreateEvent(v.ID, EventTrade, failedTrade(v, err)))
+			deleteElems = append(deleteElems, elem)
+			// short of spot is only rejected, the backtest goes on
+			if failErr == nil && !errors.Is(err, ErrShortNotAllowed) {
+				failErr = err
+			}
+			continue
 		}
 		ex.trades = append(ex.trades, tr)
 		tradeEvent := ex.CreateEvent("trade", EventTrade, &tr)
@@ -160,88 +216,110 @@ func (ex *VExchange) processCandle(candle Candle) (err error) {
 	for _, v := range deleteElems {
 		ex.orders.Remove(v)
 	}
-	// keep trade time order
-	if len(trades) != 0 {
-		for i := len(trades) - 1; i >= 0; i-- {
-			ex.Bus.Send(trades[i])
-		}
+	// keep the order of fills, the trades have the same time
+	for _, v := range trades {
+		ex.Bus.Send(v)
 	}
 	if posChange {
 		pos.Symbol = ex.symbol
 		pos.Hold = ex.position
 		//		ex.Send(ex.symbol, EventCurPosition, pos)
 		ex.Send(ex.symbol, EventPosition, &pos)
-		if pos.Hold == 0 {
-			ex.Send(ex.symbol, EventBalance, &Balance{Currency: ex.symbol, Balance: ex.balance.Get()})
+		if pos.Hold == 0 || ex.spot != nil {
+			ex.sendBalance()
 		}
 	}
-	return nil
+	return failErr
 }
 
-func (ex *VExchange) onEventCandle(e *Event) (err error) {
-	candle, ok := e.GetData().(*Candle)
-	if !ok {
-		err = fmt.Errorf("VExchange candle type error:%s", reflect.TypeOf(e.GetData()))
-		return
-	}
+func (ex *VExchange) onEventCandle(e *Event, candle *Candle) (err error) {
 	// fmt.Println("candle:", e.Name, e.GetType(), e.GetData())
-	binSize := e.GetExtra().(string)
+	binSize, _ := ExtraAs[string](e)
 	if binSize != "1m" {
 		return
 	}
 
 	ex.candle = candle
-	ex.orderIndex = 0
 	err = ex.processCandle(*candle)
 	return
 }
 
-func (ex *VExchange) onEventOrder(e *Event) (err error) {
+// cancelOrders remove the canceled orders, order_canceled is sent for every one like the real exchange
+func (ex *VExchange) cancelOrders(act *TradeAction) {
+	var canceled []TradeAction
 	ex.orderMutex.Lock()
-	defer ex.orderMutex.Unlock()
-	act := e.GetData().(*TradeAction)
-	if act == nil {
-		log.Errorf("decode tradeaction error: %##v", e.GetData())
-		return
+	for item := ex.orders.Front(); item != nil; {
+		next := item.Next()
+		od := item.Value.(TradeAction)
+		if act.Action == trademodel.CancelAll || od.ID == act.ID {
+			ex.orders.Remove(item)
+			canceled = append(canceled, od)
+		}
+		item = next
+	}
+	ex.orderMutex.Unlock()
+	for i := range canceled {
+		ex.Send(canceled[i].ID, EventOrderCanceled, &canceled[i])
 	}
-	if act.Action == trademodel.CancelAll {
-		ex.orders = list.New()
+}
+
+func (ex *VExchange) onEventOrder(e *Event, act *TradeAction) (err error) {
+	if act.Action == trademodel.CancelAll || act.Action == trademodel.CancelOne {
+		ex.cancelOrders(act)
 		return
-	} else if act.Action == trademodel.CancelOne {
-		for item := ex.orders.Front(); item != nil; item.Next() {
-			od := item.Value.(TradeAction)
-			if od.ID == act.ID {
-				ex.orders.Remove(item)
-				return
-			}
+	}
+	// the event data is shared by all subscribers, fix and stamp a copy
+	order := *act
+	order.Time = ex.Now()
+	if ex.symbolInfo != nil {
+		err = ex.symbolInfo.FixOrder(&order)
+		if err != nil {
+			log.Warnf("invalid order,action: %#v, error: %s", order, err.Error())
+			ex.sendFailed(order, err)
+			err = nil
+			return
 		}
-		return
 	}
 	if ex.candle != nil {
-		act.Time = ex.candle.Time().Add(time.Second * time.Duration(ex.orderIndex))
-		if act.Action == StopLong && act.Price >= ex.candle.Close {
-			log.Warnf("invalid stop long order,action: %#v, candle: %s", *act, *ex.candle)
+		if order.Action == StopLong && order.Price >= ex.candle.Close {
+			log.Warnf("invalid stop long order,action: %#v, candle: %s", order, *ex.candle)
+			ex.sendFailed(order, fmt.Errorf("stop long price %f >= close %f", order.Price, ex.candle.Close))
 			return
-		} else if act.Action == StopShort && act.Price <= ex.candle.Close {
-			log.Warnf("invalid stop short order,action: %#v, candle: %s", *act, *ex.candle)
+		} else if order.Action == StopShort && order.Price <= ex.candle.Close {
+			log.Warnf("invalid stop short order,action: %#v, candle: %s", order, *ex.candle)
+			ex.sendFailed(order, fmt.Errorf("stop short price %f <= close %f", order.Price, ex.candle.Close))
 			return
 		}
 	}
-	ex.orderIndex++
-	ex.orders.PushBack(*act)
+	ex.orderMutex.Lock()
+	ex.orders.PushBack(order)
+	ex.orderMutex.Unlock()
 	return
 }
 
-func (ex *VExchange) onEventBalanceInit(e *Event) (err error) {
-	balance := e.GetData().(*BalanceInfo)
+// failedTrade the failed trade of the rejected order like the real exchange
+func failedTrade(act TradeAction, err error) *Trade {
+	return &Trade{ID: act.ID,
+		Action: act.Action,
+		Time:   act.Time,
+		Price:  act.Price,
+		Amount: act.Amount,
+		Remark: "failed:" + err.Error()}
+}
+
+// sendFailed send the failed trade of the rejected order
+func (ex *VExchange) sendFailed(act TradeAction, err error) {
+	ex.Send(act.ID, EventTrade, failedTrade(act, err))
+}
+
+func (ex *VExchange) onEventBalanceInit(e *Event, balance *BalanceInfo) (err error) {
 	ex.balance.Set(balance.Balance)
 	ex.balance.SetFee(balance.Fee)
-	ex.Send(ex.symbol, EventBalance, &Balance{Currency: ex.symbol, Balance: ex.balance.Get()})
+	ex.sendBalance()
 	return
 }
 
-func (ex *VExchange) onEventRiskLimit(e *Event) (err error) {
-	info := e.GetData().(*RiskLimit)
+func (ex *VExchange) onEventRiskLimit(e *Event, info *RiskLimit) (err error) {
 	if info == nil {
 		err = fmt.Errorf("VExchange OnEventRiskLimit error %w", err)
 		log.Error(err.Error())
@@ -255,7 +333,7 @@ func (ex *VExchange) CloseAll() (err error) {
 	if ex.position == 0 {
 		return
 	}
-	virtualTime := ex.candle.Time().Add(time.Second)
+	virtualTime := ex.Now()
 	var tr Trade
 	if ex.position > 0 {
 		tr = Trade{ID: fmt.Sprintf("%d", len(ex.trades)),
@@ -287,8 +365,8 @@ func (ex *VExchange) CloseAll() (err error) {
 	pos.Hold = ex.position
 	//		ex.Send(ex.symbol, EventCurPosition, pos)
 	ex.Send(ex.symbol, EventPosition, &pos)
-	if pos.Hold == 0 {
-		ex.Send(ex.symbol, EventBalance, &Balance{Currency: ex.symbol, Balance: ex.balance.Get()})
+	if pos.Hold == 0 || ex.spot != nil {
+		ex.sendBalance()
 	}
 	return
 }
@@ -0,0 +1,162 @@
+package vex
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+	. "github.com/ztrade/ztrade/pkg/core"
+	. "github.com/ztrade/ztrade/pkg/event"
+)
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// recorder send the events to VExchange and record the trades
+type recorder struct {
+	BaseProcesser
+	trades   []Trade
+	canceled []string
+	pos      float64
+}
+
+func (r *recorder) Init(bus *Bus) (err error) {
+	r.BaseProcesser.Init(bus)
+	SubscribeData(r, EventTrade, func(e *Event, tr *Trade) error {
+		r.trades = append(r.trades, *tr)
+		return nil
+	})
+	SubscribeData(r, EventOrderCanceled, func(e *Event, act *TradeAction) error {
+		r.canceled = append(r.canceled, act.ID)
+		return nil
+	})
+	SubscribeData(r, EventPosition, func(e *Event, pos *Position) error {
+		r.pos = pos.Hold
+		return nil
+	})
+	return
+}
+
+func (r *recorder) candle(n int, price float64) {
+	r.SendWithExtra("candle", EventCandle, &Candle{Start: testStart.Add(time.Minute * time.Duration(n)).Unix(), Open: price, High: price + 1, Low: price - 1, Close: price}, "1m")
+}
+
+func newTestVExchange(t *testing.T, spot bool) (ex *VExchange, r *recorder) {
+	ex = NewVExchange("BTCUSDT")
+	if spot {
+		err := ex.SetSpot(false)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	r = &recorder{BaseProcesser: BaseProcesser{Name: "recorder"}}
+	procs := NewSyncProcessers()
+	procs.SetClock(NewSimClock())
+	procs.Adds(r, ex)
+	err := procs.Start()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() { procs.Stop() })
+	r.Send("balance_init", EventBalanceInit, &BalanceInfo{Balance: 10000})
+	return
+}
+
+func TestInvalidOrder(t *testing.T) {
+	ex, r := newTestVExchange(t, true)
+	ex.SetSymbolInfo(&SymbolInfo{Symbol: "BTCUSDT", TickSize: 0.1, LotSize: 0.01, MinQty: 0.1})
+	r.candle(0, 100)
+	small := &TradeAction{ID: "small", Action: OpenLong, Price: 100, Amount: 0.05}
+	r.Send(EventOrder, EventOrder, small)
+	if len(r.trades) != 1 || !TradeFailed(&r.trades[0]) || r.trades[0].ID != "small" {
+		t.Fatalf("failed trade not sent: %#v", r.trades)
+	}
+	act := &TradeAction{ID: "ok", Action: OpenLong, Price: 100.04, Amount: 1.234}
+	r.Send(EventOrder, EventOrder, act)
+	if act.Price != 100.04 || act.Amount != 1.234 || !act.Time.IsZero() {
+		t.Fatalf("order payload is changed: %#v", act)
+	}
+	r.candle(1, 100)
+	if len(r.trades) != 2 || r.trades[1].Price != 100 || r.trades[1].Amount != 1.23 {
+		t.Fatalf("fixed order not filled: %#v", r.trades)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	_, r := newTestVExchange(t, true)
+	r.candle(0, 100)
+	for _, v := range []string{"a", "b", "c"} {
+		r.Send(EventOrder, EventOrder, &TradeAction{ID: v, Action: OpenLong, Price: 50, Amount: 1})
+	}
+	r.Send(EventOrder, EventOrder, &TradeAction{ID: "b", Action: CancelOne})
+	r.Send(EventOrder, EventOrder, &TradeAction{Action: CancelAll})
+	if len(r.canceled) != 3 || r.canceled[0] != "b" || r.canceled[1] != "a" || r.canceled[2] != "c" {
+		t.Fatalf("cancel not confirmed: %#v", r.canceled)
+	}
+}
+
+func TestSpotSellClipped(t *testing.T) {
+	_, r := newTestVExchange(t, true)
+	r.Send("balance_init", EventBalanceInit, &BalanceInfo{Balance: 10000, Fee: 0.001})
+	r.candle(0, 100)
+	r.Send(EventOrder, EventOrder, &TradeAction{ID: "buy", Action: OpenLong, Price: 100, Amount: 1})
+	r.candle(1, 100)
+	if r.pos != 0.999 {
+		t.Fatalf("hold error: %f", r.pos)
+	}
+	// the fee of buy is charged in base, the sell is clipped to the hold
+	r.Send(EventOrder, EventOrder, &TradeAction{ID: "sell", Action: CloseLong, Price: 100, Amount: 1})
+	r.candle(2, 100)
+	if len(r.trades) != 2 || r.trades[1].Amount != 0.999 || r.pos != 0 {
+		t.Fatalf("sell not clipped: %#v, hold: %f", r.trades, r.pos)
+	}
+}
+
+func TestTradeOrder(t *testing.T) {
+	_, r := newTestVExchange(t, true)
+	r.candle(0, 100)
+	for _, v := range []string{"a", "b", "c"} {
+		r.Send(EventOrder, EventOrder, &TradeAction{ID: v, Action: OpenLong, Price: 100, Amount: 1})
+	}
+	r.candle(1, 100)
+	// the trades filled by the same candle are sent in the order of fills
+	if len(r.trades) != 3 || r.trades[0].ID != "a" || r.trades[1].ID != "b" || r.trades[2].ID != "c" {
+		t.Fatalf("trade order error: %#v", r.trades)
+	}
+}
+
+func TestRejectInCandle(t *testing.T) {
+	_, r := newTestVExchange(t, true)
+	r.Send("balance_init", EventBalanceInit, &BalanceInfo{Balance: 1000})
+	r.candle(0, 100)
+	for _, v := range []struct {
+		id     string
+		amount float64
+	}{{"a", 5}, {"b", 10}, {"c", 1}} {
+		r.Send(EventOrder, EventOrder, &TradeAction{ID: v.id, Action: OpenLong, Price: 100, Amount: v.amount})
+	}
+	r.candle(1, 100)
+	// only the order without balance is rejected, the others are filled
+	if len(r.trades) != 3 || r.trades[0].ID != "a" || !TradeFailed(&r.trades[1]) || r.trades[1].ID != "b" || r.trades[2].ID != "c" {
+		t.Fatalf("trades error: %#v", r.trades)
+	}
+	r.candle(2, 100)
+	if len(r.trades) != 3 || r.pos != 6 {
+		t.Fatalf("orders filled again: %#v, hold: %f", r.trades, r.pos)
+	}
+}
+
+func TestSpotShortInCandle(t *testing.T) {
+	_, r := newTestVExchange(t, true)
+	r.candle(0, 100)
+	r.Send(EventOrder, EventOrder, &TradeAction{ID: "buy", Action: OpenLong, Price: 100, Amount: 1})
+	r.Send(EventOrder, EventOrder, &TradeAction{ID: "short", Action: OpenShort, Price: 100, Amount: 2})
+	r.candle(1, 100)
+	// selling more than the hold of spot is rejected with a failed trade
+	if len(r.trades) != 2 || r.trades[0].ID != "buy" || r.trades[1].ID != "short" || !TradeFailed(&r.trades[1]) {
+		t.Fatalf("trades error: %#v", r.trades)
+	}
+	r.candle(2, 100)
+	if len(r.trades) != 2 || r.pos != 1 {
+		t.Fatalf("short filled again: %#v, hold: %f", r.trades, r.pos)
+	}
+}
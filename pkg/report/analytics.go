@@ -0,0 +1,176 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ztrade/base/common"
+	. "github.com/ztrade/trademodel"
+	"github.com/ztrade/ztrade/pkg/core"
+)
+
+// pricePoint high and low of one candle
+type pricePoint struct {
+	Time int64
+	High float64
+	Low  float64
+}
+
+// BucketStat statistics of the round trips opened in one weekday or hour
+type BucketStat struct {
+	Kind    string // weekday or hour
+	Key     string
+	Trades  int
+	WinRate float64
+	Profit  float64
+}
+
+// OnCandle record the price path to analyze the excursions of trades
+func (r *Report) OnCandle(candle Candle) {
+	r.OnMark(time.Unix(candle.Start, 0), candle.Close)
+	r.path = append(r.path, pricePoint{Time: candle.Start, High: candle.High, Low: candle.Low})
+}
+
+// priceRange return the highest and lowest price in [start, end]
+func (r *Report) priceRange(start, end time.Time) (high, low float64, ok bool) {
+	i := sort.Search(len(r.path), func(i int) bool {
+		return r.path[i].Time >= start.Unix()
+	})
+	for ; i < len(r.path) && r.path[i].Time <= end.Unix(); i++ {
+		if !ok {
+			high, low, ok = r.path[i].High, r.path[i].Low, true
+			continue
+		}
+		high = max(high, r.path[i].High)
+		low = min(low, r.path[i].Low)
+	}
+	return
+}
+
+// analyzeRounds fill the excursions, holding time and exit efficiency of every round trip,
+// and the distributions by the weekday and hour of open time
+func (r *Report) analyzeRounds(metrics *ReportResult) {
+	weekdays := make([]BucketStat, 7)
+	for i := range weekdays {
+		weekdays[i] = BucketStat{Kind: "weekday", Key: time.Weekday(i).String()}
+	}
+	hours := make([]BucketStat, 24)
+	for i := range hours {
+		hours[i] = BucketStat{Kind: "hour", Key: time.Date(0, 1, 1, i, 0, 0, 0, time.UTC).Format("15:04")}
+	}
+	var rounds, excursions int
+	var sumMAE, sumMFE, sumEfficiency float64
+	var sumHolding time.Duration
+	start := 0
+	for i, act := range r.tmplDatas {
+		if !act.IsFinish {
+			continue
+		}
+		round := r.tmplDatas[start : i+1]
+		start = i + 1
+		first := round[0]
+		isLong := first.Action.IsLong()
+		var openValue, openAmount, closeValue, closeAmount float64
+		for _, v := range round {
+			if v.Action.IsLong() == isLong {
+				openValue += v.Price * v.Amount
+				openAmount += v.Amount
+			} else {
+				closeValue += v.Price * v.Amount
+				closeAmount += v.Amount
+			}
+		}
+		act.Holding = int64(act.Time.Sub(first.Time).Seconds())
+		sumHolding += act.Time.Sub(first.Time)
+		rounds++
+		// buckets are in UTC, the same as the report and the daily summary
+		openTime := first.Time.UTC()
+		for _, b := range []*BucketStat{&weekdays[openTime.Weekday()], &hours[openTime.Hour()]} {
+			b.Trades++
+			b.Profit += act.Profit
+			if act.Profit > 0 {
+				b.WinRate++
+			}
+		}
+
+		high, low, ok := r.priceRange(first.Time, act.Time)
+		if !ok || openAmount == 0 || closeAmount == 0 {
+			continue
+		}
+		entry := openValue / openAmount
+		exit := closeValue / closeAmount
+		high = max(high, entry, exit)
+		low = min(low, entry, exit)
+		if isLong {
+			act.MAE = (entry - low) / entry
+			act.MFE = (high - entry) / entry
+		} else {
+			act.MAE = (high - entry) / entry
+			act.MFE = (entry - low) / entry
+		}
+		if high > low {
+			if isLong {
+				act.ExitEfficiency = (exit - low) / (high - low)
+			} else {
+				act.ExitEfficiency = (high - exit) / (high - low)
+			}
+		}
+		act.MAE = common.FormatFloat(act.MAE, 6)
+		act.MFE = common.FormatFloat(act.MFE, 6)
+		act.ExitEfficiency = common.FormatFloat(act.ExitEfficiency, 4)
+		sumMAE += act.MAE
+		sumMFE += act.MFE
+		sumEfficiency += act.ExitEfficiency
+		excursions++
+	}
+	if rounds > 0 {
+		metrics.AvgHolding = (sumHolding / time.Duration(rounds)).Truncate(time.Second)
+	}
+	if excursions > 0 {
+		metrics.AvgMAE = common.FormatFloat(sumMAE/float64(excursions), 6)
+		metrics.AvgMFE = common.FormatFloat(sumMFE/float64(excursions), 6)
+		metrics.AvgExitEfficiency = common.FormatFloat(sumEfficiency/float64(excursions), 4)
+	}
+	metrics.ByWeekday = finishBuckets(weekdays)
+	metrics.ByHour = finishBuckets(hours)
+}
+
+// analyzeScripts split the pnl by the scripts sent the orders, only if trades are from more than one script
+func (r *Report) analyzeScripts(metrics *ReportResult) {
+	scripts := make(map[string]*core.PnL)
+	var names []string
+	var price float64
+	for _, v := range r.trades {
+		name := core.ScriptOfOrder(v.ID)
+		p, ok := scripts[name]
+		if !ok {
+			p = &core.PnL{Script: name}
+			scripts[name] = p
+			names = append(names, name)
+		}
+		p.AddTrade(v, r.fee)
+		price = v.Price
+	}
+	if len(names) < 2 {
+		return
+	}
+	if n := len(r.marks); n > 0 {
+		price = r.marks[n-1].Price
+	}
+	sort.Strings(names)
+	for _, v := range names {
+		metrics.Scripts = append(metrics.Scripts, roundPnL(scripts[v].Mark(price)))
+	}
+}
+
+func finishBuckets(buckets []BucketStat) (ret []BucketStat) {
+	for _, v := range buckets {
+		if v.Trades == 0 {
+			continue
+		}
+		v.WinRate = common.FormatFloat(v.WinRate/float64(v.Trades), 4)
+		v.Profit = common.FormatFloat(v.Profit, 4)
+		ret = append(ret, v)
+	}
+	return
+}
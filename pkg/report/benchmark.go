@@ -0,0 +1,115 @@
+package report
+
+import (
+	"math"
+	"time"
+
+	"github.com/montanaflynn/stats"
+	"github.com/ztrade/base/common"
+	. "github.com/ztrade/trademodel"
+)
+
+// BuyHoldBenchmark name of the default benchmark, buy and hold the traded symbol
+const BuyHoldBenchmark = "buy&hold"
+
+// SetBenchmark compare the strategy with holding the asset of candles, must be called after SetMarkInterval
+// the traded symbol is used as benchmark if not set
+func (r *Report) SetBenchmark(name string, candles []*Candle) {
+	r.benchmarkName = name
+	r.benchmark = nil
+	interval := r.getMarkInterval()
+	for _, v := range candles {
+		period := time.Unix(v.Start, 0).Truncate(interval)
+		if n := len(r.benchmark); n > 0 && !r.benchmark[n-1].Time.Before(period) {
+			r.benchmark[n-1].Price = v.Close
+			continue
+		}
+		r.benchmark = append(r.benchmark, markPrice{Time: period, Open: v.Open, Price: v.Close})
+	}
+}
+
+// CalculateBenchmarkSeries return the equity of holding the benchmark with the init balance, aligned with the equity series
+func (r *Report) CalculateBenchmarkSeries(series []EquityPoint) (bench []EquityPoint) {
+	prices := r.benchmark
+	if r.benchmarkName == "" {
+		prices = r.marks
+	}
+	if len(prices) == 0 || len(series) == 0 {
+		return
+	}
+	interval := r.getMarkInterval()
+	first := prices[0].Open
+	if first == 0 {
+		return
+	}
+	last := first
+	i := 0
+	for _, v := range series {
+		// the price at the end of period
+		for ; i < len(prices) && prices[i].Time.Add(interval).Compare(v.Time) <= 0; i++ {
+			last = prices[i].Price
+		}
+		bench = append(bench, EquityPoint{Time: v.Time, Equity: r.balanceInit * last / first})
+	}
+	return
+}
+
+// calculateBenchmark compare the equity series with benchmark
+func (r *Report) calculateBenchmark(metrics *ReportResult, series []EquityPoint) {
+	bench := r.CalculateBenchmarkSeries(series)
+	if len(bench) == 0 {
+		return
+	}
+	metrics.Benchmark = r.benchmarkName
+	if metrics.Benchmark == "" {
+		metrics.Benchmark = BuyHoldBenchmark
+	}
+	metrics.BenchmarkReturn = common.FormatFloat(bench[len(bench)-1].Equity/r.balanceInit-1, 4)
+	strategyReturn := series[len(series)-1].Equity/r.balanceInit - 1
+	metrics.ExcessReturn = common.FormatFloat(strategyReturn-metrics.BenchmarkReturn, 4)
+
+	rs := periodReturns(series, r.balanceInit)
+	rb := periodReturns(bench, r.balanceInit)
+	if len(rs) != len(rb) || len(rs) < 2 {
+		return
+	}
+	periodsPerYear := float64(time.Hour*24) * 365.25 / float64(r.getMarkInterval())
+	varB, _ := stats.VarS(rb)
+	cov, _ := stats.Covariance(rs, rb)
+	if varB != 0 {
+		metrics.Beta = common.FormatFloat(cov/varB, 4)
+	}
+	meanS, _ := stats.Mean(rs)
+	meanB, _ := stats.Mean(rb)
+	rf := r.riskFreeRate / periodsPerYear
+	metrics.Alpha = common.FormatFloat((meanS-rf-metrics.Beta*(meanB-rf))*periodsPerYear, 4)
+	metrics.Correlation, _ = stats.Correlation(rs, rb)
+	metrics.Correlation = common.FormatFloat(metrics.Correlation, 4)
+
+	excess := make([]float64, len(rs))
+	for i := range rs {
+		excess[i] = rs[i] - rb[i]
+	}
+	meanE, _ := stats.Mean(excess)
+	trackingError, _ := stats.StandardDeviationSample(excess)
+	if trackingError != 0 {
+		metrics.InformationRatio = common.FormatFloat(meanE/trackingError*math.Sqrt(periodsPerYear), 4)
+	}
+	metrics.UpCapture = common.FormatFloat(captureRatio(rs, rb, true), 4)
+	metrics.DownCapture = common.FormatFloat(captureRatio(rs, rb, false), 4)
+}
+
+// captureRatio the mean return of strategy divided by benchmark in the periods benchmark goes up or down
+func captureRatio(rs, rb []float64, up bool) float64 {
+	var sumS, sumB float64
+	for i := range rb {
+		if (up && rb[i] > 0) || (!up && rb[i] < 0) {
+			sumS += rs[i]
+			sumB += rb[i]
+		}
+	}
+	if sumB == 0 {
+		return 0
+	}
+	return sumS / sumB
+}
@@ -0,0 +1,149 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	. "github.com/ztrade/trademodel"
+)
+
+// maxChartCandles candles are merged if more than this
+const maxChartCandles = 2000
+
+// ChartCandle candle in chart, time is unix seconds
+type ChartCandle struct {
+	Time  int64   `json:"time"`
+	Open  float64 `json:"open"`
+	High  float64 `json:"high"`
+	Low   float64 `json:"low"`
+	Close float64 `json:"close"`
+}
+
+// ChartPoint one point of line in chart
+type ChartPoint struct {
+	Time  int64   `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// ChartMarker trade marker on the candles
+type ChartMarker struct {
+	Time     int64  `json:"time"`
+	Position string `json:"position"`
+	Color    string `json:"color"`
+	Shape    string `json:"shape"`
+	Text     string `json:"text"`
+}
+
+// ChartData datas of the charts in html report
+type ChartData struct {
+	Candles  []ChartCandle
+	Markers  []ChartMarker
+	Equity   []ChartPoint
+	Drawdown []ChartPoint
+	// Benchmark equity of holding the benchmark
+	Benchmark []ChartPoint
+	// Series lines plotted by scripts, drawn over the candles
+	Series map[string][]ChartPoint
+}
+
+// htmlData data of report template
+type htmlData struct {
+	ReportResult
+	Chart ChartData
+}
+
+// SetCandles set the candles of the tested range, they are merged to at most maxChartCandles
+func (r *Report) SetCandles(candles []*Candle) {
+	step := (len(candles) + maxChartCandles - 1) / maxChartCandles
+	if step < 1 {
+		step = 1
+	}
+	r.candles = make([]ChartCandle, 0, len(candles)/step+1)
+	for i := 0; i < len(candles); i += step {
+		c := candles[i]
+		cc := ChartCandle{Time: c.Start, Open: c.Open, High: c.High, Low: c.Low, Close: c.Close}
+		for _, v := range candles[i+1 : min(i+step, len(candles))] {
+			cc.High = max(cc.High, v.High)
+			cc.Low = min(cc.Low, v.Low)
+			cc.Close = v.Close
+		}
+		r.candles = append(r.candles, cc)
+	}
+}
+
+// chartTime align t to the start of merged candle, so the markers and lines match the candles
+func (r *Report) chartTime(t int64) int64 {
+	if len(r.candles) == 0 {
+		return t
+	}
+	n := sort.Search(len(r.candles), func(i int) bool {
+		return r.candles[i].Time > t
+	})
+	if n == 0 {
+		return r.candles[0].Time
+	}
+	return r.candles[n-1].Time
+}
+
+// appendPoint add point to line, keep the last value if the time is the same
+func appendPoint(points []ChartPoint, p ChartPoint) []ChartPoint {
+	if n := len(points); n > 0 && points[n-1].Time >= p.Time {
+		points[n-1].Value = p.Value
+		return points
+	}
+	return append(points, p)
+}
+
+func (r *Report) chartData() (data ChartData) {
+	data.Candles = r.candles
+	for _, v := range r.tmplDatas {
+		m := ChartMarker{Time: r.chartTime(v.Time.Unix()), Text: fmt.Sprintf("%s %g@%g", v.Action, v.Amount, v.Price)}
+		if v.Action.IsLong() {
+			m.Position, m.Color, m.Shape = "belowBar", "#26a69a", "arrowUp"
+		} else {
+			m.Position, m.Color, m.Shape = "aboveBar", "#ef5350", "arrowDown"
+		}
+		data.Markers = append(data.Markers, m)
+	}
+	if series := r.CalculateEquitySeries(); len(series) > 0 {
+		for _, v := range r.CalculateBenchmarkSeries(series) {
+			data.Benchmark = append(data.Benchmark, ChartPoint{Time: v.Time.Unix(), Value: v.Equity})
+		}
+		peak := r.balanceInit
+		for _, v := range series {
+			peak = max(peak, v.Equity)
+			data.Equity = append(data.Equity, ChartPoint{Time: v.Time.Unix(), Value: v.Equity})
+			data.Drawdown = append(data.Drawdown, ChartPoint{Time: v.Time.Unix(), Value: -drawdownPercent(peak, v.Equity)})
+		}
+	} else if equity := r.CalculateEquityCurve(); len(equity) > 0 {
+		t := r.startTime.Unix()
+		if len(r.tmplDatas) > 0 && (r.startTime.IsZero() || r.tmplDatas[0].Time.Before(r.startTime)) {
+			t = r.tmplDatas[0].Time.Unix()
+		}
+		peak := equity[0]
+		for i, v := range equity {
+			if i > 0 {
+				t = r.tmplDatas[i-1].Time.Unix()
+			}
+			peak = max(peak, v)
+			data.Equity = appendPoint(data.Equity, ChartPoint{Time: t, Value: v})
+			data.Drawdown = appendPoint(data.Drawdown, ChartPoint{Time: t, Value: -drawdownPercent(peak, v)})
+		}
+	}
+	data.Series = make(map[string][]ChartPoint)
+	for name, plots := range r.plots {
+		var points []ChartPoint
+		for _, v := range plots {
+			points = appendPoint(points, ChartPoint{Time: r.chartTime(v.Time.Unix()), Value: v.Value})
+		}
+		data.Series[name] = points
+	}
+	return
+}
+
+func drawdownPercent(peak, value float64) float64 {
+	if peak <= 0 {
+		return 0
+	}
+	return (peak - value) / peak * 100
+}
@@ -0,0 +1,143 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/ztrade/ztrade/pkg/core"
+)
+
+//go:embed compare.tmpl
+var compareTmpl string
+
+// CompareRun one backtest run in comparison
+type CompareRun struct {
+	ID        int64
+	Name      string
+	Script    string
+	Param     string
+	Symbol    string
+	StartTime time.Time
+	EndTime   time.Time
+	Result    ReportResult
+	Equity    []EquityPoint
+}
+
+// CompareRow one metric of all runs
+type CompareRow struct {
+	Name   string
+	Values []string
+}
+
+// compareMetrics the metrics in comparison table
+var compareMetrics = []struct {
+	Name  string
+	Value func(r *ReportResult) interface{}
+}{
+	{"Total Actions", func(r *ReportResult) interface{} { return r.TotalAction }},
+	{"Win Rate", func(r *ReportResult) interface{} { return r.WinRate }},
+	{"Total Profit", func(r *ReportResult) interface{} { return r.TotalProfit }},
+	{"Total Return", func(r *ReportResult) interface{} { return r.TotalReturn }},
+	{"Annual Return", func(r *ReportResult) interface{} { return r.AnnualReturn }},
+	{"Max Drawdown", func(r *ReportResult) interface{} { return r.MaxDrawdown }},
+	{"Volatility", func(r *ReportResult) interface{} { return r.Volatility }},
+	{"Sharpe Ratio", func(r *ReportResult) interface{} { return r.SharpeRatio }},
+	{"Sortino Ratio", func(r *ReportResult) interface{} { return r.SortinoRatio }},
+	{"Calmar Ratio", func(r *ReportResult) interface{} { return r.CalmarRatio }},
+	{"Profit Factor", func(r *ReportResult) interface{} { return r.ProfitFactor }},
+	{"Avg Holding", func(r *ReportResult) interface{} { return r.AvgHolding }},
+	{"Avg Exit Efficiency", func(r *ReportResult) interface{} { return r.AvgExitEfficiency }},
+	{"Excess Return", func(r *ReportResult) interface{} { return r.ExcessReturn }},
+	{"Alpha", func(r *ReportResult) interface{} { return r.Alpha }},
+	{"Beta", func(r *ReportResult) interface{} { return r.Beta }},
+	{"Overall Score", func(r *ReportResult) interface{} { return r.OverallScore }},
+}
+
+// FillRun set the result and equity of run, must be called after the report generated
+func (r *Report) FillRun(run *core.BacktestRun) (err error) {
+	run.Result, err = json.Marshal(r.result)
+	if err != nil {
+		return
+	}
+	run.Equity, err = json.Marshal(r.EquitySeries())
+	return
+}
+
+// Result return the result analyzed by GenRPT or GetResult
+func (r *Report) Result() ReportResult {
+	return r.result
+}
+
+// NewCompareRun decode the run saved in registry
+func NewCompareRun(run *core.BacktestRun) (cr CompareRun, err error) {
+	cr = CompareRun{ID: run.ID, Script: run.Script, Param: run.Param, Symbol: run.Symbol, StartTime: run.StartTime, EndTime: run.EndTime}
+	// the name is used as the label of run, keep it unique
+	cr.Name = fmt.Sprintf("#%d", run.ID)
+	if run.Name != "" {
+		cr.Name += " " + run.Name
+	}
+	err = json.Unmarshal(run.Result, &cr.Result)
+	if err != nil {
+		err = fmt.Errorf("decode result of run %d failed: %w", run.ID, err)
+		return
+	}
+	if len(run.Equity) != 0 {
+		err = json.Unmarshal(run.Equity, &cr.Equity)
+		if err != nil {
+			err = fmt.Errorf("decode equity of run %d failed: %w", run.ID, err)
+		}
+	}
+	return
+}
+
+// CompareTable return the metrics of runs, one row per metric
+func CompareTable(runs []CompareRun) (rows []CompareRow) {
+	for _, m := range compareMetrics {
+		row := CompareRow{Name: m.Name}
+		for i := range runs {
+			row.Values = append(row.Values, fmt.Sprint(m.Value(&runs[i].Result)))
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
+// compareChart return the equity curves as the return percent, so runs with different balance can be compared
+func compareChart(runs []CompareRun) (lines map[string][]ChartPoint) {
+	lines = make(map[string][]ChartPoint)
+	for _, run := range runs {
+		var points []ChartPoint
+		start := run.Result.StartBalance
+		for _, v := range run.Equity {
+			if start == 0 {
+				start = v.Equity
+			}
+			if start == 0 {
+				continue
+			}
+			points = appendPoint(points, ChartPoint{Time: v.Time.Unix(), Value: (v.Equity/start - 1) * 100})
+		}
+		if len(points) > 0 {
+			lines[run.Name] = points
+		}
+	}
+	return
+}
+
+// GenCompareHTML render the comparison of runs
+func GenCompareHTML(w io.Writer, runs []CompareRun) (err error) {
+	tmpl, err := template.New("compare").Parse(compareTmpl)
+	if err != nil {
+		return
+	}
+	data := struct {
+		Runs  []CompareRun
+		Rows  []CompareRow
+		Lines map[string][]ChartPoint
+	}{Runs: runs, Rows: CompareTable(runs), Lines: compareChart(runs)}
+	err = tmpl.Execute(w, data)
+	return
+}
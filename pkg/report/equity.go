@@ -0,0 +1,110 @@
+package report
+
+import (
+	"time"
+
+	"github.com/ztrade/ztrade/pkg/core"
+)
+
+const defaultMarkInterval = time.Hour * 24
+
+// markPrice the first and last price of one period
+type markPrice struct {
+	Time  time.Time
+	Open  float64
+	Price float64
+}
+
+// EquityPoint mark-to-market equity at the end of one period
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// SetMarkInterval set the period of equity series, default is one day
+func (r *Report) SetMarkInterval(interval time.Duration) {
+	r.markInterval = interval
+}
+
+func (r *Report) getMarkInterval() time.Duration {
+	if r.markInterval <= 0 {
+		return defaultMarkInterval
+	}
+	return r.markInterval
+}
+
+// OnMark update the market price at t, only the first and last price of every period are kept
+func (r *Report) OnMark(t time.Time, price float64) {
+	period := t.Truncate(r.getMarkInterval())
+	if n := len(r.marks); n > 0 && !r.marks[n-1].Time.Before(period) {
+		r.marks[n-1].Price = price
+		return
+	}
+	r.marks = append(r.marks, markPrice{Time: period, Open: price, Price: price})
+}
+
+// CalculateEquitySeries replay the trades with the mark prices, the equity include the pnl of open position
+// return nil if no mark price
+func (r *Report) CalculateEquitySeries() (series []EquityPoint) {
+	if len(r.marks) == 0 {
+		return
+	}
+	interval := r.getMarkInterval()
+	bal, spot := r.newBalance()
+	var hold, avgPrice float64
+	var err error
+	i := 0
+	for _, m := range r.marks {
+		end := m.Time.Add(interval)
+		for ; i < len(r.trades) && r.trades[i].Time.Before(end); i++ {
+			tr := r.trades[i]
+			_, _, _, err = bal.AddTrade(tr)
+			if err != nil {
+				return nil
+			}
+			hold, avgPrice = core.UpdateHold(hold, avgPrice, tr)
+		}
+		equity := bal.Get()
+		if spot != nil {
+			equity = spot.Value(m.Price)
+		} else {
+			equity += hold * (m.Price - avgPrice)
+		}
+		series = append(series, EquityPoint{Time: end, Equity: equity})
+	}
+	return
+}
+
+// EquitySeries return the mark-to-market equity series,
+// the balance after every trade is used if no mark price
+func (r *Report) EquitySeries() (series []EquityPoint) {
+	series = r.CalculateEquitySeries()
+	if len(series) > 0 {
+		return
+	}
+	for _, v := range r.tmplDatas {
+		series = append(series, EquityPoint{Time: v.Time, Equity: v.Total})
+	}
+	return
+}
+
+// periodReturns return the returns of every period
+func periodReturns(series []EquityPoint, start float64) (returns []float64) {
+	last := start
+	for _, v := range series {
+		if last != 0 {
+			returns = append(returns, v.Equity/last-1)
+		}
+		last = v.Equity
+	}
+	return
+}
+
+func equityValues(series []EquityPoint, start float64) (equity []float64) {
+	equity = make([]float64, 0, len(series)+1)
+	equity = append(equity, start)
+	for _, v := range series {
+		equity = append(equity, v.Equity)
+	}
+	return
+}
@@ -0,0 +1,243 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ztrade/base/common"
+	. "github.com/ztrade/trademodel"
+	"github.com/ztrade/ztrade/pkg/core"
+)
+
+// liveMarkInterval period of the mark prices kept by live report
+const liveMarkInterval = time.Hour
+
+// roundPnL round the values of pnl
+func roundPnL(p core.PnL) core.PnL {
+	p.Realized = common.FormatFloat(p.Realized, 8)
+	p.Unrealized = common.FormatFloat(p.Unrealized, 8)
+	p.Fee = common.FormatFloat(p.Fee, 8)
+	p.Total = common.FormatFloat(p.Total, 8)
+	return p
+}
+
+// LivePnL snapshot of live report
+type LivePnL struct {
+	Time    time.Time
+	Start   time.Time
+	Price   float64
+	Balance float64 // init balance
+	Equity  float64
+	Today   float64 // total pnl since 00:00 UTC
+	core.PnL
+	Scripts []core.PnL
+}
+
+// LiveReport reporter of real trade, the pnl can be queried while trading
+type LiveReport struct {
+	mutex       sync.Mutex
+	start       time.Time
+	balanceInit float64
+	fee         float64
+	lever       float64
+	trades      []Trade
+	venueTrades map[string][]Trade
+	plots       []core.PlotData
+	marks       []markPrice
+	price       float64
+	priceTime   time.Time
+	total       core.PnL
+	scripts     map[string]*core.PnL
+	// total pnl at the start of today
+	day     time.Time
+	dayBase float64
+}
+
+// NewLiveReport constructor of LiveReport
+func NewLiveReport() *LiveReport {
+	l := new(LiveReport)
+	l.start = time.Now()
+	l.venueTrades = make(map[string][]Trade)
+	l.scripts = make(map[string]*core.PnL)
+	return l
+}
+
+// SetTimeRange set the start time of trade, the end time is ignored
+func (l *LiveReport) SetTimeRange(start, end time.Time) {
+	l.mutex.Lock()
+	l.start = start
+	l.mutex.Unlock()
+}
+
+func (l *LiveReport) OnBalanceInit(balance, fee float64) (err error) {
+	l.mutex.Lock()
+	l.balanceInit = balance
+	l.fee = fee
+	l.mutex.Unlock()
+	return
+}
+
+func (l *LiveReport) SetLever(lever float64) {
+	l.mutex.Lock()
+	l.lever = lever
+	l.mutex.Unlock()
+}
+
+func (l *LiveReport) OnTrade(t Trade) {
+	if core.TradeFailed(&t) {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rollDay(t.Time)
+	l.trades = append(l.trades, t)
+	l.total.AddTrade(t, l.fee)
+	name := core.ScriptOfOrder(t.ID)
+	p, ok := l.scripts[name]
+	if !ok {
+		p = &core.PnL{Script: name}
+		l.scripts[name] = p
+	}
+	p.AddTrade(t, l.fee)
+}
+
+func (l *LiveReport) OnVenueTrade(venue string, t Trade) {
+	if core.TradeFailed(&t) {
+		return
+	}
+	l.mutex.Lock()
+	l.venueTrades[venue] = append(l.venueTrades[venue], t)
+	l.mutex.Unlock()
+}
+
+func (l *LiveReport) OnPlot(data core.PlotData) {
+	l.mutex.Lock()
+	l.plots = append(l.plots, data)
+	l.mutex.Unlock()
+}
+
+func (l *LiveReport) OnMark(t time.Time, price float64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rollDay(t)
+	l.price = price
+	l.priceTime = t
+	period := t.Truncate(liveMarkInterval)
+	if n := len(l.marks); n > 0 && !l.marks[n-1].Time.Before(period) {
+		l.marks[n-1].Price = price
+		return
+	}
+	l.marks = append(l.marks, markPrice{Time: period, Open: price, Price: price})
+}
+
+// rollDay keep the total pnl at the start of the day of t, must be called before the pnl updated
+func (l *LiveReport) rollDay(t time.Time) {
+	day := t.UTC().Truncate(time.Hour * 24)
+	if !day.After(l.day) {
+		return
+	}
+	if !l.day.IsZero() {
+		l.dayBase = l.total.Mark(l.price).Total
+	}
+	l.day = day
+}
+
+// PnL return the current pnl of account and every script
+func (l *LiveReport) PnL() (ret LivePnL) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	ret.Time = l.priceTime
+	ret.Start = l.start
+	ret.Price = l.price
+	ret.Balance = l.balanceInit
+	ret.PnL = roundPnL(l.total.Mark(l.price))
+	ret.Equity = common.FormatFloat(l.balanceInit+ret.Total, 8)
+	ret.Today = common.FormatFloat(ret.Total-l.dayBase, 8)
+	for _, v := range l.scripts {
+		ret.Scripts = append(ret.Scripts, roundPnL(v.Mark(l.price)))
+	}
+	sort.Slice(ret.Scripts, func(i, j int) bool {
+		return ret.Scripts[i].Script < ret.Scripts[j].Script
+	})
+	return
+}
+
+// Summary text summary of pnl, used by the daily notify
+func (l *LiveReport) Summary() (title, content string) {
+	pnl := l.PnL()
+	var b strings.Builder
+	fmt.Fprintf(&b, "equity: %g, today: %g\n", pnl.Equity, pnl.Today)
+	fmt.Fprintf(&b, "pnl: %g, realized: %g, unrealized: %g, fee: %g\n", pnl.Total, pnl.Realized, pnl.Unrealized, pnl.Fee)
+	fmt.Fprintf(&b, "position: %g@%g, price: %g\n", pnl.Position, pnl.AvgPrice, pnl.Price)
+	for _, v := range pnl.Scripts {
+		name := v.Script
+		if name == "" {
+			name = "other"
+		}
+		fmt.Fprintf(&b, "%s: pnl %g, position %g, trades %d\n", name, v.Total, v.Position, v.Trades)
+	}
+	title = fmt.Sprintf("PnL %s", pnl.Time.UTC().Format("2006-01-02"))
+	content = b.String()
+	return
+}
+
+// Report create a report of the trades until now
+func (l *LiveReport) Report() *Report {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	r := NewReportSimple()
+	r.OnBalanceInit(l.balanceInit, l.fee)
+	if l.lever != 0 {
+		r.SetLever(l.lever)
+	}
+	r.SetTimeRange(l.start, time.Now())
+	for _, v := range l.marks {
+		r.OnMark(v.Time, v.Open)
+		r.OnMark(v.Time, v.Price)
+	}
+	r.trades = append(r.trades, l.trades...)
+	for k, v := range l.venueTrades {
+		for _, t := range v {
+			r.OnVenueTrade(k, t)
+		}
+	}
+	for _, v := range l.plots {
+		r.OnPlot(v)
+	}
+	return r
+}
+
+// GenRPT generate the html report of the trades until now
+func (l *LiveReport) GenRPT(fPath string) (err error) {
+	return l.Report().GenRPT(fPath)
+}
+
+// ServeHTTP return the pnl in json
+func (l *LiveReport) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	buf, err := json.Marshal(l.PnL())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf)
+}
+
+// ServeReport return the html report
+func (l *LiveReport) ServeReport(w http.ResponseWriter, req *http.Request) {
+	r := l.Report()
+	err := r.Analyzer()
+	if err == nil {
+		err = r.analyzeVenues()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	r.GenHTML(w)
+}
@@ -17,6 +17,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/ztrade/base/common"
 	. "github.com/ztrade/trademodel"
+	"github.com/ztrade/ztrade/pkg/core"
 	"xorm.io/xorm"
 )
 
@@ -52,9 +53,36 @@ type ReportResult struct {
 	LongTrades       int     // 做多次数
 	ShortTrades      int     // 做空次数
 
+	AvgMAE            float64       // 平均最大不利偏移
+	AvgMFE            float64       // 平均最大有利偏移
+	AvgHolding        time.Duration // 平均持仓时间
+	AvgExitEfficiency float64       // 平均离场效率
+	ByWeekday         []BucketStat  // 按开仓星期统计
+	ByHour            []BucketStat  // 按开仓小时统计
+
+	Benchmark        string  // 基准
+	BenchmarkReturn  float64 // 基准收益率
+	ExcessReturn     float64 // 超额收益率
+	Alpha            float64 // 年化阿尔法
+	Beta             float64 // 贝塔
+	InformationRatio float64 // 信息比率
+	Correlation      float64 // 与基准的相关系数
+	UpCapture        float64 // 上行捕获率
+	DownCapture      float64 // 下行捕获率
+
+	ConsolidatedProfit float64       // 所有交易所的总收益
+	Venues             []VenueResult // 其他交易所的结果
+	Scripts            []core.PnL    // 多个策略时每个策略的盈亏
+
 	Actions []*RptAct `json:"-"` // 所有的操作记录
 }
 
+// VenueResult report result of an extra exchange venue
+type VenueResult struct {
+	Name string
+	ReportResult
+}
+
 type Report struct {
 	actions       []TradeAction
 	trades        []Trade
@@ -66,11 +94,27 @@ type Report struct {
 	fee           float64
 
 	lever        float64
+	spot         bool // 现货模式, 账户价值以计价货币计算
+	margin       bool
 	riskFreeRate float64 // 无风险利率
 	startTime    time.Time
 	endTime      time.Time
 
 	result ReportResult
+
+	venueTrades map[string][]Trade
+	// values plotted by scripts, key is the label
+	plots map[string][]core.PlotData
+	// candles of the tested range for chart
+	candles []ChartCandle
+	// last price of every period for mark-to-market equity
+	marks        []markPrice
+	markInterval time.Duration
+	// prices of benchmark, nil means the traded symbol
+	benchmark     []markPrice
+	benchmarkName string
+	// high and low of every candle
+	path []pricePoint
 }
 
 type RptAct struct {
@@ -81,6 +125,11 @@ type RptAct struct {
 	ProfitRate  float64
 	Fee         float64
 	IsFinish    bool
+	// the fields below are only set when the round trip finished
+	MAE            float64 // max adverse excursion, ratio to entry price
+	MFE            float64 // max favorable excursion, ratio to entry price
+	Holding        int64   // holding seconds
+	ExitEfficiency float64 // position of exit price between the lowest and highest price, 1 is the best
 }
 
 func NewReportSimple() *Report {
@@ -111,6 +160,34 @@ func (r *Report) SetLever(lever float64) {
 	r.lever = lever
 }
 
+// SetSpot use spot balance, short is only allowed when margin enabled
+func (r *Report) SetSpot(margin bool) {
+	r.spot = true
+	r.margin = margin
+}
+
+type balancer interface {
+	Set(balance float64)
+	SetFee(fee float64)
+	SetLever(lever float64)
+	Get() float64
+	AddTrade(tr Trade) (profit, profitRate, fee float64, err error)
+}
+
+func (r *Report) newBalance() (bal balancer, spot *core.SpotBalance) {
+	if r.spot {
+		spot = core.NewSpotBalance("", "")
+		spot.SetMargin(r.margin)
+		bal = spot
+	} else {
+		bal = common.NewLeverBalance()
+	}
+	bal.Set(r.balanceInit)
+	bal.SetFee(r.fee)
+	bal.SetLever(r.lever)
+	return
+}
+
 func (r *Report) Analyzer() (err error) {
 	nLen := len(r.trades)
 	if nLen == 0 {
@@ -132,10 +209,8 @@ func (r *Report) Analyzer() (err error) {
 	var tmplData, lastTmplData *RptAct
 	var profit, profitRate, fee float64
 	var profitArray, loseArray []float64
-	bal := common.NewLeverBalance()
-	bal.Set(r.balanceInit)
-	bal.SetFee(r.fee)
-	bal.SetLever(r.lever)
+	bal, spot := r.newBalance()
+	var balTotal float64
 	// startBalance := bal.Get()
 
 	for _, v := range r.trades {
@@ -144,6 +219,10 @@ func (r *Report) Analyzer() (err error) {
 			log.Error("Report add trade error:", err.Error())
 			return
 		}
+		balTotal = bal.Get()
+		if spot != nil {
+			balTotal = spot.Value(v.Price)
+		}
 		actTotal = common.FloatMul(v.Price, v.Amount)
 		if v.Action.IsLong() {
 			longAmount = common.FloatAdd(longAmount, v.Amount)
@@ -157,7 +236,7 @@ func (r *Report) Analyzer() (err error) {
 		}
 
 		tmplData = &RptAct{Trade: v,
-			Total:    common.FormatFloat(bal.Get(), 4),
+			Total:    common.FormatFloat(balTotal, 4),
 			Profit:   common.FormatFloat(profit, 4),
 			Fee:      fee,
 			IsFinish: false,
@@ -193,7 +272,7 @@ func (r *Report) Analyzer() (err error) {
 				}
 			}
 			costOnce = 0
-			r.balanceEnd = bal.Get()
+			r.balanceEnd = balTotal
 		}
 		lastTmplData = tmplData
 	}
@@ -219,6 +298,8 @@ func (r *Report) Analyzer() (err error) {
 		return err
 	}
 	r.result.LoseVariance = common.FormatFloat(r.result.LoseVariance, 4)
+	r.analyzeRounds(&r.result)
+	r.analyzeScripts(&r.result)
 	r.result.Actions = r.tmplDatas
 	r.result.StartBalance = r.balanceInit
 	r.result.EndBalance = common.FormatFloat(r.balanceEnd, 4)
@@ -235,6 +316,12 @@ func (r *Report) CalculateMetrics(metrics *ReportResult) (err error) {
 
 	equity := r.CalculateEquityCurve()
 	returns := r.calculateReturns()
+	// use the mark-to-market equity if the prices are known
+	if series := r.CalculateEquitySeries(); len(series) > 0 {
+		equity = equityValues(series, r.balanceInit)
+		returns = periodReturns(series, r.balanceInit)
+		r.calculateBenchmark(metrics, series)
+	}
 
 	metrics.TotalProfit = common.FormatFloat(r.tmplDatas[len(r.tmplDatas)-1].TotalProfit, 4)
 	metrics.TotalReturn = common.FormatFloat(metrics.TotalProfit/r.balanceInit, 4)
@@ -622,7 +709,7 @@ func (r *Report) GenHTML(w io.Writer) (err error) {
 		log.Println("tmpl parse failed:", err.Error())
 		return
 	}
-	err = tmpl.Execute(w, r.result)
+	err = tmpl.Execute(w, htmlData{ReportResult: r.result, Chart: r.chartData()})
 	return
 }
 
@@ -633,17 +720,85 @@ func (r *Report) OnBalanceInit(balance, fee float64) (err error) {
 }
 
 func (r *Report) OnTrade(t Trade) {
+	if core.TradeFailed(&t) {
+		return
+	}
 	r.trades = append(r.trades, t)
 }
 
-func (r *Report) GenRPT(fPath string) (err error) {
-	sort.Slice(r.trades, func(i int, j int) bool {
-		return r.trades[i].Time.Unix() < r.trades[j].Time.Unix()
+// OnVenueTrade add trade of an extra exchange venue
+func (r *Report) OnVenueTrade(venue string, t Trade) {
+	if core.TradeFailed(&t) {
+		return
+	}
+	if r.venueTrades == nil {
+		r.venueTrades = make(map[string][]Trade)
+	}
+	r.venueTrades[venue] = append(r.venueTrades[venue], t)
+}
+
+// OnPlot add the value plotted by script, the series are named by script and label,
+// so the scripts plot the same label are not mixed
+func (r *Report) OnPlot(data core.PlotData) {
+	if r.plots == nil {
+		r.plots = make(map[string][]core.PlotData)
+	}
+	name := data.Name
+	if data.Script != "" {
+		name = data.Script + "." + data.Name
+	}
+	r.plots[name] = append(r.plots[name], data)
+}
+
+// Plots return the values plotted by scripts, keyed by script.label
+func (r *Report) Plots() map[string][]core.PlotData {
+	return r.plots
+}
+
+// sortTrades sort trades by time, the trades with the same time keep the order they received
+func sortTrades(trades []Trade) {
+	sort.SliceStable(trades, func(i int, j int) bool {
+		return trades[i].Time.Before(trades[j].Time)
 	})
+}
+
+// analyzeVenues analyze trades of every extra venue and consolidate the profit
+func (r *Report) analyzeVenues() (err error) {
+	r.result.ConsolidatedProfit = r.result.TotalProfit
+	r.result.Venues = nil
+	var venues []string
+	for k := range r.venueTrades {
+		venues = append(venues, k)
+	}
+	sort.Strings(venues)
+	for _, v := range venues {
+		trades := r.venueTrades[v]
+		sortTrades(trades)
+		sub := NewReport(trades, r.balanceInit)
+		sub.SetFee(r.fee)
+		sub.SetLever(r.lever)
+		sub.SetTimeRange(r.startTime, r.endTime)
+		err = sub.Analyzer()
+		if err != nil {
+			err = fmt.Errorf("analyze venue %s failed: %w", v, err)
+			return
+		}
+		r.result.Venues = append(r.result.Venues, VenueResult{Name: v, ReportResult: sub.result})
+		r.result.ConsolidatedProfit = common.FormatFloat(r.result.ConsolidatedProfit+sub.result.TotalProfit, 4)
+	}
+	return
+}
+
+func (r *Report) GenRPT(fPath string) (err error) {
+	sortTrades(r.trades)
 	err = r.Analyzer()
 	if err != nil {
 		return
 	}
+	err = r.analyzeVenues()
+	if err != nil {
+		return
+	}
 	err = r.GenHTMLReport(fPath)
 	if err != nil {
 		return
@@ -652,13 +807,15 @@ func (r *Report) GenRPT(fPath string) (err error) {
 }
 
 func (r *Report) GetResult() (ret ReportResult, err error) {
-	sort.Slice(r.trades, func(i int, j int) bool {
-		return r.trades[i].Time.Unix() < r.trades[j].Time.Unix()
-	})
+	sortTrades(r.trades)
 	err = r.Analyzer()
 	if err != nil {
 		return
 	}
+	err = r.analyzeVenues()
+	if err != nil {
+		return
+	}
 	ret = r.result
 	return
 }
@@ -681,5 +838,15 @@ func (r *Report) ExportToDB(dbPath string) (err error) {
 			return
 		}
 	}
+	err = eng.Sync2(new(BucketStat))
+	if err != nil {
+		return
+	}
+	for _, v := range append(r.result.ByWeekday, r.result.ByHour...) {
+		_, err = eng.Insert(&v)
+		if err != nil {
+			return
+		}
+	}
 	return
 }
@@ -0,0 +1,282 @@
+package report
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/ztrade/trademodel"
+	"github.com/ztrade/ztrade/pkg/core"
+)
+
+func TestOnMark(t *testing.T) {
+	r := NewReportSimple()
+	r.SetMarkInterval(time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 180; i++ {
+		r.OnMark(start.Add(time.Minute*time.Duration(i)), float64(i))
+	}
+	if len(r.marks) != 3 {
+		t.Fatalf("marks error: %v", r.marks)
+	}
+	if r.marks[1].Price != 119 || !r.marks[1].Time.Equal(start.Add(time.Hour)) {
+		t.Fatalf("mark error: %v", r.marks[1])
+	}
+}
+
+func TestAnalyzeRounds(t *testing.T) {
+	r := NewReportSimple()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	prices := []float64{100, 90, 120, 110}
+	for i, p := range prices {
+		r.OnCandle(Candle{Start: start.Add(time.Minute * time.Duration(i)).Unix(), High: p, Low: p, Close: p})
+	}
+	r.tmplDatas = []*RptAct{
+		{Trade: Trade{Action: OpenLong, Time: start, Price: 100, Amount: 1}},
+		{Trade: Trade{Action: CloseLong, Time: start.Add(time.Minute * 3), Price: 110, Amount: 1}, Profit: 10, IsFinish: true},
+	}
+	var result ReportResult
+	r.analyzeRounds(&result)
+	act := r.tmplDatas[1]
+	if act.MAE != 0.1 || act.MFE != 0.2 || act.Holding != 180 || math.Abs(act.ExitEfficiency-0.6667) > 1e-4 {
+		t.Fatalf("round analyze error: %+v", act)
+	}
+	if len(result.ByWeekday) != 1 || result.ByWeekday[0].Key != "Monday" || len(result.ByHour) != 1 || result.ByHour[0].WinRate != 1 {
+		t.Fatalf("distribution error: %+v %+v", result.ByWeekday, result.ByHour)
+	}
+}
+
+func TestAnalyzeRoundsUTC(t *testing.T) {
+	r := NewReportSimple()
+	// Monday 23:30 in UTC is Tuesday 07:30 in the local time of trades
+	start := time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC).In(time.FixedZone("UTC+8", 8*3600))
+	r.tmplDatas = []*RptAct{
+		{Trade: Trade{Action: OpenLong, Time: start, Price: 100, Amount: 1}},
+		{Trade: Trade{Action: CloseLong, Time: start.Add(time.Hour), Price: 110, Amount: 1}, Profit: 10, IsFinish: true},
+	}
+	var result ReportResult
+	r.analyzeRounds(&result)
+	if len(result.ByWeekday) != 1 || result.ByWeekday[0].Key != "Monday" || len(result.ByHour) != 1 || result.ByHour[0].Key != "23:00" {
+		t.Fatalf("buckets should be in UTC: %+v %+v", result.ByWeekday, result.ByHour)
+	}
+}
+
+func TestLivePnL(t *testing.T) {
+	l := NewLiveReport()
+	l.OnBalanceInit(1000, 0.001)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	l.OnMark(start, 100)
+	l.OnTrade(Trade{ID: "a-abcd1234", Action: OpenLong, Time: start, Price: 100, Amount: 2})
+	l.OnTrade(Trade{ID: "b-abcd1234-1", Action: OpenShort, Time: start, Price: 100, Amount: 1})
+	l.OnTrade(Trade{ID: "a-abcd5678", Action: CloseLong, Time: start, Price: 120, Amount: 1, Remark: "failed:timeout"})
+	l.OnTrade(Trade{ID: "a-abcd5678", Action: CloseLong, Time: start.Add(time.Hour), Price: 110, Amount: 1})
+	l.OnMark(start.Add(time.Hour*24), 90)
+
+	pnl := l.PnL()
+	if len(pnl.Scripts) != 2 || pnl.Scripts[0].Script != "a" || pnl.Scripts[1].Script != "b" {
+		t.Fatalf("scripts error: %v", pnl.Scripts)
+	}
+	a, b := pnl.Scripts[0], pnl.Scripts[1]
+	if a.Position != 1 || a.Realized != 10 || a.Unrealized != -10 || a.Trades != 2 {
+		t.Fatalf("script a error: %+v", a)
+	}
+	if b.Position != -1 || b.Unrealized != 10 || math.Abs(b.Fee-0.1) > 1e-9 {
+		t.Fatalf("script b error: %+v", b)
+	}
+	if pnl.Position != 0 || pnl.Realized != 10 || pnl.Trades != 3 || math.Abs(pnl.Total-(10-0.41)) > 1e-9 {
+		t.Fatalf("total error: %+v", pnl.PnL)
+	}
+	if math.Abs(pnl.Today) > 1e-9 {
+		t.Fatalf("today error: %f", pnl.Today)
+	}
+}
+
+func TestOnPlot(t *testing.T) {
+	r := NewReportSimple()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.OnPlot(core.PlotData{Script: "a", Name: "ma", Time: now, Value: 1})
+	r.OnPlot(core.PlotData{Script: "b", Name: "ma", Time: now, Value: 2})
+	r.OnPlot(core.PlotData{Script: "a", Name: "ma", Time: now.Add(time.Minute), Value: 3})
+	plots := r.Plots()
+	if len(plots) != 2 || len(plots["a.ma"]) != 2 || len(plots["b.ma"]) != 1 || plots["b.ma"][0].Value != 2 {
+		t.Fatalf("plots of scripts mixed: %#v", plots)
+	}
+}
+
+func TestSetCandles(t *testing.T) {
+	r := NewReportSimple()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var candles []*Candle
+	for i := 0; i < maxChartCandles*2+1; i++ {
+		p := float64(i)
+		candles = append(candles, &Candle{Start: start.Add(time.Minute * time.Duration(i)).Unix(), Open: p, High: p + 1, Low: p - 1, Close: p})
+	}
+	r.SetCandles(candles)
+	// merged every 3 candles
+	if len(r.candles) != (maxChartCandles*2+1+2)/3 {
+		t.Fatalf("merged candles error: %d", len(r.candles))
+	}
+	c := r.candles[1]
+	if c.Time != candles[3].Start || c.Open != 3 || c.High != 6 || c.Low != 2 || c.Close != 5 {
+		t.Fatalf("merged candle error: %+v", c)
+	}
+	last := r.candles[len(r.candles)-1]
+	if last.Time != candles[len(candles)-2].Start || last.Close != float64(len(candles)-1) {
+		t.Fatalf("last candle error: %+v", last)
+	}
+	if r.chartTime(candles[4].Start) != candles[3].Start || r.chartTime(start.Unix()-60) != start.Unix() {
+		t.Fatal("chart time not aligned to merged candles")
+	}
+}
+
+func TestChartData(t *testing.T) {
+	r := NewReportSimple()
+	r.SetMarkInterval(time.Hour)
+	r.SetSpot(false)
+	r.OnBalanceInit(1000, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var candles []*Candle
+	for i, p := range []float64{100, 110, 90} {
+		tm := start.Add(time.Hour * time.Duration(i))
+		candles = append(candles, &Candle{Start: tm.Unix(), Open: p, High: p, Low: p, Close: p})
+		r.OnMark(tm, p)
+	}
+	r.SetCandles(candles)
+	r.OnTrade(Trade{Action: OpenLong, Time: start, Price: 100, Amount: 1})
+	r.tmplDatas = []*RptAct{
+		{Trade: Trade{Action: OpenLong, Time: start.Add(time.Minute), Price: 100, Amount: 1}},
+		{Trade: Trade{Action: CloseLong, Time: start.Add(time.Hour * 2), Price: 90, Amount: 1}},
+	}
+	r.OnPlot(core.PlotData{Script: "a", Name: "ma", Time: start, Value: 1})
+	// the same time of chart, only the last value is kept
+	r.OnPlot(core.PlotData{Script: "a", Name: "ma", Time: start.Add(time.Minute), Value: 2})
+	r.OnPlot(core.PlotData{Script: "a", Name: "ma", Time: start.Add(time.Hour), Value: 3})
+
+	data := r.chartData()
+	if len(data.Markers) != 2 || data.Markers[0].Time != start.Unix() || data.Markers[0].Shape != "arrowUp" || data.Markers[1].Position != "aboveBar" {
+		t.Fatalf("markers error: %+v", data.Markers)
+	}
+	expect := []float64{1000, 1010, 990}
+	if len(data.Equity) != 3 || len(data.Drawdown) != 3 || len(data.Benchmark) != 3 {
+		t.Fatalf("equity error: %+v %+v %+v", data.Equity, data.Drawdown, data.Benchmark)
+	}
+	for i, v := range expect {
+		if data.Equity[i].Value != v || data.Equity[i].Time != start.Add(time.Hour*time.Duration(i+1)).Unix() {
+			t.Fatalf("equity %d error: %+v", i, data.Equity[i])
+		}
+	}
+	if data.Drawdown[1].Value != 0 || math.Abs(data.Drawdown[2].Value+20.0/1010*100) > 1e-9 {
+		t.Fatalf("drawdown error: %+v", data.Drawdown)
+	}
+	if data.Benchmark[1].Value != 1100 || data.Benchmark[2].Value != 900 {
+		t.Fatalf("benchmark error: %+v", data.Benchmark)
+	}
+	ma := data.Series["a.ma"]
+	if len(ma) != 2 || ma[0].Value != 2 || ma[1].Time != start.Add(time.Hour).Unix() {
+		t.Fatalf("series error: %+v", data.Series)
+	}
+}
+
+func TestCalculateBenchmark(t *testing.T) {
+	r := NewReportSimple()
+	r.SetMarkInterval(time.Hour * 24)
+	r.OnBalanceInit(100, 0)
+	r.riskFreeRate = 0
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// the benchmark returns: 10%, -10%, 10%
+	var candles []*Candle
+	var series []EquityPoint
+	open, equity := 100.0, 100.0
+	for i, v := range []float64{0.1, -0.1, 0.1} {
+		day := start.Add(time.Hour * 24 * time.Duration(i))
+		candles = append(candles, &Candle{Start: day.Unix(), Open: open, Close: open * (1 + v)})
+		open *= 1 + v
+		// the strategy returns twice the benchmark
+		equity *= 1 + 2*v
+		series = append(series, EquityPoint{Time: day.Add(time.Hour * 24), Equity: equity})
+	}
+	r.SetBenchmark("index", candles)
+	var result ReportResult
+	r.calculateBenchmark(&result, series)
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-4
+	}
+	if result.Benchmark != "index" || !near(result.BenchmarkReturn, 0.089) || !near(result.ExcessReturn, 0.063) {
+		t.Fatalf("benchmark return error: %s %f %f", result.Benchmark, result.BenchmarkReturn, result.ExcessReturn)
+	}
+	if !near(result.Beta, 2) || !near(result.Correlation, 1) || !near(result.Alpha, 0) {
+		t.Fatalf("alpha beta error: %f %f %f", result.Alpha, result.Beta, result.Correlation)
+	}
+	if !near(result.UpCapture, 2) || !near(result.DownCapture, 2) {
+		t.Fatalf("capture error: %f %f", result.UpCapture, result.DownCapture)
+	}
+	// excess returns: 10%, -10%, 10%, mean/std*sqrt(365.25)
+	if !near(result.InformationRatio, 5.517) {
+		t.Fatalf("information ratio error: %f", result.InformationRatio)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var runs []CompareRun
+	for i, v := range []float64{0.1, -0.2} {
+		r := NewReportSimple()
+		r.result = ReportResult{StartBalance: 1000, TotalReturn: v}
+		r.tmplDatas = []*RptAct{{Trade: Trade{Time: start}, Total: 1000 * (1 + v)}}
+		run := &core.BacktestRun{ID: int64(i + 1), Symbol: "BTCUSDT"}
+		if i == 0 {
+			run.Name = "fast"
+		}
+		err := r.FillRun(run)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		cr, err := NewCompareRun(run)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		runs = append(runs, cr)
+	}
+	if runs[0].Name != "#1 fast" || runs[1].Name != "#2" {
+		t.Fatalf("run names error: %s %s", runs[0].Name, runs[1].Name)
+	}
+	rows := CompareTable(runs)
+	if len(rows) != len(compareMetrics) || rows[3].Name != "Total Return" || rows[3].Values[0] != "0.1" || rows[3].Values[1] != "-0.2" {
+		t.Fatalf("compare table error: %+v", rows)
+	}
+	lines := compareChart(runs)
+	if len(lines) != 2 || math.Abs(lines["#1 fast"][0].Value-10) > 1e-9 || math.Abs(lines["#2"][0].Value+20) > 1e-9 {
+		t.Fatalf("compare chart error: %+v", lines)
+	}
+	var buf bytes.Buffer
+	err := GenCompareHTML(&buf, runs)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(buf.String(), "#1 fast") {
+		t.Fatal("run not in compare report")
+	}
+	_, err = NewCompareRun(&core.BacktestRun{ID: 3, Result: []byte("{")})
+	if err == nil {
+		t.Fatal("invalid result should fail")
+	}
+}
+
+func TestVenueTradeFailed(t *testing.T) {
+	r := NewReportSimple()
+	r.OnBalanceInit(1000, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.OnVenueTrade("okx", Trade{ID: "a", Action: OpenLong, Time: start, Price: 100, Amount: 1})
+	r.OnVenueTrade("okx", Trade{ID: "b", Action: CloseLong, Time: start.Add(time.Minute), Price: 200, Amount: 1, Remark: "failed:rejected"})
+	r.OnVenueTrade("okx", Trade{ID: "c", Action: CloseLong, Time: start.Add(time.Minute * 2), Price: 110, Amount: 1})
+	if len(r.venueTrades["okx"]) != 2 {
+		t.Fatalf("failed trade of venue recorded: %#v", r.venueTrades["okx"])
+	}
+	l := NewLiveReport()
+	l.OnVenueTrade("okx", Trade{ID: "b", Action: CloseLong, Time: start, Price: 200, Amount: 1, Remark: "failed:rejected"})
+	if len(l.venueTrades["okx"]) != 0 {
+		t.Fatalf("failed trade of venue recorded in live report: %#v", l.venueTrades["okx"])
+	}
+}